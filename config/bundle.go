@@ -0,0 +1,468 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+
+	configtypes "github.com/vmware-tanzu/tanzu-plugin-runtime/config/types"
+)
+
+const (
+	// BundleAPIVersion is the apiVersion of the config bundles produced by Export
+	BundleAPIVersion = "config.tanzu.vmware.com/v1alpha1"
+	// BundleKind is the kind of the config bundles produced by Export
+	BundleKind = "ConfigBundle"
+)
+
+// BundleSection is a section of the client config that can be exported to a bundle
+type BundleSection string
+
+const (
+	BundleSectionContexts         BundleSection = "contexts"
+	BundleSectionCerts            BundleSection = "certs"
+	BundleSectionEnv              BundleSection = "env"
+	BundleSectionFeatures         BundleSection = "features"
+	BundleSectionDiscoverySources BundleSection = "discoverySources"
+)
+
+// allBundleSections are the sections exported by default
+var allBundleSections = []BundleSection{
+	BundleSectionContexts,
+	BundleSectionCerts,
+	BundleSectionEnv,
+	BundleSectionFeatures,
+	BundleSectionDiscoverySources,
+}
+
+// ConfigBundle is a portable subset of the client config
+type ConfigBundle struct {
+	APIVersion       string                            `json:"apiVersion" yaml:"apiVersion"`
+	Kind             string                            `json:"kind" yaml:"kind"`
+	Contexts         []*configtypes.Context            `json:"contexts,omitempty" yaml:"contexts,omitempty"`
+	Certs            []*configtypes.Cert               `json:"certs,omitempty" yaml:"certs,omitempty"`
+	Env              map[string]string                 `json:"env,omitempty" yaml:"env,omitempty"`
+	Features         map[string]configtypes.FeatureMap `json:"features,omitempty" yaml:"features,omitempty"`
+	DiscoverySources []configtypes.PluginDiscovery     `json:"discoverySources,omitempty" yaml:"discoverySources,omitempty"`
+}
+
+type exportOptions struct {
+	sections       []BundleSection
+	contexts       []string
+	includeSecrets bool
+}
+
+type ExportOptions func(o *exportOptions)
+
+// WithExportSections restricts the bundle to the sections. All the sections are exported by default.
+func WithExportSections(sections ...BundleSection) ExportOptions {
+	return func(o *exportOptions) {
+		o.sections = sections
+	}
+}
+
+// WithExportContexts restricts the contexts of the bundle to the named contexts
+func WithExportContexts(names ...string) ExportOptions {
+	return func(o *exportOptions) {
+		o.contexts = names
+	}
+}
+
+// WithExportSecrets includes the tokens of the contexts in the bundle, read from the secret store if
+// one is configured. The tokens are scrubbed by default.
+func WithExportSecrets() ExportOptions {
+	return func(o *exportOptions) {
+		o.includeSecrets = true
+	}
+}
+
+// Export returns a YAML ConfigBundle of the contexts, certs, env variables, features and CLI discovery
+// sources of the client config. The tokens of the contexts are scrubbed unless WithExportSecrets is specified.
+func Export(opts ...ExportOptions) ([]byte, error) {
+	options := &exportOptions{sections: allBundleSections}
+	for _, opt := range opts {
+		opt(options)
+	}
+	sections := map[BundleSection]bool{}
+	for _, section := range options.sections {
+		if !isBundleSection(section) {
+			return nil, errors.Errorf("unknown bundle section %q", section)
+		}
+		sections[section] = true
+	}
+
+	// Retrieve client config node
+	node, err := getClientConfigNode()
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := convertNodeToClientConfig(node)
+	if err != nil {
+		return nil, err
+	}
+
+	bundle := &ConfigBundle{APIVersion: BundleAPIVersion, Kind: BundleKind}
+	if sections[BundleSectionContexts] {
+		if bundle.Contexts, err = exportContexts(cfg, options); err != nil {
+			return nil, err
+		}
+	}
+	if sections[BundleSectionCerts] {
+		bundle.Certs = cfg.Certs
+	}
+	if sections[BundleSectionEnv] && cfg.ClientOptions != nil {
+		bundle.Env = cfg.ClientOptions.Env
+	}
+	if sections[BundleSectionFeatures] && cfg.ClientOptions != nil {
+		bundle.Features = cfg.ClientOptions.Features
+	}
+	if sections[BundleSectionDiscoverySources] && cfg.CoreCliOptions != nil {
+		bundle.DiscoverySources = cfg.CoreCliOptions.DiscoverySources
+	}
+
+	data, err := yaml.Marshal(bundle)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal the config bundle")
+	}
+	return data, nil
+}
+
+func isBundleSection(section BundleSection) bool {
+	for _, s := range allBundleSections {
+		if s == section {
+			return true
+		}
+	}
+	return false
+}
+
+// exportContexts returns the contexts of the bundle with their tokens rehydrated or scrubbed
+func exportContexts(cfg *configtypes.ClientConfig, options *exportOptions) ([]*configtypes.Context, error) {
+	contexts := cfg.KnownContexts
+	if len(options.contexts) != 0 {
+		contexts = nil
+		for _, name := range options.contexts {
+			c, err := cfg.GetContext(name)
+			if err != nil {
+				return nil, err
+			}
+			contexts = append(contexts, c)
+		}
+	}
+
+	exported := make([]*configtypes.Context, 0, len(contexts))
+	for _, c := range contexts {
+		if options.includeSecrets {
+			c, err := rehydrateContextSecrets(c)
+			if err != nil {
+				return nil, err
+			}
+			exported = append(exported, c)
+			continue
+		}
+		exported = append(exported, scrubContextSecrets(c))
+	}
+	return exported, nil
+}
+
+// scrubContextSecrets returns a copy of the context without its tokens
+func scrubContextSecrets(c *configtypes.Context) *configtypes.Context {
+	if c.GlobalOpts == nil {
+		return c
+	}
+	copied := *c
+	globalOpts := *c.GlobalOpts
+	copied.GlobalOpts = &globalOpts
+	for _, value := range contextAuthSecrets(&copied) {
+		*value = ""
+	}
+	copied.GlobalOpts.Auth.Expiration = time.Time{}
+	return &copied
+}
+
+// ImportStrategy is the policy applied by Import to the items of the bundle that conflict with
+// existing items of the client config
+type ImportStrategy string
+
+const (
+	// ImportStrategySkip keeps the existing items
+	ImportStrategySkip ImportStrategy = "skip"
+	// ImportStrategyOverwrite merges the items of the bundle into the existing items following the
+	// patch strategies of the config metadata
+	ImportStrategyOverwrite ImportStrategy = "overwrite"
+	// ImportStrategyRename imports the contexts and discovery sources of the bundle under a new name,
+	// <name>-imported. The other items cannot be renamed and are skipped.
+	ImportStrategyRename ImportStrategy = "rename"
+)
+
+// ImportActionRenamed means the item of the bundle was imported under a new name
+const ImportActionRenamed ImportAction = "renamed"
+
+// BundleImportedItem reports what Import did for an item of the bundle
+type BundleImportedItem struct {
+	// Section is the section of the item
+	Section BundleSection `json:"section" yaml:"section"`
+	// Name identifies the item in its section: the name of a context or discovery source, the host of a
+	// cert, the env variable, or <plugin>.<key> for a feature
+	Name string `json:"name" yaml:"name"`
+	// Action is what was done for the item
+	Action ImportAction `json:"action" yaml:"action"`
+	// NewName is the name under which the item was imported if renamed
+	NewName string `json:"newName,omitempty" yaml:"newName,omitempty"`
+	// Reason explains why the item was skipped
+	Reason string `json:"reason,omitempty" yaml:"reason,omitempty"`
+}
+
+// BundleImportResult reports what Import did for each item of the bundle
+type BundleImportResult struct {
+	Items []BundleImportedItem `json:"items" yaml:"items"`
+}
+
+// Filter returns the outcomes of the items for which the action was performed
+func (r *BundleImportResult) Filter(action ImportAction) []BundleImportedItem {
+	var items []BundleImportedItem
+	for _, item := range r.Items {
+		if item.Action == action {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Import merges the ConfigBundle produced by Export into the client config. The items of the bundle
+// conflicting with existing items are handled according to the strategy. Items equal to the existing
+// ones are skipped. The current contexts are not changed. The bundle is imported in a single config update.
+func Import(bundle []byte, strategy ImportStrategy) (*BundleImportResult, error) {
+	switch strategy {
+	case ImportStrategySkip, ImportStrategyOverwrite, ImportStrategyRename:
+	default:
+		return nil, errors.Errorf("unknown import strategy %q", strategy)
+	}
+	b := &ConfigBundle{}
+	if err := yaml.Unmarshal(bundle, b); err != nil {
+		return nil, errors.Wrap(err, "failed to parse the config bundle")
+	}
+	if b.Kind != BundleKind || b.APIVersion != BundleAPIVersion {
+		return nil, errors.Errorf("unsupported config bundle %s %s, expected %s %s", b.APIVersion, b.Kind, BundleAPIVersion, BundleKind)
+	}
+
+	result := &BundleImportResult{}
+	err := Update(func(tx *ConfigTx) error {
+		imp := &bundleImporter{tx: tx, strategy: strategy}
+		for _, step := range []func(*ConfigBundle) error{imp.importContexts, imp.importCerts, imp.importEnv, imp.importFeatures, imp.importDiscoverySources} {
+			if err := step(b); err != nil {
+				return err
+			}
+		}
+		result.Items = imp.items
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// bundleImporter imports the items of a bundle in a config transaction
+type bundleImporter struct {
+	tx       *ConfigTx
+	strategy ImportStrategy
+	items    []BundleImportedItem
+}
+
+// resolve returns the outcome of an item of the bundle conflicting with an existing item, and whether
+// the item must be written. renamer returns a free name, nil if the item cannot be renamed.
+func (imp *bundleImporter) resolve(section BundleSection, name string, exists, equal bool, renamer func() string) (BundleImportedItem, bool) {
+	item := BundleImportedItem{Section: section, Name: name}
+	switch {
+	case !exists:
+		item.Action = ImportActionCreated
+		return item, true
+	case equal:
+		item.Action = ImportActionSkipped
+		item.Reason = fmt.Sprintf("%s %s is identical", section, name)
+		return item, false
+	case imp.strategy == ImportStrategyOverwrite:
+		item.Action = ImportActionUpdated
+		return item, true
+	case imp.strategy == ImportStrategyRename && renamer != nil:
+		item.Action = ImportActionRenamed
+		item.NewName = renamer()
+		return item, true
+	case imp.strategy == ImportStrategyRename:
+		item.Action = ImportActionSkipped
+		item.Reason = fmt.Sprintf("%s %s already exists and cannot be renamed", section, name)
+		return item, false
+	default:
+		item.Action = ImportActionSkipped
+		item.Reason = fmt.Sprintf("%s %s already exists", section, name)
+		return item, false
+	}
+}
+
+// importedName returns the first <name>-imported, <name>-imported-2, ... not taken
+func importedName(name string, taken func(string) bool) string {
+	candidate := name + "-imported"
+	for i := 2; taken(candidate); i++ {
+		candidate = fmt.Sprintf("%s-imported-%d", name, i)
+	}
+	return candidate
+}
+
+func (imp *bundleImporter) importContexts(b *ConfigBundle) error {
+	taken := func(name string) bool {
+		_, err := getContext(imp.tx.node, name)
+		return err == nil
+	}
+	for _, c := range b.Contexts {
+		if c == nil || c.Name == "" {
+			return errors.New("config bundle has a context without name")
+		}
+		existing, err := imp.tx.GetContext(c.Name)
+		exists := err == nil
+		// The tokens of the bundle are scrubbed by default, an existing context differing only by its tokens is unchanged
+		equal := exists && equalYAML(scrubContextSecrets(existing), scrubContextSecrets(c))
+		item, write := imp.resolve(BundleSectionContexts, c.Name, exists, equal, func() string {
+			return importedName(c.Name, taken)
+		})
+		imp.items = append(imp.items, item)
+		if !write {
+			continue
+		}
+		if item.NewName != "" {
+			renamed := *c
+			renamed.Name = item.NewName
+			c = &renamed
+		}
+		if err := imp.tx.SetContext(c, false); err != nil {
+			return errors.Wrapf(err, "failed to import context %s", item.Name)
+		}
+	}
+	return nil
+}
+
+func (imp *bundleImporter) importCerts(b *ConfigBundle) error {
+	for _, c := range b.Certs {
+		if c == nil || c.Host == "" {
+			return errors.New("config bundle has a cert without host")
+		}
+		existing, err := getCert(imp.tx.node, c.Host)
+		exists := err == nil
+		item, write := imp.resolve(BundleSectionCerts, c.Host, exists, exists && equalYAML(existing, c), nil)
+		imp.items = append(imp.items, item)
+		if !write {
+			continue
+		}
+		if err := imp.tx.SetCert(c); err != nil {
+			return errors.Wrapf(err, "failed to import cert %s", c.Host)
+		}
+	}
+	return nil
+}
+
+func (imp *bundleImporter) importEnv(b *ConfigBundle) error {
+	for _, key := range sortedKeys(b.Env) {
+		value := b.Env[key]
+		existing, err := imp.tx.GetEnv(key)
+		exists := err == nil
+		item, write := imp.resolve(BundleSectionEnv, key, exists, exists && existing == value, nil)
+		imp.items = append(imp.items, item)
+		if !write {
+			continue
+		}
+		if err := imp.tx.SetEnv(key, value); err != nil {
+			return errors.Wrapf(err, "failed to import env %s", key)
+		}
+	}
+	return nil
+}
+
+func (imp *bundleImporter) importFeatures(b *ConfigBundle) error {
+	for _, plugin := range sortedKeys(b.Features) {
+		features := b.Features[plugin]
+		for _, key := range sortedKeys(features) {
+			value := features[key]
+			existing, err := getFeature(imp.tx.node, plugin, key)
+			exists := err == nil
+			item, write := imp.resolve(BundleSectionFeatures, plugin+"."+key, exists, exists && existing == value, nil)
+			imp.items = append(imp.items, item)
+			if !write {
+				continue
+			}
+			if err := imp.tx.SetFeature(plugin, key, value); err != nil {
+				return errors.Wrapf(err, "failed to import feature %s.%s", plugin, key)
+			}
+		}
+	}
+	return nil
+}
+
+func (imp *bundleImporter) importDiscoverySources(b *ConfigBundle) error {
+	taken := func(name string) bool {
+		_, err := imp.tx.GetCLIDiscoverySource(name)
+		return err == nil
+	}
+	for _, ds := range b.DiscoverySources {
+		_, name, err := getDiscoverySourceTypeAndName(ds)
+		if err != nil {
+			return errors.Wrap(err, "config bundle has an invalid discovery source")
+		}
+		existing, err := imp.tx.GetCLIDiscoverySource(name)
+		exists := err == nil
+		item, write := imp.resolve(BundleSectionDiscoverySources, name, exists, exists && equalYAML(existing, &ds), func() string {
+			return importedName(name, taken)
+		})
+		imp.items = append(imp.items, item)
+		if !write {
+			continue
+		}
+		if item.NewName != "" {
+			ds = renameDiscoverySource(ds, item.NewName)
+		}
+		if err := imp.tx.SetCLIDiscoverySource(ds); err != nil {
+			return errors.Wrapf(err, "failed to import discovery source %s", name)
+		}
+	}
+	return nil
+}
+
+// renameDiscoverySource returns a copy of the discovery source with the name of its backend set
+func renameDiscoverySource(ds configtypes.PluginDiscovery, name string) configtypes.PluginDiscovery {
+	switch {
+	//nolint:staticcheck // Deprecated
+	case ds.GCP != nil:
+		gcp := *ds.GCP //nolint:staticcheck
+		gcp.Name = name
+		ds.GCP = &gcp //nolint:staticcheck
+	case ds.OCI != nil:
+		oci := *ds.OCI
+		oci.Name = name
+		ds.OCI = &oci
+	case ds.Local != nil:
+		local := *ds.Local
+		local.Name = name
+		ds.Local = &local
+	case ds.Kubernetes != nil:
+		k8s := *ds.Kubernetes
+		k8s.Name = name
+		ds.Kubernetes = &k8s
+	case ds.REST != nil:
+		rest := *ds.REST
+		rest.Name = name
+		ds.REST = &rest
+	}
+	return ds
+}
+
+// equalYAML checks whether the values have the same YAML representation, ignoring the empty fields
+func equalYAML(v1, v2 interface{}) bool {
+	data1, err1 := yaml.Marshal(v1)
+	data2, err2 := yaml.Marshal(v2)
+	return err1 == nil && err2 == nil && bytes.Equal(data1, data2)
+}
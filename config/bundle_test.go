@@ -0,0 +1,221 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+
+	configtypes "github.com/vmware-tanzu/tanzu-plugin-runtime/config/types"
+)
+
+func setupForBundle(t *testing.T) {
+	err := setupForGetContext()
+	assert.NoError(t, err)
+
+	c, err := GetContext("test-tmc")
+	assert.NoError(t, err)
+	c.GlobalOpts.Auth.AccessToken = "test-access-token"
+	c.GlobalOpts.Auth.RefreshToken = "test-refresh-token"
+	c.GlobalOpts.Auth.UserName = "test-user"
+	assert.NoError(t, SetContext(c, false))
+	assert.NoError(t, SetEnv("TEST_ENV", "test-value"))
+	assert.NoError(t, SetFeature("global", "test-feature", "true"))
+	assert.NoError(t, SetCert(&configtypes.Cert{Host: "test-host", SkipCertVerify: "true"}))
+	assert.NoError(t, SetCLIDiscoverySource(configtypes.PluginDiscovery{
+		OCI: &configtypes.OCIDiscovery{Name: "test-source", Image: "test-image:latest"},
+	}))
+}
+
+func TestExport(t *testing.T) {
+	setupForBundle(t)
+	defer cleanupTestingDir(t)
+
+	data, err := Export()
+	assert.NoError(t, err)
+	bundle := &ConfigBundle{}
+	assert.NoError(t, yaml.Unmarshal(data, bundle))
+
+	assert.Equal(t, BundleAPIVersion, bundle.APIVersion)
+	assert.Equal(t, BundleKind, bundle.Kind)
+	assert.Len(t, bundle.Contexts, 4)
+	assert.Equal(t, map[string]string{"TEST_ENV": "test-value"}, bundle.Env)
+	assert.Equal(t, "true", bundle.Features["global"]["test-feature"])
+	assert.Equal(t, []*configtypes.Cert{{Host: "test-host", SkipCertVerify: "true"}}, bundle.Certs)
+	assert.Equal(t, "test-image:latest", bundle.DiscoverySources[0].OCI.Image)
+
+	// The tokens are scrubbed by default
+	tmc := bundle.Contexts[2]
+	assert.Equal(t, "test-tmc", tmc.Name)
+	assert.Equal(t, "test-user", tmc.GlobalOpts.Auth.UserName)
+	assert.Empty(t, tmc.GlobalOpts.Auth.AccessToken)
+	assert.Empty(t, tmc.GlobalOpts.Auth.RefreshToken)
+	assert.NotContains(t, string(data), "test-access-token")
+
+	// The scrubbing does not change the config
+	c, err := GetContext("test-tmc")
+	assert.NoError(t, err)
+	assert.Equal(t, "test-access-token", c.GlobalOpts.Auth.AccessToken)
+
+	data, err = Export(WithExportSections(BundleSectionContexts), WithExportContexts("test-tmc"), WithExportSecrets())
+	assert.NoError(t, err)
+	bundle = &ConfigBundle{}
+	assert.NoError(t, yaml.Unmarshal(data, bundle))
+	assert.Len(t, bundle.Contexts, 1)
+	assert.Equal(t, "test-access-token", bundle.Contexts[0].GlobalOpts.Auth.AccessToken)
+	assert.Nil(t, bundle.Env)
+	assert.Nil(t, bundle.Certs)
+
+	_, err = Export(WithExportContexts("missing"))
+	assert.Error(t, err)
+	_, err = Export(WithExportSections("servers"))
+	assert.EqualError(t, err, `unknown bundle section "servers"`)
+}
+
+const testBundle = `apiVersion: config.tanzu.vmware.com/v1alpha1
+kind: ConfigBundle
+contexts:
+  - name: test-mc
+    target: kubernetes
+    contextType: kubernetes
+    clusterOpts:
+      endpoint: imported-endpoint
+      path: imported-path
+      context: imported-context
+  - name: new-context
+    contextType: kubernetes
+    clusterOpts:
+      path: new-path
+      context: new-context
+certs:
+  - host: test-host
+    skipCertVerify: "false"
+env:
+  TEST_ENV: imported-value
+  NEW_ENV: new-value
+features:
+  global:
+    test-feature: "true"
+discoverySources:
+  - oci:
+      name: test-source
+      image: imported-image:latest
+`
+
+func TestImportSkip(t *testing.T) {
+	setupForBundle(t)
+	defer cleanupTestingDir(t)
+
+	result, err := Import([]byte(testBundle), ImportStrategySkip)
+	assert.NoError(t, err)
+	assert.Equal(t, []BundleImportedItem{
+		{Section: BundleSectionContexts, Name: "test-mc", Action: ImportActionSkipped, Reason: "contexts test-mc already exists"},
+		{Section: BundleSectionContexts, Name: "new-context", Action: ImportActionCreated},
+		{Section: BundleSectionCerts, Name: "test-host", Action: ImportActionSkipped, Reason: "certs test-host already exists"},
+		{Section: BundleSectionEnv, Name: "NEW_ENV", Action: ImportActionCreated},
+		{Section: BundleSectionEnv, Name: "TEST_ENV", Action: ImportActionSkipped, Reason: "env TEST_ENV already exists"},
+		{Section: BundleSectionFeatures, Name: "global.test-feature", Action: ImportActionSkipped, Reason: "features global.test-feature is identical"},
+		{Section: BundleSectionDiscoverySources, Name: "test-source", Action: ImportActionSkipped, Reason: "discoverySources test-source already exists"},
+	}, result.Items)
+
+	c, err := GetContext("test-mc")
+	assert.NoError(t, err)
+	assert.Equal(t, "test-path", c.ClusterOpts.Path)
+	c, err = GetContext("new-context")
+	assert.NoError(t, err)
+	assert.Equal(t, "new-path", c.ClusterOpts.Path)
+	value, err := GetEnv("TEST_ENV")
+	assert.NoError(t, err)
+	assert.Equal(t, "test-value", value)
+
+	// The current contexts are not changed
+	current, err := GetActiveContext(configtypes.ContextTypeK8s)
+	assert.NoError(t, err)
+	assert.Equal(t, "test-mc-2", current.Name)
+}
+
+func TestImportOverwrite(t *testing.T) {
+	setupForBundle(t)
+	defer cleanupTestingDir(t)
+
+	result, err := Import([]byte(testBundle), ImportStrategyOverwrite)
+	assert.NoError(t, err)
+	assert.Len(t, result.Filter(ImportActionUpdated), 4)
+
+	c, err := GetContext("test-mc")
+	assert.NoError(t, err)
+	assert.Equal(t, "imported-path", c.ClusterOpts.Path)
+	assert.Equal(t, "imported-context", c.ClusterOpts.Context)
+	value, err := GetEnv("TEST_ENV")
+	assert.NoError(t, err)
+	assert.Equal(t, "imported-value", value)
+	cert, err := GetCert("test-host")
+	assert.NoError(t, err)
+	assert.Equal(t, "false", cert.SkipCertVerify)
+	ds, err := GetCLIDiscoverySource("test-source")
+	assert.NoError(t, err)
+	assert.Equal(t, "imported-image:latest", ds.OCI.Image)
+}
+
+func TestImportRename(t *testing.T) {
+	setupForBundle(t)
+	defer cleanupTestingDir(t)
+
+	result, err := Import([]byte(testBundle), ImportStrategyRename)
+	assert.NoError(t, err)
+	assert.Equal(t, []BundleImportedItem{
+		{Section: BundleSectionContexts, Name: "test-mc", Action: ImportActionRenamed, NewName: "test-mc-imported"},
+		{Section: BundleSectionDiscoverySources, Name: "test-source", Action: ImportActionRenamed, NewName: "test-source-imported"},
+	}, result.Filter(ImportActionRenamed))
+	assert.Equal(t, []BundleImportedItem{
+		{Section: BundleSectionCerts, Name: "test-host", Action: ImportActionSkipped, Reason: "certs test-host already exists and cannot be renamed"},
+		{Section: BundleSectionEnv, Name: "TEST_ENV", Action: ImportActionSkipped, Reason: "env TEST_ENV already exists and cannot be renamed"},
+		{Section: BundleSectionFeatures, Name: "global.test-feature", Action: ImportActionSkipped, Reason: "features global.test-feature is identical"},
+	}, result.Filter(ImportActionSkipped))
+
+	c, err := GetContext("test-mc")
+	assert.NoError(t, err)
+	assert.Equal(t, "test-path", c.ClusterOpts.Path)
+	c, err = GetContext("test-mc-imported")
+	assert.NoError(t, err)
+	assert.Equal(t, "imported-path", c.ClusterOpts.Path)
+	ds, err := GetCLIDiscoverySource("test-source-imported")
+	assert.NoError(t, err)
+	assert.Equal(t, "imported-image:latest", ds.OCI.Image)
+
+	// The next free name is used
+	result, err = Import([]byte(testBundle), ImportStrategyRename)
+	assert.NoError(t, err)
+	assert.Equal(t, "test-mc-imported-2", result.Items[0].NewName)
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	setupForBundle(t)
+	defer cleanupTestingDir(t)
+
+	data, err := Export(WithExportSecrets())
+	assert.NoError(t, err)
+
+	// Importing the exported config does not change anything
+	result, err := Import(data, ImportStrategyOverwrite)
+	assert.NoError(t, err)
+	assert.Len(t, result.Filter(ImportActionSkipped), len(result.Items))
+
+	// The contexts of a bundle with scrubbed tokens are unchanged and keep their tokens
+	data, err = Export()
+	assert.NoError(t, err)
+	result, err = Import(data, ImportStrategyOverwrite)
+	assert.NoError(t, err)
+	assert.Len(t, result.Filter(ImportActionSkipped), len(result.Items))
+	c, err := GetContext("test-tmc")
+	assert.NoError(t, err)
+	assert.Equal(t, "test-access-token", c.GlobalOpts.Auth.AccessToken)
+
+	_, err = Import([]byte("apiVersion: v1\nkind: Config\n"), ImportStrategySkip)
+	assert.Error(t, err)
+	_, err = Import(data, "merge")
+	assert.EqualError(t, err, `unknown import strategy "merge"`)
+}
@@ -25,7 +25,8 @@ func GetCerts() ([]*configtypes.Cert, error) {
 	return getCerts(node)
 }
 
-// GetCert retrieves the cert configuration by host
+// GetCert retrieves the cert configuration by host. The cert configuration of the exact host
+// is returned if any, otherwise the best matching one as resolved by ResolveCert.
 func GetCert(host string) (*configtypes.Cert, error) {
 	if host == "" {
 		return nil, errors.New("host is empty")
@@ -35,7 +36,7 @@ func GetCert(host string) (*configtypes.Cert, error) {
 	if err != nil {
 		return nil, err
 	}
-	return getCert(node, host)
+	return lookupCert(node, host)
 }
 
 // SetCert add or update cert configuration
@@ -43,12 +44,18 @@ func SetCert(c *configtypes.Cert) error {
 	if c == nil {
 		return nil
 	}
-	if c.Host == "" {
-		return errors.New("host is empty")
+	if err := validateCertHost(c.Host); err != nil {
+		return err
+	}
+	if err := validateCACertData(c.CACertData); err != nil {
+		return err
 	}
 	// Retrieve client config node
-	AcquireTanzuConfigLock()
-	defer ReleaseTanzuConfigLock()
+	unlock, err := acquireTanzuConfigLock()
+	if err != nil {
+		return err
+	}
+	defer unlock.Unlock()
 	node, err := getClientConfigNodeNoLock()
 	if err != nil {
 		return err
@@ -73,8 +80,11 @@ func DeleteCert(host string) error {
 		return errors.New("host is empty")
 	}
 	// Retrieve client config node
-	AcquireTanzuConfigLock()
-	defer ReleaseTanzuConfigLock()
+	unlock, err := acquireTanzuConfigLock()
+	if err != nil {
+		return err
+	}
+	defer unlock.Unlock()
 	node, err := getClientConfigNodeNoLock()
 	if err != nil {
 		return err
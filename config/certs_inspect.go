@@ -0,0 +1,192 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"crypto/sha1" //nolint:gosec
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/vmware-tanzu/tanzu-plugin-runtime/log"
+
+	configtypes "github.com/vmware-tanzu/tanzu-plugin-runtime/config/types"
+)
+
+// DefaultCertExpiryWarningPeriod is the default period before the expiration of a CA certificate during which a warning is emitted
+const DefaultCertExpiryWarningPeriod = 30 * 24 * time.Hour
+
+// CertificateInfo describes a certificate of the CA bundle of a cert configuration
+type CertificateInfo struct {
+	// Subject is the distinguished name of the certificate subject
+	Subject string `json:"subject" yaml:"subject"`
+	// Issuer is the distinguished name of the certificate issuer
+	Issuer string `json:"issuer" yaml:"issuer"`
+	// SerialNumber is the serial number of the certificate in decimal
+	SerialNumber string `json:"serialNumber" yaml:"serialNumber"`
+	// DNSNames are the DNS subject alternative names
+	DNSNames []string `json:"dnsNames,omitempty" yaml:"dnsNames,omitempty"`
+	// IPAddresses are the IP address subject alternative names
+	IPAddresses []string `json:"ipAddresses,omitempty" yaml:"ipAddresses,omitempty"`
+	// IsCA is true if the certificate can sign other certificates
+	IsCA bool `json:"isCA" yaml:"isCA"`
+	// NotBefore is the start of the validity period of the certificate
+	NotBefore time.Time `json:"notBefore" yaml:"notBefore"`
+	// NotAfter is the expiration time of the certificate
+	NotAfter time.Time `json:"notAfter" yaml:"notAfter"`
+	// SHA256Fingerprint is the colon separated hex SHA-256 fingerprint of the certificate
+	SHA256Fingerprint string `json:"sha256Fingerprint" yaml:"sha256Fingerprint"`
+	// SHA1Fingerprint is the colon separated hex SHA-1 fingerprint of the certificate
+	SHA1Fingerprint string `json:"sha1Fingerprint" yaml:"sha1Fingerprint"`
+}
+
+// ExpiresWithin checks whether the certificate is expired or expires within the duration
+func (c *CertificateInfo) ExpiresWithin(d time.Duration) bool {
+	return time.Now().Add(d).After(c.NotAfter)
+}
+
+// InspectCert decodes the CA certificates of the cert configuration of the host
+func InspectCert(host string) ([]*CertificateInfo, error) {
+	cert, err := GetCert(host)
+	if err != nil {
+		return nil, err
+	}
+	return inspectCert(cert)
+}
+
+func inspectCert(cert *configtypes.Cert) ([]*CertificateInfo, error) {
+	if cert.CACertData == "" {
+		return nil, errors.Errorf("cert configuration %q has no CA certificate data", cert.Host)
+	}
+	certs, err := parseCACertData(cert.CACertData)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid CA certificate data of cert configuration %q", cert.Host)
+	}
+	infos := make([]*CertificateInfo, 0, len(certs))
+	for _, c := range certs {
+		infos = append(infos, newCertificateInfo(c))
+	}
+	return infos, nil
+}
+
+// WarnCertExpiry emits a warning for each CA certificate of the cert configuration of the host
+// that is expired or expires within the duration, and returns these certificates.
+// No warning is emitted if the host has no cert configuration or CA certificate data. A warning is emitted
+// and the error returned if the CA certificate data cannot be parsed.
+func WarnCertExpiry(host string, within time.Duration) ([]*CertificateInfo, error) {
+	if host == "" {
+		return nil, errors.New("host is empty")
+	}
+	node, err := getClientConfigNode()
+	if err != nil {
+		return nil, err
+	}
+	// Report the config that cannot be decoded, so that only a missing cert configuration is ignored below
+	if _, err := getCerts(node); err != nil {
+		return nil, err
+	}
+	cert, err := lookupCert(node, host)
+	if err != nil || cert.CACertData == "" {
+		return nil, nil
+	}
+	infos, err := inspectCert(cert)
+	if err != nil {
+		log.Warningf("The CA certificate data configured for host %q cannot be parsed: %v", cert.Host, err)
+		return nil, err
+	}
+	var expiring []*CertificateInfo
+	for _, info := range infos {
+		if !info.ExpiresWithin(within) {
+			continue
+		}
+		expiring = append(expiring, info)
+		if info.NotAfter.Before(time.Now()) {
+			log.Warningf("The CA certificate %q configured for host %q expired on %s", info.Subject, cert.Host, info.NotAfter.Format(time.RFC3339))
+		} else {
+			log.Warningf("The CA certificate %q configured for host %q expires on %s", info.Subject, cert.Host, info.NotAfter.Format(time.RFC3339))
+		}
+	}
+	return expiring, nil
+}
+
+// validateCACertData checks that the CA certificate data, if any, is a valid PEM bundle of certificates
+func validateCACertData(data string) error {
+	if data == "" {
+		return nil
+	}
+	if _, err := parseCACertData(data); err != nil {
+		return errors.Wrap(err, "invalid CA certificate data")
+	}
+	return nil
+}
+
+// parseCACertData parses the PEM bundle of certificates of the CA certificate data, which is stored either base64 encoded or as is
+func parseCACertData(data string) ([]*x509.Certificate, error) {
+	rest := decodeCACertData(data)
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			return nil, errors.Errorf("unexpected PEM block of type %q", block.Type)
+		}
+		c, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse certificate")
+		}
+		certs = append(certs, c)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no PEM encoded certificate found")
+	}
+	if strings.TrimSpace(string(rest)) != "" {
+		return nil, errors.New("unexpected data after the PEM encoded certificates")
+	}
+	return certs, nil
+}
+
+func newCertificateInfo(c *x509.Certificate) *CertificateInfo {
+	info := &CertificateInfo{
+		Subject:           c.Subject.String(),
+		Issuer:            c.Issuer.String(),
+		SerialNumber:      c.SerialNumber.String(),
+		DNSNames:          c.DNSNames,
+		IsCA:              c.IsCA,
+		NotBefore:         c.NotBefore,
+		NotAfter:          c.NotAfter,
+		SHA256Fingerprint: fingerprint(sha256Sum(c.Raw)),
+		SHA1Fingerprint:   fingerprint(sha1Sum(c.Raw)),
+	}
+	for _, ip := range c.IPAddresses {
+		info.IPAddresses = append(info.IPAddresses, ip.String())
+	}
+	return info
+}
+
+// fingerprint formats the digest as colon separated upper case hex bytes
+func fingerprint(sum []byte) string {
+	var parts []string
+	for _, b := range sum {
+		parts = append(parts, fmt.Sprintf("%02X", b))
+	}
+	return strings.Join(parts, ":")
+}
+
+func sha256Sum(data []byte) []byte {
+	sum := sha256.Sum256(data)
+	return sum[:]
+}
+
+func sha1Sum(data []byte) []byte {
+	sum := sha1.Sum(data) //nolint:gosec
+	return sum[:]
+}
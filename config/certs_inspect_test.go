@@ -0,0 +1,108 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	configtypes "github.com/vmware-tanzu/tanzu-plugin-runtime/config/types"
+	"github.com/vmware-tanzu/tanzu-plugin-runtime/log"
+)
+
+func TestInspectCert(t *testing.T) {
+	// Setup config data
+	_, cleanUp := setupTestConfig(t, &CfgTestData{})
+
+	defer func() {
+		cleanUp()
+	}()
+
+	notAfter := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+	caData := newTestCACertData(t, "test.vmware.com", notAfter)
+	assert.NoError(t, SetCert(&configtypes.Cert{Host: "test.vmware.com", CACertData: caData}))
+	assert.NoError(t, SetCert(&configtypes.Cert{Host: "skip.vmware.com", SkipCertVerify: "true"}))
+
+	infos, err := InspectCert("test.vmware.com")
+	assert.NoError(t, err)
+	assert.Len(t, infos, 1)
+	assert.Equal(t, "CN=test.vmware.com", infos[0].Subject)
+	assert.Equal(t, "CN=test.vmware.com", infos[0].Issuer)
+	assert.Equal(t, []string{"test.vmware.com"}, infos[0].DNSNames)
+	assert.True(t, infos[0].IsCA)
+	assert.True(t, notAfter.Equal(infos[0].NotAfter))
+	assert.Len(t, infos[0].SHA256Fingerprint, 32*3-1)
+	assert.Len(t, infos[0].SHA1Fingerprint, 20*3-1)
+
+	_, err = InspectCert("skip.vmware.com")
+	assert.ErrorContains(t, err, "has no CA certificate data")
+
+	_, err = InspectCert("unknown.vmware.com")
+	assert.EqualError(t, err, "cert configuration for unknown.vmware.com not found")
+}
+
+func TestSetCertInvalidCACertData(t *testing.T) {
+	// Setup config data
+	_, cleanUp := setupTestConfig(t, &CfgTestData{})
+
+	defer func() {
+		cleanUp()
+	}()
+
+	err := SetCert(&configtypes.Cert{Host: "test.vmware.com", CACertData: "testCAData"})
+	assert.ErrorContains(t, err, "no PEM encoded certificate found")
+
+	err = SetCert(&configtypes.Cert{Host: "test.vmware.com", CACertData: "-----BEGIN CERTIFICATE-----\ndGVzdA==\n-----END CERTIFICATE-----\n"})
+	assert.ErrorContains(t, err, "failed to parse certificate")
+
+	exists, _ := CertExists("test.vmware.com")
+	assert.False(t, exists)
+}
+
+func TestWarnCertExpiry(t *testing.T) {
+	// Setup config data
+	_, cleanUp := setupTestConfig(t, &CfgTestData{})
+	var stderr bytes.Buffer
+	log.SetStderr(&stderr)
+
+	defer func() {
+		cleanUp()
+		log.SetStderr(os.Stderr)
+	}()
+
+	assert.NoError(t, SetCert(&configtypes.Cert{Host: "expiring.vmware.com", CACertData: newTestCACertData(t, "expiring", time.Now().Add(24*time.Hour))}))
+	assert.NoError(t, SetCert(&configtypes.Cert{Host: "valid.vmware.com", CACertData: newTestCACertData(t, "valid", time.Now().Add(365*24*time.Hour))}))
+
+	expiring, err := WarnCertExpiry("expiring.vmware.com", DefaultCertExpiryWarningPeriod)
+	assert.NoError(t, err)
+	assert.Len(t, expiring, 1)
+	assert.Contains(t, stderr.String(), `The CA certificate "CN=expiring" configured for host "expiring.vmware.com" expires on`)
+
+	stderr.Reset()
+	expiring, err = WarnCertExpiry("valid.vmware.com", DefaultCertExpiryWarningPeriod)
+	assert.NoError(t, err)
+	assert.Empty(t, expiring)
+	assert.Empty(t, stderr.String())
+
+	expiring, err = WarnCertExpiry("unknown.vmware.com", DefaultCertExpiryWarningPeriod)
+	assert.NoError(t, err)
+	assert.Empty(t, expiring)
+
+	// Malformed CA certificate data, e.g. written by an older CLI, is reported
+	err = Update(func(tx *ConfigTx) error {
+		persist, err := setCert(tx.node, &configtypes.Cert{Host: "malformed.vmware.com", CACertData: "not a certificate"})
+		tx.markPersist(persist)
+		return err
+	})
+	assert.NoError(t, err)
+	stderr.Reset()
+	expiring, err = WarnCertExpiry("malformed.vmware.com", DefaultCertExpiryWarningPeriod)
+	assert.ErrorContains(t, err, `invalid CA certificate data of cert configuration "malformed.vmware.com"`)
+	assert.Empty(t, expiring)
+	assert.Contains(t, stderr.String(), `The CA certificate data configured for host "malformed.vmware.com" cannot be parsed`)
+}
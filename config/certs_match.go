@@ -0,0 +1,232 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+
+	configtypes "github.com/vmware-tanzu/tanzu-plugin-runtime/config/types"
+)
+
+// CertMatchType is the kind of Host pattern of a cert configuration
+type CertMatchType string
+
+const (
+	// CertMatchExact matches the host name, and the port if the pattern has one, exactly. e.g. "example.com:443"
+	CertMatchExact CertMatchType = "exact"
+	// CertMatchWildcard matches any subdomain of the domain. e.g. "*.corp.example.com"
+	CertMatchWildcard CertMatchType = "wildcard"
+	// CertMatchCIDR matches any IP address of the range. e.g. "10.0.0.0/8"
+	CertMatchCIDR CertMatchType = "cidr"
+)
+
+// certMatchTypeRank orders the match types from the least to the most specific
+var certMatchTypeRank = map[CertMatchType]int{
+	CertMatchCIDR:     1,
+	CertMatchWildcard: 2,
+	CertMatchExact:    3,
+}
+
+// CertMatch is the cert configuration matching a host and the explanation of the match
+type CertMatch struct {
+	// Cert is the matching cert configuration
+	Cert *configtypes.Cert
+	// Type is the kind of Host pattern that matched
+	Type CertMatchType
+	// Reason explains why the cert configuration matched
+	Reason string
+}
+
+// certHostPattern is the parsed Host of a cert configuration
+type certHostPattern struct {
+	matchType CertMatchType
+	// host is the host name of an exact pattern or the domain of a wildcard pattern, lower cased
+	host string
+	// ipNet is the range of a CIDR pattern
+	ipNet *net.IPNet
+	// port is empty if the pattern matches any port
+	port string
+}
+
+// parseCertHost parses the Host of a cert configuration which is a host name or IP address,
+// a wildcard domain "*.example.com" or a CIDR range "10.0.0.0/8", optionally followed by a port
+func parseCertHost(host string) (*certHostPattern, error) {
+	if host == "" {
+		return nil, errors.New("host is empty")
+	}
+	p := &certHostPattern{host: strings.ToLower(host)}
+	if h, port, err := net.SplitHostPort(host); err == nil {
+		p.host, p.port = strings.ToLower(h), port
+	}
+
+	switch {
+	case strings.Contains(p.host, "/"):
+		_, ipNet, err := net.ParseCIDR(p.host)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid CIDR range in host %q", host)
+		}
+		p.matchType, p.ipNet = CertMatchCIDR, ipNet
+	case strings.HasPrefix(p.host, "*."):
+		p.matchType, p.host = CertMatchWildcard, strings.TrimPrefix(p.host, "*.")
+		if p.host == "" || strings.Contains(p.host, "*") {
+			return nil, errors.Errorf("invalid wildcard in host %q, only a leading \"*.\" is supported", host)
+		}
+	default:
+		if strings.Contains(p.host, "*") {
+			return nil, errors.Errorf("invalid wildcard in host %q, only a leading \"*.\" is supported", host)
+		}
+		p.matchType = CertMatchExact
+	}
+	return p, nil
+}
+
+// validateCertHost checks that the Host of the cert configuration is a valid pattern
+func validateCertHost(host string) error {
+	_, err := parseCertHost(host)
+	return err
+}
+
+// match checks whether the pattern matches the host name and port. The port is empty if unknown.
+func (p *certHostPattern) match(hostname, port string) bool {
+	if p.port != "" && p.port != port {
+		return false
+	}
+	switch p.matchType {
+	case CertMatchCIDR:
+		ip := net.ParseIP(hostname)
+		return ip != nil && p.ipNet.Contains(ip)
+	case CertMatchWildcard:
+		return strings.HasSuffix(hostname, "."+p.host)
+	default:
+		return hostname == p.host
+	}
+}
+
+// specificity returns the length of the domain of a wildcard pattern or the prefix length of a CIDR pattern
+func (p *certHostPattern) specificity() int {
+	switch p.matchType {
+	case CertMatchCIDR:
+		ones, _ := p.ipNet.Mask.Size()
+		return ones
+	case CertMatchWildcard:
+		return len(p.host)
+	default:
+		return 0
+	}
+}
+
+// morePreciseThan orders the patterns by match type, exact first, then by specificity,
+// the longest domain or prefix first, and finally patterns with a port before the ones without
+func (p *certHostPattern) morePreciseThan(o *certHostPattern) bool {
+	if certMatchTypeRank[p.matchType] != certMatchTypeRank[o.matchType] {
+		return certMatchTypeRank[p.matchType] > certMatchTypeRank[o.matchType]
+	}
+	if p.specificity() != o.specificity() {
+		return p.specificity() > o.specificity()
+	}
+	return p.port != "" && o.port == ""
+}
+
+func (p *certHostPattern) explain(cert *configtypes.Cert, hostname, port string) string {
+	var reason string
+	switch p.matchType {
+	case CertMatchCIDR:
+		reason = fmt.Sprintf("IP address %s is in range %s of cert configuration %q", hostname, p.ipNet, cert.Host)
+	case CertMatchWildcard:
+		reason = fmt.Sprintf("host %s is a subdomain of %s of cert configuration %q", hostname, p.host, cert.Host)
+	default:
+		reason = fmt.Sprintf("host %s matches cert configuration %q", hostname, cert.Host)
+	}
+	if p.port != "" {
+		return fmt.Sprintf("%s on port %s", reason, port)
+	}
+	return reason + " for any port"
+}
+
+// ResolveCert returns the cert configuration that applies to the URL, which may also be a host or host:port,
+// and explains why it matched. When several cert configurations match, an exact host is preferred over a
+// wildcard domain and a wildcard domain over a CIDR range. Among the same kind, the longest wildcard domain
+// or CIDR prefix is preferred, and a configuration of the specific port over one applying to any port.
+func ResolveCert(rawURL string) (*CertMatch, error) {
+	certs, err := GetCerts()
+	if err != nil {
+		return nil, err
+	}
+	return resolveCert(certs, rawURL)
+}
+
+func resolveCert(certs []*configtypes.Cert, rawURL string) (*CertMatch, error) {
+	hostname, port, err := parseCertTarget(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var best *certHostPattern
+	var match *CertMatch
+	for _, cert := range certs {
+		p, err := parseCertHost(cert.Host)
+		if err != nil || !p.match(hostname, port) {
+			continue
+		}
+		if best == nil || p.morePreciseThan(best) {
+			best = p
+			match = &CertMatch{Cert: cert, Type: p.matchType, Reason: p.explain(cert, hostname, port)}
+		}
+	}
+	if match == nil {
+		return nil, errors.Errorf("cert configuration for %v not found", rawURL)
+	}
+	return match, nil
+}
+
+// parseCertTarget returns the lower cased host name and the port of the URL, host or host:port.
+// The port defaults to the port of the scheme of the URL.
+func parseCertTarget(rawURL string) (hostname, port string, err error) {
+	if rawURL == "" {
+		return "", "", errors.New("host is empty")
+	}
+	if strings.Contains(rawURL, "://") {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			return "", "", errors.Wrapf(err, "invalid url %q", rawURL)
+		}
+		hostname, port = u.Hostname(), u.Port()
+		if port == "" {
+			switch u.Scheme {
+			case "https":
+				port = "443"
+			case "http":
+				port = "80"
+			}
+		}
+		return strings.ToLower(hostname), port, nil
+	}
+	if h, p, err := net.SplitHostPort(rawURL); err == nil {
+		return strings.ToLower(h), p, nil
+	}
+	return strings.ToLower(strings.Trim(rawURL, "[]")), "", nil
+}
+
+// lookupCert returns the cert configuration of the host, the exact Host entry if any, the best match otherwise
+func lookupCert(node *yaml.Node, host string) (*configtypes.Cert, error) {
+	cert, err := getCert(node, host)
+	if err == nil {
+		return cert, nil
+	}
+	certs, certsErr := getCerts(node)
+	if certsErr != nil {
+		return nil, certsErr
+	}
+	match, matchErr := resolveCert(certs, host)
+	if matchErr != nil {
+		return nil, err
+	}
+	return match.Cert, nil
+}
@@ -0,0 +1,95 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	configtypes "github.com/vmware-tanzu/tanzu-plugin-runtime/config/types"
+)
+
+func TestResolveCert(t *testing.T) {
+	// Setup config data
+	_, cleanUp := setupTestConfig(t, &CfgTestData{})
+
+	defer func() {
+		cleanUp()
+	}()
+
+	for _, host := range []string{
+		"api.corp.example.com:8443",
+		"api.corp.example.com",
+		"*.example.com",
+		"*.corp.example.com",
+		"*.corp.example.com:443",
+		"10.0.0.0/8",
+		"10.1.0.0/16",
+		"192.168.1.10:6443",
+	} {
+		assert.NoError(t, SetCert(&configtypes.Cert{Host: host, SkipCertVerify: "true"}))
+	}
+
+	tests := []struct {
+		url       string
+		host      string
+		matchType CertMatchType
+		reason    string
+		err       string
+	}{
+		{url: "https://api.corp.example.com:8443/v1", host: "api.corp.example.com:8443", matchType: CertMatchExact},
+		{url: "https://API.corp.example.com", host: "api.corp.example.com", matchType: CertMatchExact, reason: "for any port"},
+		{url: "api.corp.example.com:9443", host: "api.corp.example.com", matchType: CertMatchExact},
+		{url: "https://ui.corp.example.com", host: "*.corp.example.com:443", matchType: CertMatchWildcard, reason: "on port 443"},
+		{url: "ui.corp.example.com:80", host: "*.corp.example.com", matchType: CertMatchWildcard},
+		{url: "https://a.b.example.com", host: "*.example.com", matchType: CertMatchWildcard, reason: "subdomain of example.com"},
+		{url: "https://10.1.2.3", host: "10.1.0.0/16", matchType: CertMatchCIDR, reason: "in range 10.1.0.0/16"},
+		{url: "10.2.2.3:6443", host: "10.0.0.0/8", matchType: CertMatchCIDR},
+		{url: "192.168.1.10:6443", host: "192.168.1.10:6443", matchType: CertMatchExact},
+		{url: "https://192.168.1.10", err: "cert configuration for https://192.168.1.10 not found"},
+		{url: "https://example.com", err: "cert configuration for https://example.com not found"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.url, func(t *testing.T) {
+			match, err := ResolveCert(tc.url)
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.host, match.Cert.Host)
+			assert.Equal(t, tc.matchType, match.Type)
+			assert.Contains(t, match.Reason, tc.reason)
+		})
+	}
+
+	// GetCert prefers the exact host and falls back to the best match
+	cert, err := GetCert("*.example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, "*.example.com", cert.Host)
+	cert, err = GetCert("10.3.3.3")
+	assert.NoError(t, err)
+	assert.Equal(t, "10.0.0.0/8", cert.Host)
+
+	// Deletion only applies to the exact host
+	err = DeleteCert("10.3.3.3")
+	assert.EqualError(t, err, "cert configuration for 10.3.3.3 not found")
+}
+
+func TestSetCertInvalidHost(t *testing.T) {
+	// Setup config data
+	_, cleanUp := setupTestConfig(t, &CfgTestData{})
+
+	defer func() {
+		cleanUp()
+	}()
+
+	err := SetCert(&configtypes.Cert{Host: "10.0.0.0/33"})
+	assert.ErrorContains(t, err, "invalid CIDR range")
+	err = SetCert(&configtypes.Cert{Host: "api.*.example.com"})
+	assert.ErrorContains(t, err, "invalid wildcard")
+	err = SetCert(&configtypes.Cert{Host: "*."})
+	assert.ErrorContains(t, err, "invalid wildcard")
+}
@@ -5,6 +5,7 @@ package config
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -19,15 +20,17 @@ func TestSetGetDeleteCerts(t *testing.T) {
 		cleanUp()
 	}()
 
+	testCAData := newTestCACertData(t, "test.vmware.com", time.Now().Add(24*time.Hour))
+
 	cert1 := &configtypes.Cert{
 		Host:           "test1",
-		CACertData:     "<REDACTED>",
+		CACertData:     testCAData,
 		SkipCertVerify: "false",
 	}
 
 	cert2 := &configtypes.Cert{
 		Host:           "test2",
-		CACertData:     "<REDACTED>",
+		CACertData:     testCAData,
 		SkipCertVerify: "true",
 		Insecure:       "false",
 	}
@@ -75,6 +78,10 @@ func TestSetCert(t *testing.T) {
 		cleanUp()
 	}()
 
+	testCAData := newTestCACertData(t, "test.vmware.com", time.Now().Add(24*time.Hour))
+	testCADataUpdated := newTestCACertData(t, "test.vmware.com", time.Now().Add(48*time.Hour))
+	testCAData2 := newTestCACertData(t, "test2.vmware.com", time.Now().Add(24*time.Hour))
+
 	tests := []struct {
 		name   string
 		cert   *configtypes.Cert
@@ -84,7 +91,7 @@ func TestSetCert(t *testing.T) {
 			name: "should add new cert to empty client config",
 			cert: &configtypes.Cert{
 				Host:           "test.vmware.com",
-				CACertData:     "testCAData",
+				CACertData:     testCAData,
 				SkipCertVerify: "true",
 				Insecure:       "true",
 			},
@@ -93,7 +100,7 @@ func TestSetCert(t *testing.T) {
 			name: "should update existing cert",
 			cert: &configtypes.Cert{
 				Host:           "test.vmware.com",
-				CACertData:     "testCADataUpdated",
+				CACertData:     testCADataUpdated,
 				Insecure:       "false",
 				SkipCertVerify: "false",
 			},
@@ -102,7 +109,7 @@ func TestSetCert(t *testing.T) {
 			name: "should update existing cert with SkipCertVerify and Insecure field",
 			cert: &configtypes.Cert{
 				Host:           "test.vmware.com",
-				CACertData:     "testCADataUpdated",
+				CACertData:     testCADataUpdated,
 				SkipCertVerify: "true",
 				Insecure:       "true",
 			},
@@ -111,7 +118,7 @@ func TestSetCert(t *testing.T) {
 			name: "should add the new cert to the existing certs",
 			cert: &configtypes.Cert{
 				Host:           "test.vmware.com:443",
-				CACertData:     "testCAData2",
+				CACertData:     testCAData2,
 				SkipCertVerify: "true",
 				Insecure:       "false",
 			},
@@ -120,7 +127,7 @@ func TestSetCert(t *testing.T) {
 			name: "should return error when the host is empty",
 			cert: &configtypes.Cert{
 				Host:           "",
-				CACertData:     "testCAData2",
+				CACertData:     testCAData2,
 				SkipCertVerify: "true",
 				Insecure:       "false",
 			},
@@ -170,6 +177,10 @@ func TestGetCerts(t *testing.T) {
 		cleanUp()
 	}()
 
+	testCAData := newTestCACertData(t, "test.vmware.com", time.Now().Add(24*time.Hour))
+	testCADataUpdated := newTestCACertData(t, "test.vmware.com", time.Now().Add(48*time.Hour))
+	testCAData2 := newTestCACertData(t, "test2.vmware.com", time.Now().Add(24*time.Hour))
+
 	tests := []struct {
 		name      string
 		cert      *configtypes.Cert
@@ -185,13 +196,13 @@ func TestGetCerts(t *testing.T) {
 			name: "should return the cert added",
 			cert: &configtypes.Cert{
 				Host:       "test.vmware.com",
-				CACertData: "testCAData",
+				CACertData: testCAData,
 				Insecure:   "false",
 			},
 			wantCerts: []*configtypes.Cert{
 				{
 					Host:       "test.vmware.com",
-					CACertData: "testCAData",
+					CACertData: testCAData,
 					Insecure:   "false",
 				},
 			},
@@ -200,13 +211,13 @@ func TestGetCerts(t *testing.T) {
 			name: "should return the cert updated",
 			cert: &configtypes.Cert{
 				Host:       "test.vmware.com",
-				CACertData: "testCADataUpdated",
+				CACertData: testCADataUpdated,
 				Insecure:   "true",
 			},
 			wantCerts: []*configtypes.Cert{
 				{
 					Host:       "test.vmware.com",
-					CACertData: "testCADataUpdated",
+					CACertData: testCADataUpdated,
 					Insecure:   "true",
 				},
 			},
@@ -215,19 +226,19 @@ func TestGetCerts(t *testing.T) {
 			name: "should return both the existing and the new cert added",
 			cert: &configtypes.Cert{
 				Host:           "test.vmware.com:443",
-				CACertData:     "testCAData2",
+				CACertData:     testCAData2,
 				SkipCertVerify: "true",
 				Insecure:       "false",
 			},
 			wantCerts: []*configtypes.Cert{
 				{
 					Host:       "test.vmware.com",
-					CACertData: "testCADataUpdated",
+					CACertData: testCADataUpdated,
 					Insecure:   "true",
 				},
 				{
 					Host:           "test.vmware.com:443",
-					CACertData:     "testCAData2",
+					CACertData:     testCAData2,
 					SkipCertVerify: "true",
 					Insecure:       "false",
 				},
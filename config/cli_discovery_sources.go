@@ -38,8 +38,11 @@ func GetCLIDiscoverySource(name string) (*configtypes.PluginDiscovery, error) {
 // SetCLIDiscoverySources Add/Update array of cli discovery sources to the yaml node
 func SetCLIDiscoverySources(discoverySources []configtypes.PluginDiscovery) (err error) {
 	// Retrieve client config node
-	AcquireTanzuConfigLock()
-	defer ReleaseTanzuConfigLock()
+	unlock, err := acquireTanzuConfigLock()
+	if err != nil {
+		return err
+	}
+	defer unlock.Unlock()
 	node, err := getClientConfigNodeNoLock()
 	if err != nil {
 		return err
@@ -65,8 +68,11 @@ func SetCLIDiscoverySources(discoverySources []configtypes.PluginDiscovery) (err
 // SetCLIDiscoverySource add or update a cli discoverySource
 func SetCLIDiscoverySource(discoverySource configtypes.PluginDiscovery) (err error) {
 	// Retrieve client config node
-	AcquireTanzuConfigLock()
-	defer ReleaseTanzuConfigLock()
+	unlock, err := acquireTanzuConfigLock()
+	if err != nil {
+		return err
+	}
+	defer unlock.Unlock()
 	node, err := getClientConfigNodeNoLock()
 	if err != nil {
 		return err
@@ -89,8 +95,11 @@ func SetCLIDiscoverySource(discoverySource configtypes.PluginDiscovery) (err err
 // DeleteCLIDiscoverySource delete cli discoverySource by name
 func DeleteCLIDiscoverySource(name string) error {
 	// Retrieve client config node
-	AcquireTanzuConfigLock()
-	defer ReleaseTanzuConfigLock()
+	unlock, err := acquireTanzuConfigLock()
+	if err != nil {
+		return err
+	}
+	defer unlock.Unlock()
 	node, err := getClientConfigNodeNoLock()
 	if err != nil {
 		return err
@@ -145,7 +145,8 @@ servers:
           contextType: tmc
 current: test-mc
 `
-	expectedCFG2 := `cli:
+	expectedCFG2 := `apiVersion: config.tanzu.vmware.com/v1alpha3
+cli:
     discoverySources:
         - oci:
             name: default
@@ -185,6 +186,7 @@ contexts:
             annotation: two
             required: true
           contextType: tmc
+      contextType: kubernetes
 currentContext:
     kubernetes: test-mc
 `
@@ -270,7 +272,8 @@ func setupDataWithPatchStrategy() (string, string, string, string) {
 `
 	expectedCfg := `{}
 `
-	expectedCfg2 := `cli:
+	expectedCfg2 := `apiVersion: config.tanzu.vmware.com/v1alpha3
+cli:
     discoverySources:
         - oci:
             name: default
@@ -31,8 +31,11 @@ func SetEdition(val string) (err error) {
 		return errors.New("value cannot be empty")
 	}
 	// Retrieve client config node
-	AcquireTanzuConfigLock()
-	defer ReleaseTanzuConfigLock()
+	unlock, err := acquireTanzuConfigLock()
+	if err != nil {
+		return err
+	}
+	defer unlock.Unlock()
 	node, err := getClientConfigNodeNoLock()
 	if err != nil {
 		return err
@@ -38,8 +38,11 @@ func GetCEIPOptIn() (string, error) {
 // SetCEIPOptIn adds or updates ceipOptIn value
 func SetCEIPOptIn(val string) (err error) {
 	// Retrieve client config node
-	AcquireTanzuConfigLock()
-	defer ReleaseTanzuConfigLock()
+	unlock, err := acquireTanzuConfigLock()
+	if err != nil {
+		return err
+	}
+	defer unlock.Unlock()
 	node, err := getClientConfigNodeNoLock()
 	if err != nil {
 		return err
@@ -83,8 +86,11 @@ func SetEULAStatus(val EULAStatus) (err error) {
 	}
 
 	// Retrieve client config node
-	AcquireTanzuConfigLock()
-	defer ReleaseTanzuConfigLock()
+	unlock, err := acquireTanzuConfigLock()
+	if err != nil {
+		return err
+	}
+	defer unlock.Unlock()
 	node, err := getClientConfigNodeNoLock()
 	if err != nil {
 		return err
@@ -123,8 +129,11 @@ func SetEULAAcceptedVersions(acceptedVersions []string) (err error) {
 	}
 
 	// Retrieve client config node
-	AcquireTanzuConfigLock()
-	defer ReleaseTanzuConfigLock()
+	unlock, err := acquireTanzuConfigLock()
+	if err != nil {
+		return err
+	}
+	defer unlock.Unlock()
 	node, err := getClientConfigNodeNoLock()
 	if err != nil {
 		return err
@@ -183,8 +192,11 @@ func GetCLIId() (string, error) {
 // SetCLIId adds or updates cliId value
 func SetCLIId(val string) (err error) {
 	// Retrieve client config node
-	AcquireTanzuConfigLock()
-	defer ReleaseTanzuConfigLock()
+	unlock, err := acquireTanzuConfigLock()
+	if err != nil {
+		return err
+	}
+	defer unlock.Unlock()
 	node, err := getClientConfigNodeNoLock()
 	if err != nil {
 		return err
@@ -28,8 +28,11 @@ func SetCLITelemetryOptions(c *configtypes.TelemetryOptions) error {
 		return nil
 	}
 	// Retrieve client config node
-	AcquireTanzuConfigLock()
-	defer ReleaseTanzuConfigLock()
+	unlock, err := acquireTanzuConfigLock()
+	if err != nil {
+		return err
+	}
+	defer unlock.Unlock()
 	node, err := getClientConfigNodeNoLock()
 	if err != nil {
 		return err
@@ -51,8 +54,11 @@ func SetCLITelemetryOptions(c *configtypes.TelemetryOptions) error {
 // DeleteTelemetryOptions deletes the telemetry options  from the CLI configuration
 func DeleteTelemetryOptions() error {
 	// Retrieve client config node
-	AcquireTanzuConfigLock()
-	defer ReleaseTanzuConfigLock()
+	unlock, err := acquireTanzuConfigLock()
+	if err != nil {
+		return err
+	}
+	defer unlock.Unlock()
 	node, err := getClientConfigNodeNoLock()
 	if err != nil {
 		return err
@@ -44,8 +44,11 @@ func GetCLIRepository(name string) (*configtypes.PluginRepository, error) {
 // Deprecated: This API is deprecated
 func SetCLIRepository(repository configtypes.PluginRepository) (err error) {
 	// Retrieve client config node
-	AcquireTanzuConfigLock()
-	defer ReleaseTanzuConfigLock()
+	unlock, err := acquireTanzuConfigLock()
+	if err != nil {
+		return err
+	}
+	defer unlock.Unlock()
 	node, err := getClientConfigNodeNoLock()
 	if err != nil {
 		return err
@@ -73,8 +76,11 @@ func SetCLIRepository(repository configtypes.PluginRepository) (err error) {
 // Deprecated: This API is deprecated
 func DeleteCLIRepository(name string) error {
 	// Retrieve client config node
-	AcquireTanzuConfigLock()
-	defer ReleaseTanzuConfigLock()
+	unlock, err := acquireTanzuConfigLock()
+	if err != nil {
+		return err
+	}
+	defer unlock.Unlock()
 	node, err := getClientConfigNodeNoLock()
 	if err != nil {
 		return err
@@ -163,7 +163,8 @@ current: test-mc
 currentContext:
     kubernetes: test-mc
 `
-	expectedCfg2 := `contexts:
+	expectedCfg2 := `apiVersion: config.tanzu.vmware.com/v1alpha3
+contexts:
     - name: test-mc
       target: kubernetes
       group: one
@@ -191,6 +192,7 @@ currentContext:
             annotation: two
             required: true
           contextType: tmc
+      contextType: kubernetes
 currentContext:
     kubernetes: test-mc
 `
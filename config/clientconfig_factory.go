@@ -9,8 +9,6 @@
 package config
 
 import (
-	"os"
-
 	"github.com/pkg/errors"
 	"gopkg.in/yaml.v3"
 
@@ -19,19 +17,40 @@ import (
 
 // getClientConfigNode retrieves the multi config from the local directory with file lock
 func getClientConfigNode() (*yaml.Node, error) {
+	// Apply the pending migrations the first time the config is loaded
+	migrateInMemory := ensureConfigMigratedOnRead()
+
 	useUnifiedConfig, err := UseUnifiedConfig()
 	if err != nil {
 		useUnifiedConfig = false
 	}
 
+	var node *yaml.Node
 	if useUnifiedConfig {
-		return getClientConfigNextGenNode()
+		node, err = getClientConfigNextGenNode()
+	} else {
+		node, err = getMultiConfig()
+	}
+	if err != nil || !migrateInMemory {
+		return node, err
+	}
+	if err := migrateConfigNodeInMemory(node); err != nil {
+		return nil, err
 	}
-	return getMultiConfig()
+	return node, nil
 }
 
 // getClientConfigNodeNoLock retrieves the multi config from the local directory without acquiring the lock
 func getClientConfigNodeNoLock() (*yaml.Node, error) {
+	// Apply the pending migrations the first time the config is loaded
+	if err := ensureConfigMigratedNoLock(); err != nil {
+		return nil, err
+	}
+	return readClientConfigNodeNoLock()
+}
+
+// readClientConfigNodeNoLock reads the multi config as stored, without acquiring the lock
+func readClientConfigNodeNoLock() (*yaml.Node, error) {
 	// Check config migration feature flag
 	useUnifiedConfig, err := UseUnifiedConfig()
 	if err != nil {
@@ -47,8 +66,11 @@ func getClientConfigNodeNoLock() (*yaml.Node, error) {
 // getClientConfig retrieves the config from the local directory with file lock
 func getClientConfig() (*yaml.Node, error) {
 	// Acquire tanzu config lock
-	AcquireTanzuConfigLock()
-	defer ReleaseTanzuConfigLock()
+	unlock, err := acquireTanzuConfigLock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock.Unlock()
 	return getClientConfigNoLock()
 }
 
@@ -58,7 +80,8 @@ func getClientConfigNoLock() (*yaml.Node, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, "getClientConfigNodeNoLock: failed getting client config path")
 	}
-	bytes, err := os.ReadFile(cfgPath)
+	// Read the committed config, including a commit of config.yaml and config-ng.yaml still pending
+	bytes, err := readCommittedFile(cfgPath)
 	if err != nil || len(bytes) == 0 {
 		node, err := newClientConfigNode()
 		if err != nil {
@@ -85,12 +108,3 @@ func newClientConfigNode() (*yaml.Node, error) {
 	}
 	return node, nil
 }
-
-// persistClientConfig write to config.yaml
-func persistClientConfig(node *yaml.Node) error {
-	path, err := ClientConfigPath()
-	if err != nil {
-		return errors.Wrap(err, "could not find config path")
-	}
-	return persistNode(node, WithCfgPath(path))
-}
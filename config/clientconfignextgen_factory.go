@@ -9,8 +9,6 @@
 package config
 
 import (
-	"os"
-
 	"github.com/pkg/errors"
 	"gopkg.in/yaml.v3"
 )
@@ -18,8 +16,11 @@ import (
 // getClientConfigNextGenNode retrieves the config from the local directory with file lock
 func getClientConfigNextGenNode() (*yaml.Node, error) {
 	// Acquire tanzu config v2 lock
-	AcquireTanzuConfigNextGenLock()
-	defer ReleaseTanzuConfigNextGenLock()
+	unlock, err := acquireTanzuConfigNextGenLock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock.Unlock()
 	return getClientConfigNextGenNodeNoLock()
 }
 
@@ -29,7 +30,8 @@ func getClientConfigNextGenNodeNoLock() (*yaml.Node, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, "failed getting client config path")
 	}
-	bytes, err := os.ReadFile(cfgPath)
+	// Read the committed config, including a commit of config.yaml and config-ng.yaml still pending
+	bytes, err := readCommittedFile(cfgPath)
 	if err != nil || len(bytes) == 0 {
 		node, err := newClientConfigNode()
 		if err != nil {
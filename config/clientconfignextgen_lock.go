@@ -5,12 +5,10 @@
 package config
 
 import (
+	"context"
 	"fmt"
 	"path/filepath"
-	"sync"
 	"time"
-
-	"github.com/juju/fslock"
 )
 
 const (
@@ -19,49 +17,49 @@ const (
 	DefaultConfigNextGenLockTimeout = 10 * time.Minute
 )
 
-var cfgNextGenLockFile string
-
-// cfgNextGenLock used as a static lock variable that stores fslock
-// This is used for interprocess locking of the config file
-var cfgNextGenLock *fslock.Lock
+// cfgNextGenLock used as a static lock that is used for interprocess locking of the config-ng file
+var cfgNextGenLock = newTanzuLock("tanzu config ng file", func() (string, error) {
+	path, err := ClientConfigNextGenPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(path), LocalTanzuConfigNextGenFileLock), nil
+})
 
-// cfgNextGenMutex is used to handle the locking behavior between concurrent calls
-// within the existing process trying to acquire the lock
-var cfgNextGenMutex sync.Mutex
+// cfgNextGenUnlocker stores the unlocker of the lock acquired with AcquireTanzuConfigNextGenLock
+var cfgNextGenUnlocker Unlocker
 
-// AcquireTanzuConfigNextGenLock tries to acquire lock to update tanzu config file with timeout
-func AcquireTanzuConfigNextGenLock() {
-	var err error
+// AcquireTanzuConfigNextGenLockContext acquires the lock to update tanzu config-ng file waiting until
+// the context is done. The returned Unlocker must be used to release the lock.
+func AcquireTanzuConfigNextGenLockContext(ctx context.Context) (Unlocker, error) {
+	return cfgNextGenLock.acquire(ctx)
+}
 
-	if cfgNextGenLockFile == "" {
-		path, err := ClientConfigNextGenPath()
-		if err != nil {
-			panic(fmt.Sprintf("cannot get config path while acquiring lock on tanzu config file, reason: %v", err))
-		}
-		cfgNextGenLockFile = filepath.Join(filepath.Dir(path), LocalTanzuConfigNextGenFileLock)
-	}
+// acquireTanzuConfigNextGenLock acquires the tanzu config ng lock waiting up to DefaultConfigNextGenLockTimeout
+func acquireTanzuConfigNextGenLock() (Unlocker, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultConfigNextGenLockTimeout)
+	defer cancel()
+	return AcquireTanzuConfigNextGenLockContext(ctx)
+}
 
-	// using fslock to handle interprocess locking
-	lock, err := getFileLockWithTimeOut(cfgNextGenLockFile, DefaultConfigNextGenLockTimeout)
+// AcquireTanzuConfigNextGenLock tries to acquire lock to update tanzu config file with timeout.
+// It panics on failure, use AcquireTanzuConfigNextGenLockContext to handle the error instead.
+func AcquireTanzuConfigNextGenLock() {
+	unlocker, err := acquireTanzuConfigNextGenLock()
 	if err != nil {
 		panic(fmt.Sprintf("cannot acquire lock for tanzu config file, reason: %v", err))
 	}
-
-	// Lock the mutex to prevent concurrent calls to acquire and configure the tanzuConfigLock
-	cfgNextGenMutex.Lock()
-	cfgNextGenLock = lock
+	cfgNextGenUnlocker = unlocker
 }
 
 // ReleaseTanzuConfigNextGenLock releases the lock if the tanzuConfigLock was acquired
 func ReleaseTanzuConfigNextGenLock() {
-	if cfgNextGenLock == nil {
+	if cfgNextGenUnlocker == nil {
 		return
 	}
-	if errUnlock := cfgNextGenLock.Unlock(); errUnlock != nil {
+	unlocker := cfgNextGenUnlocker
+	cfgNextGenUnlocker = nil
+	if errUnlock := unlocker.Unlock(); errUnlock != nil {
 		panic(fmt.Sprintf("cannot release lock for tanzu config file, reason: %v", errUnlock))
 	}
-
-	cfgNextGenLock = nil
-	// Unlock the mutex to allow other concurrent calls to acquire and configure the tanzuConfigLock
-	cfgNextGenMutex.Unlock()
 }
@@ -0,0 +1,169 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+
+	"github.com/vmware-tanzu/tanzu-plugin-runtime/config/internal/atomicfile"
+)
+
+// CfgCommitJournalName is the name of the journal file used to commit config.yaml and config-ng.yaml together
+const CfgCommitJournalName = ".tanzu-config.journal"
+
+// fileWrite is a pending write of data to a config file
+type fileWrite struct {
+	path string
+	data []byte
+}
+
+// commitJournal records the staged files that have to be renamed to their targets
+// for a multi file commit to be complete
+type commitJournal struct {
+	Entries []commitJournalEntry `yaml:"entries"`
+}
+
+type commitJournalEntry struct {
+	Staged string `yaml:"staged"`
+	Target string `yaml:"target"`
+}
+
+// commitJournalPath returns the path of the commit journal, which lives next to the config.yaml
+func commitJournalPath() (string, error) {
+	cfgPath, err := ClientConfigPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(cfgPath), CfgCommitJournalName), nil
+}
+
+// commitFiles writes all the files as a single commit.
+// All the files are first staged next to their targets, then the journal listing the
+// pending renames is written and finally the staged files are renamed. If the process
+// dies after the journal is written the commit is completed by recoverConfigCommit
+// on the next write, otherwise none of the targets are modified.
+// Pre-reqs: tanzu config lock is acquired
+func commitFiles(writes []fileWrite) error {
+	journalPath, err := commitJournalPath()
+	if err != nil {
+		return errors.Wrap(err, "could not find config commit journal path")
+	}
+
+	journal := &commitJournal{}
+	discardStaged := func() {
+		for _, entry := range journal.Entries {
+			_ = os.Remove(entry.Staged)
+		}
+	}
+	for _, w := range writes {
+		staged, err := atomicfile.Stage(w.path, w.data, 0644)
+		if err != nil {
+			discardStaged()
+			return errors.Wrapf(err, "failed to stage the config file %s", w.path)
+		}
+		journal.Entries = append(journal.Entries, commitJournalEntry{Staged: staged, Target: w.path})
+	}
+
+	data, err := yaml.Marshal(journal)
+	if err != nil {
+		discardStaged()
+		return errors.Wrap(err, "failed to marshal config commit journal")
+	}
+	staged, err := atomicfile.Stage(journalPath, data, 0600)
+	if err != nil {
+		discardStaged()
+		return errors.Wrap(err, "failed to stage config commit journal")
+	}
+	// Renaming the journal in place is the commit point
+	if err := os.Rename(staged, journalPath); err != nil {
+		_ = os.Remove(staged)
+		discardStaged()
+		return errors.Wrap(err, "failed to write config commit journal")
+	}
+	if err := atomicfile.SyncDir(filepath.Dir(journalPath)); err != nil {
+		return errors.Wrap(err, "failed to write config commit journal")
+	}
+	return applyCommitJournal(journalPath, journal)
+}
+
+// recoverConfigCommit completes a multi file commit interrupted after the commit point.
+// This is a no-op if there is no pending commit.
+// Pre-reqs: tanzu config lock is acquired
+func recoverConfigCommit() error {
+	journalPath, err := commitJournalPath()
+	if err != nil {
+		return errors.Wrap(err, "could not find config commit journal path")
+	}
+	journal, err := readCommitJournal(journalPath)
+	if err != nil || journal == nil {
+		return err
+	}
+	return applyCommitJournal(journalPath, journal)
+}
+
+// readCommitJournal reads the journal of the pending commit, nil if there is none
+func readCommitJournal(journalPath string) (*commitJournal, error) {
+	data, err := os.ReadFile(journalPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read config commit journal")
+	}
+	journal := &commitJournal{}
+	if err := yaml.Unmarshal(data, journal); err != nil {
+		return nil, errors.Wrap(err, "failed to parse config commit journal")
+	}
+	return journal, nil
+}
+
+// readCommittedFile reads the content of the config file as of the last commit. If a commit was
+// interrupted after the commit point, the staged content of the file is returned. Unlike
+// recoverConfigCommit, the pending commit is left to be completed by the next writer, so the
+// config can be read without acquiring the tanzu config lock.
+func readCommittedFile(path string) ([]byte, error) {
+	if journalPath, err := commitJournalPath(); err == nil {
+		// An unreadable journal is ignored by readers, the next writer reports it
+		if journal, err := readCommitJournal(journalPath); err == nil && journal != nil {
+			for _, entry := range journal.Entries {
+				if entry.Target != path {
+					continue
+				}
+				data, err := os.ReadFile(entry.Staged)
+				// The staged file was already renamed to its target
+				if os.IsNotExist(err) {
+					break
+				}
+				return data, err
+			}
+		}
+	}
+	return os.ReadFile(path)
+}
+
+// applyCommitJournal renames the staged files of the journal that are still pending and removes the journal.
+// The staged files and the journal are kept if a rename fails, so that recoverConfigCommit retries the commit.
+func applyCommitJournal(journalPath string, journal *commitJournal) error {
+	for _, entry := range journal.Entries {
+		stagedExists, err := fileExists(entry.Staged)
+		if err != nil {
+			return err
+		}
+		// The staged file was already renamed before the commit got interrupted
+		if !stagedExists {
+			continue
+		}
+		if err := commitStagedFile(entry.Staged, entry.Target); err != nil {
+			return errors.Wrapf(err, "failed to write the config file %s", entry.Target)
+		}
+	}
+	if err := os.Remove(journalPath); err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "failed to remove config commit journal")
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+
+	"github.com/vmware-tanzu/tanzu-plugin-runtime/config/internal/atomicfile"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir, err := os.MkdirTemp("", "tanzu_atomic_write")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "config.yaml")
+
+	err = writeFileAtomic(path, []byte("v1"), 0644)
+	assert.NoError(t, err)
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "v1", string(data))
+	exists, err := fileExists(path + BackupFileSuffix)
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	err = writeFileAtomic(path, []byte("v2"), 0644)
+	assert.NoError(t, err)
+	data, err = os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "v2", string(data))
+	data, err = os.ReadFile(path + BackupFileSuffix)
+	assert.NoError(t, err)
+	assert.Equal(t, "v1", string(data))
+
+	// No temporary files are left behind
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(entries))
+}
+
+func TestPersistConfigCommit(t *testing.T) {
+	// Setup config data
+	files, cleanUp := setupTestConfig(t, &CfgTestData{cfg: "current: old\n", cfgNextGen: "apiVersion: " + CurrentConfigAPIVersion + "\ncurrentContext: {}\n"})
+
+	defer func() {
+		cleanUp()
+	}()
+
+	err := SetEnv("env1", "val1")
+	assert.NoError(t, err)
+
+	journalPath, err := commitJournalPath()
+	assert.NoError(t, err)
+	exists, err := fileExists(journalPath)
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	// The previous version of each file is kept as backup
+	data, err := os.ReadFile(files[0].Name() + BackupFileSuffix)
+	assert.NoError(t, err)
+	assert.Equal(t, "current: old\n", string(data))
+	data, err = os.ReadFile(files[1].Name() + BackupFileSuffix)
+	assert.NoError(t, err)
+	assert.Equal(t, "apiVersion: "+CurrentConfigAPIVersion+"\ncurrentContext: {}\n", string(data))
+
+	val, err := GetEnv("env1")
+	assert.NoError(t, err)
+	assert.Equal(t, "val1", val)
+}
+
+func TestRecoverConfigCommit(t *testing.T) {
+	// Setup config data
+	files, cleanUp := setupTestConfig(t, &CfgTestData{cfg: "current: old\n", cfgNextGen: "apiVersion: " + CurrentConfigAPIVersion + "\ncurrentContext: {}\n"})
+
+	defer func() {
+		cleanUp()
+	}()
+
+	// Simulate a commit interrupted after the journal is written and config.yaml is renamed
+	cfgData := []byte("clientOptions:\n  env:\n    env1: val1\n")
+	err := os.WriteFile(files[0].Name(), cfgData, 0644)
+	assert.NoError(t, err)
+	cfgNextGenData := []byte("apiVersion: " + CurrentConfigAPIVersion + "\ncurrentContext:\n  kubernetes: test-mc\ncontexts:\n  - name: test-mc\n    contextType: kubernetes\n")
+	staged, err := atomicfile.Stage(files[1].Name(), cfgNextGenData, 0644)
+	assert.NoError(t, err)
+
+	journal := &commitJournal{
+		Entries: []commitJournalEntry{
+			{Staged: files[0].Name() + ".tmp-gone", Target: files[0].Name()},
+			{Staged: staged, Target: files[1].Name()},
+		},
+	}
+	data, err := yaml.Marshal(journal)
+	assert.NoError(t, err)
+	journalPath, err := commitJournalPath()
+	assert.NoError(t, err)
+	err = os.WriteFile(journalPath, data, 0600)
+	assert.NoError(t, err)
+
+	// Reading the config sees the pending commit without completing it
+	ctx, err := GetActiveContext("kubernetes")
+	assert.NoError(t, err)
+	assert.Equal(t, "test-mc", ctx.Name)
+	val, err := GetEnv("env1")
+	assert.NoError(t, err)
+	assert.Equal(t, "val1", val)
+
+	exists, err := fileExists(journalPath)
+	assert.NoError(t, err)
+	assert.True(t, exists)
+	data, err = os.ReadFile(files[1].Name())
+	assert.NoError(t, err)
+	assert.Equal(t, "apiVersion: "+CurrentConfigAPIVersion+"\ncurrentContext: {}\n", string(data))
+
+	// The next write completes the pending commit under the tanzu config lock
+	err = SetEnv("env2", "val2")
+	assert.NoError(t, err)
+
+	exists, err = fileExists(journalPath)
+	assert.NoError(t, err)
+	assert.False(t, exists)
+	exists, err = fileExists(staged)
+	assert.NoError(t, err)
+	assert.False(t, exists)
+	ctx, err = GetActiveContext("kubernetes")
+	assert.NoError(t, err)
+	assert.Equal(t, "test-mc", ctx.Name)
+	envs, err := GetAllEnvs()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"env1": "val1", "env2": "val2"}, envs)
+}
+
+func TestRecoverConfigCommitRetriesFailedRename(t *testing.T) {
+	// Setup config data
+	files, cleanUp := setupTestConfig(t, &CfgTestData{cfg: "current: old\n", cfgNextGen: "apiVersion: " + CurrentConfigAPIVersion + "\ncurrentContext: {}\n"})
+
+	defer func() {
+		cleanUp()
+	}()
+
+	// The backup of config-ng.yaml cannot be written, so its staged file cannot be committed
+	backup := files[1].Name() + BackupFileSuffix
+	assert.NoError(t, os.RemoveAll(backup))
+	assert.NoError(t, os.MkdirAll(filepath.Join(backup, "blocker"), 0755))
+
+	cfgNextGenData := []byte("apiVersion: " + CurrentConfigAPIVersion + "\ncurrentContext:\n  kubernetes: test-mc\n")
+	err := commitFiles([]fileWrite{
+		{path: files[0].Name(), data: []byte("current: new\n")},
+		{path: files[1].Name(), data: cfgNextGenData},
+	})
+	assert.Error(t, err)
+
+	// The journal and the staged file are kept for the recovery
+	journalPath, err := commitJournalPath()
+	assert.NoError(t, err)
+	journal, err := readCommitJournal(journalPath)
+	assert.NoError(t, err)
+	assert.NotNil(t, journal)
+	exists, err := fileExists(journal.Entries[1].Staged)
+	assert.NoError(t, err)
+	assert.True(t, exists)
+
+	err = recoverConfigCommit()
+	assert.Error(t, err)
+	exists, err = fileExists(journalPath)
+	assert.NoError(t, err)
+	assert.True(t, exists)
+
+	// The recovery completes the commit once the rename can succeed
+	assert.NoError(t, os.RemoveAll(backup))
+	assert.NoError(t, recoverConfigCommit())
+	exists, err = fileExists(journalPath)
+	assert.NoError(t, err)
+	assert.False(t, exists)
+	data, err := os.ReadFile(files[0].Name())
+	assert.NoError(t, err)
+	assert.Equal(t, "current: new\n", string(data))
+	data, err = os.ReadFile(files[1].Name())
+	assert.NoError(t, err)
+	assert.Equal(t, string(cfgNextGenData), string(data))
+}
@@ -78,8 +78,14 @@ func makeMultiFileCfg(cfgNode, cfgNextGenNode *yaml.Node) (*yaml.Node, error) {
 		rootCfgNode.Content[0].Content = append(rootCfgNode.Content[0].Content, cfgNode.Content[0].Content[cfgNodeIndex-1:cfgNodeIndex+1]...)
 	}
 
-	// Append the config-ng.yaml nodes to root config node
-	rootCfgNode.Content[0].Content = append(rootCfgNode.Content[0].Content, cfgNextGenNode.Content[0].Content...)
+	// Append the config-ng.yaml nodes to root config node, except the apiVersion of config-ng.yaml
+	// which is distinct from the apiVersion of config.yaml
+	for i := 0; i+1 < len(cfgNextGenNode.Content[0].Content); i += 2 {
+		if cfgNextGenNode.Content[0].Content[i].Value == KeyAPIVersion {
+			continue
+		}
+		rootCfgNode.Content[0].Content = append(rootCfgNode.Content[0].Content, cfgNextGenNode.Content[0].Content[i:i+2]...)
+	}
 
 	// return the construct root node that contains both config.yaml with cfgItems and all of config-ng.yaml
 	return rootCfgNode, nil
@@ -87,6 +93,19 @@ func makeMultiFileCfg(cfgNode, cfgNextGenNode *yaml.Node) (*yaml.Node, error) {
 
 // persistConfig write the updated node data to config.yaml and config-ng.yaml based on cfgItems
 func persistConfig(node *yaml.Node) error {
+	return persistConfigWithAPIVersion(node, "")
+}
+
+// persistConfigWithAPIVersion write the updated node data to config.yaml and config-ng.yaml based on cfgItems
+// and sets the apiVersion of config-ng.yaml if not empty. The config is not written if config-ng.yaml has an
+// apiVersion unknown to this runtime.
+// Pre-reqs: tanzu config lock is acquired
+func persistConfigWithAPIVersion(node *yaml.Node, apiVersion string) error {
+	// Complete the interrupted commit of config.yaml and config-ng.yaml, if any, before writing
+	if err := recoverConfigCommit(); err != nil {
+		return err
+	}
+
 	// check to persist multi file or to config-ng yaml
 	useUnifiedConfig, err := UseUnifiedConfig()
 	if err != nil {
@@ -95,6 +114,14 @@ func persistConfig(node *yaml.Node) error {
 
 	// If useUnifiedConfig is set to true write to config-ng.yaml
 	if useUnifiedConfig {
+		if err := checkConfigAPIVersion(node); err != nil {
+			return err
+		}
+		// Keep the current version of the config files in the snapshot history
+		snapshotBeforeWrite()
+		if apiVersion != "" {
+			setConfigAPIVersion(node, apiVersion)
+		}
 		return persistClientConfigNextGen(node)
 	}
 
@@ -109,6 +136,12 @@ func persistConfig(node *yaml.Node) error {
 	if err != nil {
 		return err
 	}
+	if err := checkConfigAPIVersion(cfgNextGenNode); err != nil {
+		return err
+	}
+
+	// Keep the current version of the config files in the snapshot history
+	snapshotBeforeWrite()
 
 	// for each of the change node update the respective node in cfg and cfg-ng
 	for index, changeNode := range node.Content[0].Content {
@@ -141,6 +174,10 @@ func persistConfig(node *yaml.Node) error {
 		}
 	}
 
+	if apiVersion != "" {
+		setConfigAPIVersion(cfgNextGenNode, apiVersion)
+	}
+
 	// Discard nodes from config.yaml
 	for _, discardedCfgNodeKey := range DiscardedConfigNodeKeys {
 		// Discard node from config.yaml
@@ -151,14 +188,26 @@ func persistConfig(node *yaml.Node) error {
 		}
 	}
 
-	// Store the non nextGenItem config data to config.yaml
-	err = persistClientConfig(cfgNode)
+	cfgPath, err := ClientConfigPath()
+	if err != nil {
+		return errors.Wrap(err, "could not find config path")
+	}
+	cfgWrite, err := prepareNodeWrite(cfgNode, cfgPath)
+	if err != nil {
+		return err
+	}
+	cfgNextGenPath, err := ClientConfigNextGenPath()
+	if err != nil {
+		return errors.Wrap(err, "could not find config ng path")
+	}
+	cfgNextGenWrite, err := prepareNodeWrite(cfgNextGenNode, cfgNextGenPath)
 	if err != nil {
 		return err
 	}
 
-	// Store the nextGenItem config data to config-ng.yaml
-	err = persistClientConfigNextGen(cfgNextGenNode)
+	// Store the non nextGenItem config data to config.yaml and the nextGenItem config data
+	// to config-ng.yaml as a single commit
+	err = commitFiles([]fileWrite{cfgWrite, cfgNextGenWrite})
 	if err != nil {
 		return err
 	}
@@ -178,26 +227,35 @@ func persistNode(node *yaml.Node, opts ...CfgOpts) error {
 	for _, opt := range opts {
 		opt(configurations)
 	}
-	cfgPathExists, err := fileExists(configurations.CfgPath)
+	w, err := prepareNodeWrite(node, configurations.CfgPath)
 	if err != nil {
-		return errors.Wrap(err, "failed to check config path existence")
+		return err
+	}
+	err = writeFileAtomic(w.path, w.data, 0644)
+	if err != nil {
+		return errors.Wrap(err, "failed to write the config to file")
+	}
+	return nil
+}
+
+// prepareNodeWrite marshals the yaml node and makes sure the local tanzu directory exists
+func prepareNodeWrite(node *yaml.Node, cfgPath string) (fileWrite, error) {
+	cfgPathExists, err := fileExists(cfgPath)
+	if err != nil {
+		return fileWrite{}, errors.Wrap(err, "failed to check config path existence")
 	}
 	if !cfgPathExists {
 		localDir, err := LocalDir()
 		if err != nil {
-			return errors.Wrap(err, "could not find local tanzu dir for OS")
+			return fileWrite{}, errors.Wrap(err, "could not find local tanzu dir for OS")
 		}
 		if err := os.MkdirAll(localDir, 0755); err != nil {
-			return errors.Wrap(err, "could not make local tanzu directory")
+			return fileWrite{}, errors.Wrap(err, "could not make local tanzu directory")
 		}
 	}
 	data, err := yaml.Marshal(node)
 	if err != nil {
-		return errors.Wrap(err, "failed to marshal nodeutils")
+		return fileWrite{}, errors.Wrap(err, "failed to marshal nodeutils")
 	}
-	err = os.WriteFile(configurations.CfgPath, data, 0644)
-	if err != nil {
-		return errors.Wrap(err, "failed to write the config to file")
-	}
-	return nil
+	return fileWrite{path: cfgPath, data: data}, nil
 }
@@ -194,7 +194,8 @@ currentContext:
     kubernetes: test-mc
 `
 	//nolint:goconst
-	expectedCfgNextGen := `contexts:
+	expectedCfgNextGen := `apiVersion: config.tanzu.vmware.com/v1alpha3
+contexts:
     - name: test-mc
       target: kubernetes
       group: one
@@ -222,6 +223,7 @@ currentContext:
             annotation: two
             required: true
           contextType: tmc
+      contextType: kubernetes
 currentContext:
     kubernetes: test-mc
 `
@@ -445,7 +447,8 @@ func TestPersistConfig(t *testing.T) {
 func TestPersistConfigWithMigrateToNewConfig(t *testing.T) {
 	// Setup data
 	cfg, cfgNextGen, _, _ := setupCfgAndCfgNextGenData()
-	expected := `contexts:
+	expected := `apiVersion: config.tanzu.vmware.com/v1alpha3
+contexts:
     - name: test-mc
       target: kubernetes
       group: one
@@ -473,6 +476,7 @@ func TestPersistConfigWithMigrateToNewConfig(t *testing.T) {
             annotation: two
             required: true
           contextType: tmc
+      contextType: kubernetes
 currentContext:
     kubernetes: test-mc
 `
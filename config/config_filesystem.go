@@ -27,9 +27,13 @@ var (
 	TestLocalDirName = ".tanzu-test"
 )
 
-// LocalDir returns the local directory in which tanzu state is stored.
+// LocalDir returns the local directory in which tanzu state is stored, the directory of the active profile.
 func LocalDir() (path string, err error) {
-	return localDirPath(LocalDirName)
+	profile, err := ActiveProfile()
+	if err != nil {
+		return "", err
+	}
+	return profileDir(profile)
 }
 
 // localDirPath returns the full path of the directory name in which tanzu state is stored.
@@ -30,4 +30,5 @@ const (
 	KeyCLIId                   = "cliId"
 	KeySource                  = "source"
 	KeyAdditionalMetadata      = "additionalMetadata"
+	KeyLabels                  = "labels"
 )
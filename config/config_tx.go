@@ -0,0 +1,374 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+
+	"github.com/vmware-tanzu/tanzu-plugin-runtime/config/collectionutils"
+	configtypes "github.com/vmware-tanzu/tanzu-plugin-runtime/config/types"
+)
+
+// ConfigTx is an in-memory view of the client config used to apply multiple
+// mutations under a single acquisition of the tanzu config lock.
+// A ConfigTx is only valid inside the callback passed to Update.
+type ConfigTx struct {
+	node    *yaml.Node
+	persist bool
+	// secrets records the secret store mutations applied once the config is persisted
+	secrets secretStoreTx
+}
+
+// Update acquires the tanzu config lock once, invokes fn with a transaction bound to
+// the current client config and persists config.yaml and config-ng.yaml only if fn
+// returns nil and at least one mutation changed the config.
+//
+// Getters and setters of the transaction must not be used after fn returns, and fn must
+// not call the package level config APIs as the lock is already held.
+func Update(fn func(tx *ConfigTx) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultLockTimeout)
+	defer cancel()
+	return UpdateContext(ctx, fn)
+}
+
+// UpdateContext is like Update but waits for the tanzu config lock only until ctx is done
+func UpdateContext(ctx context.Context, fn func(tx *ConfigTx) error) error {
+	if fn == nil {
+		return errors.New("update function cannot be nil")
+	}
+	// Retrieve client config node
+	unlock, err := AcquireTanzuConfigLockContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock.Unlock()
+	node, err := getClientConfigNodeNoLock()
+	if err != nil {
+		return err
+	}
+
+	tx := &ConfigTx{node: node}
+	if err := fn(tx); err != nil {
+		tx.secrets.rollback()
+		return err
+	}
+	if tx.persist {
+		if err := persistConfig(tx.node); err != nil {
+			tx.secrets.rollback()
+			return err
+		}
+	}
+	tx.secrets.commit()
+	return nil
+}
+
+// markPersist records whether a mutation of the transaction needs to be persisted
+func (tx *ConfigTx) markPersist(persist bool) {
+	tx.persist = tx.persist || persist
+}
+
+// GetClientConfig returns the client config as seen by the transaction
+func (tx *ConfigTx) GetClientConfig() (*configtypes.ClientConfig, error) {
+	return convertNodeToClientConfig(tx.node)
+}
+
+// GetContext retrieves the context by name
+func (tx *ConfigTx) GetContext(name string) (*configtypes.Context, error) {
+	c, err := getContext(tx.node, name)
+	if err != nil {
+		return nil, err
+	}
+	return rehydrateContextSecrets(c)
+}
+
+// GetActiveContext retrieves the active context for the specified contextType
+func (tx *ConfigTx) GetActiveContext(contextType configtypes.ContextType) (*configtypes.Context, error) {
+	c, err := getActiveContext(tx.node, contextType)
+	if err != nil {
+		return nil, err
+	}
+	return rehydrateContextSecrets(c)
+}
+
+// SetContext add or update context and currentContext.
+// The GlobalServerAuth tokens are moved to the secret store, if one is configured.
+func (tx *ConfigTx) SetContext(c *configtypes.Context, setCurrent bool) error {
+	if c == nil {
+		return errors.New("context cannot be nil")
+	}
+	var previousRefs []string
+	if previous, err := getContext(tx.node, c.Name); err == nil {
+		previousRefs = contextSecretRefs(previous)
+	}
+
+	// Move the tokens to the secret store
+	c, err := tx.secrets.externalize(c)
+	if err != nil {
+		return err
+	}
+
+	// Add or update the context
+	persist, err := setContext(tx.node, c)
+	if err != nil {
+		return err
+	}
+	tx.markPersist(persist)
+
+	// Release the secrets replaced by the update
+	if len(previousRefs) != 0 {
+		updated, err := getContext(tx.node, c.Name)
+		if err != nil {
+			return err
+		}
+		tx.secrets.release(subtractStrings(previousRefs, contextSecretRefs(updated))...)
+	}
+
+	// Set current context
+	if setCurrent {
+		persist, err = setCurrentContext(tx.node, c.Name, c.ContextType)
+		if err != nil {
+			return err
+		}
+		tx.markPersist(persist)
+	}
+
+	// Back-fill servers based on contexts
+	s := convertContextToServer(c)
+
+	// Add or update server
+	persist, err = setServer(tx.node, s)
+	if err != nil {
+		return err
+	}
+	tx.markPersist(persist)
+
+	// Set current server
+	if setCurrent && s.Type == configtypes.ManagementClusterServerType { //nolint:staticcheck
+		persist, err = setCurrentServer(tx.node, s.Name)
+		if err != nil {
+			return err
+		}
+		tx.markPersist(persist)
+	}
+	return nil
+}
+
+// DeleteContext delete a context by name
+func (tx *ConfigTx) DeleteContext(name string) error {
+	ctx, err := getContext(tx.node, name)
+	if err != nil {
+		return err
+	}
+	tx.secrets.release(contextSecretRefs(ctx)...)
+	err = removeCurrentContext(tx.node, ctx.Name, ctx.ContextType)
+	if err != nil {
+		return err
+	}
+	err = removeContext(tx.node, name)
+	if err != nil {
+		return err
+	}
+	err = removeServer(tx.node, name)
+	if err != nil {
+		return err
+	}
+	err = removeCurrentServer(tx.node, name)
+	if err != nil {
+		return err
+	}
+	tx.markPersist(true)
+	return nil
+}
+
+// SetActiveContext sets the active context to the specified name if context is present
+func (tx *ConfigTx) SetActiveContext(name string) error {
+	ctx, err := getContext(tx.node, name)
+	if err != nil {
+		return err
+	}
+	persist, err := setCurrentContext(tx.node, ctx.Name, ctx.ContextType)
+	if err != nil {
+		return err
+	}
+	tx.markPersist(persist)
+	if ctx.ContextType == configtypes.ContextTypeK8s {
+		persist, err = setCurrentServer(tx.node, name)
+		if err != nil {
+			return err
+		}
+		tx.markPersist(persist)
+	}
+	return nil
+}
+
+// RemoveActiveContext removed the current context of specified context type
+func (tx *ConfigTx) RemoveActiveContext(contextType configtypes.ContextType) error {
+	c, err := getActiveContext(tx.node, contextType)
+	if err != nil {
+		return err
+	}
+	err = removeCurrentContext(tx.node, "", contextType)
+	if err != nil {
+		return err
+	}
+	err = removeCurrentServer(tx.node, c.Name)
+	if err != nil {
+		return err
+	}
+	tx.markPersist(true)
+	return nil
+}
+
+// GetAllEnvs retrieves all env values from config
+func (tx *ConfigTx) GetAllEnvs() (map[string]string, error) {
+	return getAllEnvs(tx.node)
+}
+
+// GetEnv retrieves env value by key
+func (tx *ConfigTx) GetEnv(key string) (string, error) {
+	return getEnv(tx.node, key)
+}
+
+// SetEnv add or update a env key and value
+func (tx *ConfigTx) SetEnv(key, value string) error {
+	persist, err := setEnv(tx.node, key, value)
+	if err != nil {
+		return err
+	}
+	tx.markPersist(persist)
+	return nil
+}
+
+// DeleteEnv delete the env entry of specified key
+func (tx *ConfigTx) DeleteEnv(key string) error {
+	err := deleteEnv(tx.node, key)
+	if err != nil {
+		return err
+	}
+	tx.markPersist(true)
+	return nil
+}
+
+// IsFeatureEnabled checks and returns whether specific plugin and key is true
+func (tx *ConfigTx) IsFeatureEnabled(plugin, key string) (bool, error) {
+	val, err := getFeature(tx.node, plugin, key)
+	if err != nil {
+		return false, err
+	}
+	return strings.EqualFold(val, "true"), nil
+}
+
+// SetFeature add or update plugin key value
+func (tx *ConfigTx) SetFeature(plugin, key, value string) error {
+	persist, err := setFeature(tx.node, plugin, key, value)
+	if err != nil {
+		return err
+	}
+	tx.markPersist(persist)
+	return nil
+}
+
+// DeleteFeature deletes the specified plugin key
+func (tx *ConfigTx) DeleteFeature(plugin, key string) error {
+	err := deleteFeature(tx.node, plugin, key)
+	if err != nil {
+		return err
+	}
+	tx.markPersist(true)
+	return nil
+}
+
+// GetCerts retrieves all the certs
+func (tx *ConfigTx) GetCerts() ([]*configtypes.Cert, error) {
+	return getCerts(tx.node)
+}
+
+// GetCert retrieves the cert configuration by host
+func (tx *ConfigTx) GetCert(host string) (*configtypes.Cert, error) {
+	if host == "" {
+		return nil, errors.New("host is empty")
+	}
+	return lookupCert(tx.node, host)
+}
+
+// SetCert add or update cert configuration
+func (tx *ConfigTx) SetCert(c *configtypes.Cert) error {
+	if c == nil {
+		return nil
+	}
+	if err := validateCertHost(c.Host); err != nil {
+		return err
+	}
+	if err := validateCACertData(c.CACertData); err != nil {
+		return err
+	}
+	persist, err := setCert(tx.node, c)
+	if err != nil {
+		return err
+	}
+	tx.markPersist(persist)
+	return nil
+}
+
+// DeleteCert delete a cert configuration by host
+func (tx *ConfigTx) DeleteCert(host string) error {
+	if host == "" {
+		return errors.New("host is empty")
+	}
+	_, err := getCert(tx.node, host)
+	if err != nil {
+		return err
+	}
+	err = removeCert(tx.node, host)
+	if err != nil {
+		return err
+	}
+	tx.markPersist(true)
+	return nil
+}
+
+// GetCLIDiscoverySources retrieves cli discovery sources
+func (tx *ConfigTx) GetCLIDiscoverySources() ([]configtypes.PluginDiscovery, error) {
+	return getCLIDiscoverySources(tx.node)
+}
+
+// GetCLIDiscoverySource retrieves cli discovery source by name
+func (tx *ConfigTx) GetCLIDiscoverySource(name string) (*configtypes.PluginDiscovery, error) {
+	return getCLIDiscoverySource(tx.node, name)
+}
+
+// SetCLIDiscoverySource add or update a cli discoverySource
+func (tx *ConfigTx) SetCLIDiscoverySource(discoverySource configtypes.PluginDiscovery) error {
+	persist, err := setCLIDiscoverySource(tx.node, discoverySource)
+	if err != nil {
+		return err
+	}
+	tx.markPersist(persist)
+	return nil
+}
+
+// DeleteCLIDiscoverySource delete cli discoverySource by name
+func (tx *ConfigTx) DeleteCLIDiscoverySource(name string) error {
+	err := deleteCLIDiscoverySource(tx.node, name)
+	if err != nil {
+		return err
+	}
+	tx.markPersist(true)
+	return nil
+}
+
+// subtractStrings returns the values of a that are not in b
+func subtractStrings(a, b []string) []string {
+	var result []string
+	for _, v := range a {
+		if !collectionutils.Contains(b, v) {
+			result = append(result, v)
+		}
+	}
+	return result
+}
@@ -0,0 +1,160 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"os"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	configtypes "github.com/vmware-tanzu/tanzu-plugin-runtime/config/types"
+)
+
+func TestUpdate(t *testing.T) {
+	// Setup config data
+	files, cleanUp := setupTestConfig(t, &CfgTestData{})
+
+	defer func() {
+		cleanUp()
+	}()
+
+	ctx := &configtypes.Context{
+		Name:        "test-mc",
+		ContextType: configtypes.ContextTypeK8s,
+		ClusterOpts: &configtypes.ClusterServer{
+			Endpoint:            "test-endpoint",
+			Path:                "test-path",
+			Context:             "test-context",
+			IsManagementCluster: true,
+		},
+	}
+	cert := &configtypes.Cert{
+		Host:           "test-endpoint",
+		SkipCertVerify: "true",
+	}
+
+	err := Update(func(tx *ConfigTx) error {
+		if err := tx.SetContext(ctx, true); err != nil {
+			return err
+		}
+		if err := tx.SetCert(cert); err != nil {
+			return err
+		}
+		if err := tx.SetEnv("env1", "val1"); err != nil {
+			return err
+		}
+		if err := tx.SetFeature("global", "feature1", "true"); err != nil {
+			return err
+		}
+
+		// Changes are visible within the transaction
+		c, err := tx.GetActiveContext(configtypes.ContextTypeK8s)
+		assert.NoError(t, err)
+		assert.Equal(t, "test-mc", c.Name)
+		enabled, err := tx.IsFeatureEnabled("global", "feature1")
+		assert.NoError(t, err)
+		assert.True(t, enabled)
+		return nil
+	})
+	assert.NoError(t, err)
+
+	c, err := GetActiveContext(configtypes.ContextTypeK8s)
+	assert.NoError(t, err)
+	assert.Equal(t, ctx, c)
+
+	crt, err := GetCert("test-endpoint")
+	assert.NoError(t, err)
+	assert.Equal(t, cert, crt)
+
+	val, err := GetEnv("env1")
+	assert.NoError(t, err)
+	assert.Equal(t, "val1", val)
+
+	enabled, err := IsFeatureEnabled("global", "feature1")
+	assert.NoError(t, err)
+	assert.True(t, enabled)
+
+	// A failing callback does not persist any change
+	cfgBefore, err := os.ReadFile(files[0].Name())
+	assert.NoError(t, err)
+	cfgNextGenBefore, err := os.ReadFile(files[1].Name())
+	assert.NoError(t, err)
+
+	err = Update(func(tx *ConfigTx) error {
+		if err := tx.SetEnv("env2", "val2"); err != nil {
+			return err
+		}
+		if err := tx.DeleteContext("test-mc"); err != nil {
+			return err
+		}
+		return errors.New("failed to apply changes")
+	})
+	assert.Equal(t, "failed to apply changes", err.Error())
+
+	cfgAfter, err := os.ReadFile(files[0].Name())
+	assert.NoError(t, err)
+	cfgNextGenAfter, err := os.ReadFile(files[1].Name())
+	assert.NoError(t, err)
+	assert.Equal(t, string(cfgBefore), string(cfgAfter))
+	assert.Equal(t, string(cfgNextGenBefore), string(cfgNextGenAfter))
+
+	_, err = GetEnv("env2")
+	assert.Equal(t, "not found", err.Error())
+	ok, err := ContextExists("test-mc")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	// Errors from setters are returned as is
+	err = Update(func(tx *ConfigTx) error {
+		return tx.SetEnv("", "val")
+	})
+	assert.Equal(t, "key cannot be empty", err.Error())
+
+	err = Update(nil)
+	assert.Equal(t, "update function cannot be nil", err.Error())
+}
+
+func TestUpdateDeleteContextAndEnv(t *testing.T) {
+	// Setup config data
+	_, cleanUp := setupTestConfig(t, &CfgTestData{})
+
+	defer func() {
+		cleanUp()
+	}()
+
+	ctx := &configtypes.Context{
+		Name:        "test-tmc",
+		ContextType: configtypes.ContextTypeTMC,
+		GlobalOpts: &configtypes.GlobalServer{
+			Endpoint: "test-endpoint",
+		},
+	}
+	err := AddContext(ctx, true)
+	assert.NoError(t, err)
+	err = SetEnv("env1", "val1")
+	assert.NoError(t, err)
+
+	err = Update(func(tx *ConfigTx) error {
+		if err := tx.RemoveActiveContext(configtypes.ContextTypeTMC); err != nil {
+			return err
+		}
+		if err := tx.DeleteContext("test-tmc"); err != nil {
+			return err
+		}
+		return tx.DeleteEnv("env1")
+	})
+	assert.NoError(t, err)
+
+	ok, err := ContextExists("test-tmc")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	_, err = GetActiveContext(configtypes.ContextTypeTMC)
+	assert.Equal(t, "no current context set for type \"mission-control\"", err.Error())
+
+	_, err = GetEnv("env1")
+	assert.Equal(t, "not found", err.Error())
+}
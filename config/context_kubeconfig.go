@@ -0,0 +1,115 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/vmware-tanzu/tanzu-plugin-runtime/config/kubeconfig"
+	configtypes "github.com/vmware-tanzu/tanzu-plugin-runtime/config/types"
+)
+
+const (
+	// execCredentialAPIVersion is the client authentication API version of the exec credential plugin entries
+	execCredentialAPIVersion = "client.authentication.k8s.io/v1"
+
+	// defaultCLICommand is the command invoked by the exec credential plugin entries when TANZU_BIN is not set
+	defaultCLICommand = "tanzu"
+)
+
+// getKubeconfigForKubernetesContext returns the kubeconfig of the kubernetes context minified to the
+// kubeconfig context of the Tanzu context, with the referenced certificate and token files inlined
+func getKubeconfigForKubernetesContext(ctx *configtypes.Context) (*kubeconfig.Config, error) {
+	if ctx.ClusterOpts == nil || ctx.ClusterOpts.Path == "" {
+		return nil, errors.Errorf("context %s has no kubeconfig path", ctx.Name)
+	}
+	kc, err := kubeconfig.ReadKubeConfig(ctx.ClusterOpts.Path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read the kubernetes context kubeconfig")
+	}
+	kc, err = kubeconfig.MinifyKubeConfig(kc, ctx.ClusterOpts.Context)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to minify the kubeconfig")
+	}
+	if err := kubeconfig.Flatten(kc, filepath.Dir(ctx.ClusterOpts.Path)); err != nil {
+		return nil, errors.Wrap(err, "failed to flatten the kubeconfig")
+	}
+	return kc, nil
+}
+
+// getKubeconfigForMissionControlContext returns a kubeconfig to the endpoint of the mission-control context.
+// The user of the kubeconfig authenticates with an exec credential plugin entry calling back into the
+// CLI, referenced by TANZU_BIN or found in PATH, to get a token from the global server auth of the context.
+func getKubeconfigForMissionControlContext(ctx *configtypes.Context) (*kubeconfig.Config, error) {
+	if ctx.GlobalOpts == nil || ctx.GlobalOpts.Endpoint == "" {
+		return nil, errors.Errorf("context %s has no endpoint", ctx.Name)
+	}
+	name := "tanzu-cli-" + ctx.Name
+	userName := name + "-user"
+
+	cluster := &kubeconfig.Cluster{Name: name}
+	cluster.Cluster.Server = missionControlServerURL(ctx.GlobalOpts.Endpoint)
+	if certs, err := GetCerts(); err == nil {
+		if match, err := resolveCert(certs, cluster.Cluster.Server); err == nil {
+			// The CA certificates are stored either base64 encoded or as is, the kubeconfig expects them base64 encoded
+			if match.Cert.CACertData != "" {
+				cluster.Cluster.CertificateAuthorityData = base64.StdEncoding.EncodeToString(decodeCACertData(match.Cert.CACertData))
+			}
+			// Like the TLS config of the host, an insecure host is not verified
+			skipVerify, _ := strconv.ParseBool(match.Cert.SkipCertVerify)
+			insecure, _ := strconv.ParseBool(match.Cert.Insecure)
+			cluster.Cluster.InsecureSkipTLSVerify = skipVerify || insecure
+		}
+	}
+
+	kubeContext := &kubeconfig.Context{Name: name}
+	kubeContext.Context.Cluster = name
+	kubeContext.Context.AuthInfo = userName
+
+	return &kubeconfig.Config{
+		Kind:           "Config",
+		APIVersion:     "v1",
+		CurrentContext: name,
+		Clusters:       []*kubeconfig.Cluster{cluster},
+		Contexts:       []*kubeconfig.Context{kubeContext},
+		AuthInfos: []*kubeconfig.AuthInfo{
+			{
+				Name:     userName,
+				AuthInfo: getTokenExecAuthInfo(ctx.Name),
+			},
+		},
+	}, nil
+}
+
+// getTokenExecAuthInfo returns the kubeconfig user running "tanzu context get-token <contextName>" to get a token
+func getTokenExecAuthInfo(contextName string) map[string]interface{} {
+	command := os.Getenv("TANZU_BIN")
+	if command == "" {
+		command = defaultCLICommand
+	}
+	return map[string]interface{}{
+		"exec": map[string]interface{}{
+			"apiVersion":         execCredentialAPIVersion,
+			"command":            command,
+			"args":               []string{"context", "get-token", contextName},
+			"env":                []interface{}{},
+			"interactiveMode":    "Never",
+			"provideClusterInfo": false,
+		},
+	}
+}
+
+// missionControlServerURL returns the URL of the mission-control endpoint which may not have a scheme
+func missionControlServerURL(endpoint string) string {
+	if strings.Contains(endpoint, "://") {
+		return endpoint
+	}
+	return "https://" + endpoint
+}
@@ -21,7 +21,11 @@ func GetContext(name string) (*configtypes.Context, error) {
 	if err != nil {
 		return nil, err
 	}
-	return getContext(node, name)
+	c, err := getContext(node, name)
+	if err != nil {
+		return nil, err
+	}
+	return rehydrateContextSecrets(c)
 }
 
 // AddContext add or update context and currentContext
@@ -30,70 +34,10 @@ func AddContext(c *configtypes.Context, setCurrent bool) error {
 }
 
 // SetContext add or update context and currentContext
-//
-//nolint:gocyclo
 func SetContext(c *configtypes.Context, setCurrent bool) error {
-	// Retrieve client config node
-	AcquireTanzuConfigLock()
-	defer ReleaseTanzuConfigLock()
-	node, err := getClientConfigNodeNoLock()
-	if err != nil {
-		return err
-	}
-	// Add or update the context
-	persist, err := setContext(node, c)
-	if err != nil {
-		return err
-	}
-	if persist {
-		err = persistConfig(node)
-		if err != nil {
-			return err
-		}
-	}
-	// Set current context
-	if setCurrent {
-		persist, err = setCurrentContext(node, c.Name, c.ContextType)
-		if err != nil {
-			return err
-		}
-		if persist {
-			err = persistConfig(node)
-			if err != nil {
-				return err
-			}
-		}
-	}
-
-	// Back-fill servers based on contexts
-	s := convertContextToServer(c)
-
-	// Add or update server
-	persist, err = setServer(node, s)
-	if err != nil {
-		return err
-	}
-	if persist {
-		err = persistConfig(node)
-		if err != nil {
-			return err
-		}
-	}
-
-	// Set current server
-	if setCurrent && s.Type == configtypes.ManagementClusterServerType { //nolint:staticcheck
-		persist, err = setCurrentServer(node, s.Name)
-		if err != nil {
-			return err
-		}
-		if persist {
-			err = persistConfig(node)
-			if err != nil {
-				return err
-			}
-		}
-	}
-	return err
+	return Update(func(tx *ConfigTx) error {
+		return tx.SetContext(c, setCurrent)
+	})
 }
 
 // DeleteContext delete a context by name
@@ -103,34 +47,9 @@ func DeleteContext(name string) error {
 
 // RemoveContext delete a context by name
 func RemoveContext(name string) error {
-	// Retrieve client config node
-	AcquireTanzuConfigLock()
-	defer ReleaseTanzuConfigLock()
-	node, err := getClientConfigNodeNoLock()
-	if err != nil {
-		return err
-	}
-	ctx, err := getContext(node, name)
-	if err != nil {
-		return err
-	}
-	err = removeCurrentContext(node, ctx.Name, ctx.ContextType)
-	if err != nil {
-		return err
-	}
-	err = removeContext(node, name)
-	if err != nil {
-		return err
-	}
-	err = removeServer(node, name)
-	if err != nil {
-		return err
-	}
-	err = removeCurrentServer(node, name)
-	if err != nil {
-		return err
-	}
-	return persistConfig(node)
+	return Update(func(tx *ConfigTx) error {
+		return tx.DeleteContext(name)
+	})
 }
 
 // ContextExists checks if context by name already exists
@@ -147,7 +66,7 @@ func validateContext(c *configtypes.Context) error {
 	if c.Target != "" && c.ContextType != "" && c.ContextType != configtypes.ConvertTargetToContextType(c.Target) {
 		return errors.Errorf("specified Target(%s) and ContextType(%s) for the Context object does not match", c.Target, c.ContextType)
 	}
-	return nil
+	return validateLabels(c.Labels)
 }
 
 // GetCurrentContext retrieves the current context for the specified target
@@ -164,7 +83,11 @@ func GetActiveContext(contextType configtypes.ContextType) (c *configtypes.Conte
 	if err != nil {
 		return nil, err
 	}
-	return getActiveContext(node, contextType)
+	c, err = getActiveContext(node, contextType)
+	if err != nil {
+		return nil, err
+	}
+	return rehydrateContextSecrets(c)
 }
 
 // GetContextsByType retrieves the contexts of a provided context type
@@ -237,41 +160,9 @@ func SetCurrentContext(name string) error {
 
 // SetActiveContext sets the active context to the specified name if context is present
 func SetActiveContext(name string) error {
-	// Retrieve client config node
-	AcquireTanzuConfigLock()
-	defer ReleaseTanzuConfigLock()
-	node, err := getClientConfigNodeNoLock()
-	if err != nil {
-		return err
-	}
-
-	ctx, err := getContext(node, name)
-	if err != nil {
-		return err
-	}
-	persist, err := setCurrentContext(node, ctx.Name, ctx.ContextType)
-	if err != nil {
-		return err
-	}
-	if persist {
-		err = persistConfig(node)
-		if err != nil {
-			return err
-		}
-	}
-	if ctx.ContextType == configtypes.ContextTypeK8s {
-		persist, err = setCurrentServer(node, name)
-		if err != nil {
-			return err
-		}
-		if persist {
-			err = persistConfig(node)
-			if err != nil {
-				return err
-			}
-		}
-	}
-	return err
+	return Update(func(tx *ConfigTx) error {
+		return tx.SetActiveContext(name)
+	})
 }
 
 // RemoveCurrentContext removed the current context of specified context type
@@ -283,26 +174,9 @@ func RemoveCurrentContext(target configtypes.Target) error {
 
 // RemoveActiveContext removed the current context of specified context type
 func RemoveActiveContext(contextType configtypes.ContextType) error {
-	// Retrieve client config node
-	AcquireTanzuConfigLock()
-	defer ReleaseTanzuConfigLock()
-	node, err := getClientConfigNodeNoLock()
-	if err != nil {
-		return err
-	}
-	c, err := getActiveContext(node, contextType)
-	if err != nil {
-		return err
-	}
-	err = removeCurrentContext(node, "", contextType)
-	if err != nil {
-		return err
-	}
-	err = removeCurrentServer(node, c.Name)
-	if err != nil {
-		return err
-	}
-	return persistConfig(node)
+	return Update(func(tx *ConfigTx) error {
+		return tx.RemoveActiveContext(contextType)
+	})
 }
 
 // EndpointFromContext retrieved the endpoint from the specified context
@@ -0,0 +1,259 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"text/template"
+
+	"github.com/pkg/errors"
+
+	"github.com/vmware-tanzu/tanzu-plugin-runtime/config/kubeconfig"
+	configtypes "github.com/vmware-tanzu/tanzu-plugin-runtime/config/types"
+)
+
+// DefaultImportNameTemplate is the default template of the names of the contexts imported from a kubeconfig
+const DefaultImportNameTemplate = "{{.KubeContext}}"
+
+// ImportAction is what ImportContextsFromKubeconfig did for a kube context
+type ImportAction string
+
+const (
+	// ImportActionCreated means a new context was created for the kube context
+	ImportActionCreated ImportAction = "created"
+	// ImportActionUpdated means the existing context was updated to point to the kube context
+	ImportActionUpdated ImportAction = "updated"
+	// ImportActionSkipped means the kube context was not imported, see the reason
+	ImportActionSkipped ImportAction = "skipped"
+)
+
+// ImportNameTemplateData is the data available to the template of the names of the imported contexts
+type ImportNameTemplateData struct {
+	// KubeContext is the name of the kube context
+	KubeContext string
+	// Cluster is the name of the cluster of the kube context
+	Cluster string
+	// User is the name of the user of the kube context
+	User string
+	// Namespace is the namespace of the kube context, if any
+	Namespace string
+}
+
+// ImportedContext reports what was done for a kube context of the kubeconfig
+type ImportedContext struct {
+	// Name is the name of the Tanzu context
+	Name string `json:"name" yaml:"name"`
+	// KubeContext is the name of the kube context
+	KubeContext string `json:"kubeContext" yaml:"kubeContext"`
+	// Action is what was done for the kube context
+	Action ImportAction `json:"action" yaml:"action"`
+	// Reason explains why the kube context was skipped
+	Reason string `json:"reason,omitempty" yaml:"reason,omitempty"`
+}
+
+// ImportResult reports what ImportContextsFromKubeconfig did for each kube context, in the order of the kubeconfig
+type ImportResult struct {
+	// Path is the path of the imported kubeconfig
+	Path string `json:"path" yaml:"path"`
+	// Contexts are the outcomes of the kube contexts
+	Contexts []ImportedContext `json:"contexts" yaml:"contexts"`
+}
+
+// Filter returns the outcomes of the kube contexts for which the action was performed
+func (r *ImportResult) Filter(action ImportAction) []ImportedContext {
+	var contexts []ImportedContext
+	for _, c := range r.Contexts {
+		if c.Action == action {
+			contexts = append(contexts, c)
+		}
+	}
+	return contexts
+}
+
+type importOptions struct {
+	nameTemplate   string
+	updateExisting bool
+	kubeContexts   []string
+}
+
+type ImportOptions func(o *importOptions)
+
+// WithImportNameTemplate sets the text/template, evaluated with ImportNameTemplateData, of the names of
+// the imported contexts. e.g. "{{.Cluster}}-{{.Namespace}}". Defaults to DefaultImportNameTemplate.
+func WithImportNameTemplate(nameTemplate string) ImportOptions {
+	return func(o *importOptions) {
+		o.nameTemplate = nameTemplate
+	}
+}
+
+// WithImportUpdateExisting updates the existing kubernetes contexts with the same name to point to
+// the kubeconfig instead of skipping them
+func WithImportUpdateExisting() ImportOptions {
+	return func(o *importOptions) {
+		o.updateExisting = true
+	}
+}
+
+// WithImportKubeContexts restricts the import to the kube contexts
+func WithImportKubeContexts(kubeContexts ...string) ImportOptions {
+	return func(o *importOptions) {
+		o.kubeContexts = kubeContexts
+	}
+}
+
+// ImportContextsFromKubeconfig creates a context of type kubernetes for each kube context of the kubeconfig
+// at path, the default kubeconfig if empty. The names of the contexts are generated from the name template.
+// Existing contexts with the same name are skipped unless WithImportUpdateExisting is specified, in which
+// case existing kubernetes contexts are updated. Contexts of other types are never updated.
+// The current contexts are not changed. All the contexts are imported in a single config update.
+func ImportContextsFromKubeconfig(path string, opts ...ImportOptions) (*ImportResult, error) {
+	options := &importOptions{nameTemplate: DefaultImportNameTemplate}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	if path == "" {
+		defaultPath, err := kubeconfig.DefaultKubeConfigPath()
+		if err != nil {
+			return nil, err
+		}
+		path = defaultPath
+	}
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to resolve the kubeconfig path %s", path)
+	}
+	kc, err := kubeconfig.ReadKubeConfig(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read the kubeconfig %s", path)
+	}
+	tmpl, err := template.New("name").Option("missingkey=error").Parse(options.nameTemplate)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid context name template %q", options.nameTemplate)
+	}
+	kubeContexts, err := selectKubeContexts(kc, options.kubeContexts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Generate all the names first so that an invalid template does not result in a partial import
+	names := make([]string, len(kubeContexts))
+	for i, kubeContext := range kubeContexts {
+		if names[i], err = importContextName(tmpl, kubeContext); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &ImportResult{Path: path}
+	err = Update(func(tx *ConfigTx) error {
+		result.Contexts = nil
+		seen := map[string]string{}
+		for i, kubeContext := range kubeContexts {
+			imported, err := importKubeContext(tx, kc, kubeContext, names[i], path, seen, options)
+			if err != nil {
+				return err
+			}
+			result.Contexts = append(result.Contexts, imported)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// selectKubeContexts returns the kube contexts of the kubeconfig restricted to the names if any
+func selectKubeContexts(kc *kubeconfig.Config, names []string) ([]*kubeconfig.Context, error) {
+	if len(names) == 0 {
+		return kc.Contexts, nil
+	}
+	var kubeContexts []*kubeconfig.Context
+	for _, name := range names {
+		kubeContext := kubeconfig.GetContext(kc, name)
+		if kubeContext == nil {
+			return nil, errors.Errorf("kube context %s not found in the kubeconfig", name)
+		}
+		kubeContexts = append(kubeContexts, kubeContext)
+	}
+	return kubeContexts, nil
+}
+
+func importContextName(tmpl *template.Template, kubeContext *kubeconfig.Context) (string, error) {
+	var buf bytes.Buffer
+	data := ImportNameTemplateData{
+		KubeContext: kubeContext.Name,
+		Cluster:     kubeContext.Context.Cluster,
+		User:        kubeContext.Context.AuthInfo,
+		Namespace:   kubeContext.Context.Namespace,
+	}
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", errors.Wrapf(err, "failed to generate the context name of kube context %s", kubeContext.Name)
+	}
+	if buf.Len() == 0 {
+		return "", errors.Errorf("the context name generated for kube context %s is empty", kubeContext.Name)
+	}
+	return buf.String(), nil
+}
+
+// importKubeContext creates or updates the context for the kube context. seen maps the names of the
+// contexts already imported to their kube context to detect the names generated more than once.
+func importKubeContext(tx *ConfigTx, kc *kubeconfig.Config, kubeContext *kubeconfig.Context, name, path string, seen map[string]string, options *importOptions) (ImportedContext, error) {
+	imported := ImportedContext{Name: name, KubeContext: kubeContext.Name}
+	skip := func(format string, args ...interface{}) (ImportedContext, error) {
+		imported.Action = ImportActionSkipped
+		imported.Reason = fmt.Sprintf(format, args...)
+		return imported, nil
+	}
+
+	if other, ok := seen[name]; ok {
+		return skip("context name %s is also generated for kube context %s", name, other)
+	}
+	seen[name] = kubeContext.Name
+
+	cluster := kubeconfig.GetCluster(kc, kubeContext.Context.Cluster)
+	if cluster == nil {
+		return skip("cluster %s of the kube context not found", kubeContext.Context.Cluster)
+	}
+
+	// Only a missing context is created, a context that cannot be read is reported
+	var c *configtypes.Context
+	if findContextNode(tx.node, name) != nil {
+		var err error
+		if c, err = tx.GetContext(name); err != nil {
+			return imported, errors.Wrapf(err, "failed to import kube context %s", kubeContext.Name)
+		}
+	}
+	switch {
+	case c == nil:
+		c = &configtypes.Context{
+			Name:        name,
+			ContextType: configtypes.ContextTypeK8s,
+			ClusterOpts: &configtypes.ClusterServer{},
+		}
+		imported.Action = ImportActionCreated
+	case !options.updateExisting:
+		return skip("context %s already exists", name)
+	case c.ContextType != configtypes.ContextTypeK8s:
+		return skip("context %s already exists with type %s", name, c.ContextType)
+	default:
+		if c.ClusterOpts == nil {
+			c.ClusterOpts = &configtypes.ClusterServer{}
+		}
+		if c.ClusterOpts.Path == path && c.ClusterOpts.Context == kubeContext.Name && c.ClusterOpts.Endpoint == cluster.Cluster.Server {
+			return skip("context %s is up to date", name)
+		}
+		imported.Action = ImportActionUpdated
+	}
+
+	c.ClusterOpts.Path = path
+	c.ClusterOpts.Context = kubeContext.Name
+	c.ClusterOpts.Endpoint = cluster.Cluster.Server
+	if err := tx.SetContext(c, false); err != nil {
+		return imported, errors.Wrapf(err, "failed to import kube context %s", kubeContext.Name)
+	}
+	return imported, nil
+}
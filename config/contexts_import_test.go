@@ -0,0 +1,133 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	configtypes "github.com/vmware-tanzu/tanzu-plugin-runtime/config/types"
+)
+
+func TestImportContextsFromKubeconfig(t *testing.T) {
+	err := setupForGetContext()
+	assert.NoError(t, err)
+	defer cleanupTestingDir(t)
+
+	kubeconfigFile, err := filepath.Abs("../fakes/config/kubeconfig-1.yaml")
+	assert.NoError(t, err)
+
+	result, err := ImportContextsFromKubeconfig(kubeconfigFile, WithImportNameTemplate("imported-{{.KubeContext}}"))
+	assert.NoError(t, err)
+	assert.Equal(t, kubeconfigFile, result.Path)
+	assert.Equal(t, []ImportedContext{
+		{Name: "imported-bar-context", KubeContext: "bar-context", Action: ImportActionCreated},
+		{Name: "imported-foo-context", KubeContext: "foo-context", Action: ImportActionCreated},
+		{Name: "imported-tanzu-cli-mytanzu", KubeContext: "tanzu-cli-mytanzu", Action: ImportActionCreated},
+	}, result.Contexts)
+
+	c, err := GetContext("imported-foo-context")
+	assert.NoError(t, err)
+	assert.Equal(t, configtypes.ContextTypeK8s, c.ContextType)
+	assert.Equal(t, &configtypes.ClusterServer{
+		Path:     kubeconfigFile,
+		Context:  "foo-context",
+		Endpoint: "https://foo.org:443",
+	}, c.ClusterOpts)
+
+	// The current contexts are not changed
+	current, err := GetActiveContext(configtypes.ContextTypeK8s)
+	assert.NoError(t, err)
+	assert.Equal(t, "test-mc-2", current.Name)
+
+	// Existing contexts are skipped by default
+	result, err = ImportContextsFromKubeconfig(kubeconfigFile, WithImportNameTemplate("imported-{{.KubeContext}}"), WithImportKubeContexts("foo-context"))
+	assert.NoError(t, err)
+	assert.Equal(t, []ImportedContext{
+		{Name: "imported-foo-context", KubeContext: "foo-context", Action: ImportActionSkipped, Reason: "context imported-foo-context already exists"},
+	}, result.Contexts)
+}
+
+func TestImportContextsFromKubeconfigUpdateExisting(t *testing.T) {
+	err := setupForGetContext()
+	assert.NoError(t, err)
+	defer cleanupTestingDir(t)
+
+	kubeconfigFile, err := filepath.Abs("../fakes/config/kubeconfig-1.yaml")
+	assert.NoError(t, err)
+
+	// test-mc is an existing kubernetes context and test-tanzu an existing Tanzu context
+	result, err := ImportContextsFromKubeconfig(kubeconfigFile, WithImportUpdateExisting(),
+		WithImportNameTemplate(`{{if eq .KubeContext "foo-context"}}test-mc{{else if eq .KubeContext "bar-context"}}test-tanzu{{else}}{{.Cluster}}{{end}}`))
+	assert.NoError(t, err)
+	assert.Equal(t, []ImportedContext{
+		{Name: "test-tanzu", KubeContext: "bar-context", Action: ImportActionSkipped, Reason: "context test-tanzu already exists with type tanzu"},
+		{Name: "test-mc", KubeContext: "foo-context", Action: ImportActionUpdated},
+		{Name: "tanzu-cli-mytanzu/current", KubeContext: "tanzu-cli-mytanzu", Action: ImportActionCreated},
+	}, result.Contexts)
+	assert.Len(t, result.Filter(ImportActionSkipped), 1)
+
+	c, err := GetContext("test-mc")
+	assert.NoError(t, err)
+	assert.Equal(t, kubeconfigFile, c.ClusterOpts.Path)
+	assert.Equal(t, "foo-context", c.ClusterOpts.Context)
+	assert.Equal(t, "https://foo.org:443", c.ClusterOpts.Endpoint)
+
+	// Importing again reports the context as up to date
+	result, err = ImportContextsFromKubeconfig(kubeconfigFile, WithImportUpdateExisting(), WithImportKubeContexts("foo-context"),
+		WithImportNameTemplate("test-mc"))
+	assert.NoError(t, err)
+	assert.Equal(t, ImportActionSkipped, result.Contexts[0].Action)
+	assert.Equal(t, "context test-mc is up to date", result.Contexts[0].Reason)
+
+	// A name generated for several kube contexts is only imported once
+	result, err = ImportContextsFromKubeconfig(kubeconfigFile, WithImportNameTemplate("same-name"))
+	assert.NoError(t, err)
+	assert.Equal(t, ImportActionCreated, result.Contexts[0].Action)
+	assert.Equal(t, "context name same-name is also generated for kube context bar-context", result.Contexts[1].Reason)
+}
+
+func TestImportContextsFromKubeconfigErrors(t *testing.T) {
+	err := setupForGetContext()
+	assert.NoError(t, err)
+	defer cleanupTestingDir(t)
+
+	kubeconfigFile := "../fakes/config/kubeconfig-1.yaml"
+
+	_, err = ImportContextsFromKubeconfig(kubeconfigFile, WithImportNameTemplate("{{.Unknown}}"))
+	assert.ErrorContains(t, err, "failed to generate the context name of kube context bar-context")
+
+	_, err = ImportContextsFromKubeconfig(kubeconfigFile, WithImportNameTemplate("{{.Namespace}}"))
+	assert.ErrorContains(t, err, "the context name generated for kube context tanzu-cli-mytanzu is empty")
+
+	_, err = ImportContextsFromKubeconfig(kubeconfigFile, WithImportKubeContexts("missing"))
+	assert.ErrorContains(t, err, "kube context missing not found in the kubeconfig")
+
+	_, err = ImportContextsFromKubeconfig("does-not-exist.yaml")
+	assert.ErrorContains(t, err, "failed to read the kubeconfig")
+
+	// A context that cannot be read is not replaced
+	err = Update(func(tx *ConfigTx) error {
+		persist, err := setContext(tx.node, &configtypes.Context{
+			Name:        "test-unreadable",
+			ContextType: configtypes.ContextTypeK8s,
+			GlobalOpts:  &configtypes.GlobalServer{Auth: configtypes.GlobalServerAuth{AccessToken: SecretRefPrefix + "missing"}},
+		})
+		tx.markPersist(persist)
+		return err
+	})
+	assert.NoError(t, err)
+	_, err = ImportContextsFromKubeconfig(kubeconfigFile, WithImportUpdateExisting(), WithImportKubeContexts("foo-context"), WithImportNameTemplate("test-unreadable"))
+	assert.ErrorContains(t, err, "failed to import kube context foo-context")
+	assert.ErrorContains(t, err, "no secret store is configured")
+
+	// Nothing was imported
+	_, err = GetContext("bar-context")
+	assert.Error(t, err)
+	cfg, err := GetClientConfig()
+	assert.NoError(t, err)
+	assert.True(t, cfg.HasContext("test-unreadable"))
+}
@@ -198,7 +198,8 @@ current: test-mc2
 currentContext:
   kubernetes: test-mc
 `
-	expectedCfg2 := `contexts:
+	expectedCfg2 := `apiVersion: config.tanzu.vmware.com/v1alpha3
+contexts:
     - name: test-mc
       target: kubernetes
       group: one
@@ -218,6 +219,7 @@ currentContext:
             manifestPath: test-manifest-path
             annotation: one
             required: true
+      contextType: kubernetes
     - target: kubernetes
       group: one
       clusterOpts:
@@ -236,6 +238,7 @@ currentContext:
             manifestPath: test-manifest-path
             annotation: one
             required: true
+      contextType: kubernetes
     - target: kubernetes
       group: one
       clusterOpts:
@@ -254,6 +257,7 @@ currentContext:
             manifestPath: test-manifest-path
             annotation: one
             required: true
+      contextType: kubernetes
     - name: test-mc2
       target: kubernetes
       contextType: kubernetes
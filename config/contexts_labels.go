@@ -0,0 +1,322 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+
+	"github.com/vmware-tanzu/tanzu-plugin-runtime/config/collectionutils"
+	"github.com/vmware-tanzu/tanzu-plugin-runtime/config/nodeutils"
+	configtypes "github.com/vmware-tanzu/tanzu-plugin-runtime/config/types"
+)
+
+const (
+	// labelNameMaxLength is the maximum length of a label value and of the name part of a label key
+	labelNameMaxLength = 63
+	// labelPrefixMaxLength is the maximum length of the DNS subdomain prefix of a label key
+	labelPrefixMaxLength = 253
+)
+
+var (
+	labelNameRegexp   = regexp.MustCompile(`^[A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?$`)
+	labelPrefixRegexp = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+	// labelSetRequirementRegexp matches the set based requirements, e.g. team in (a,b)
+	labelSetRequirementRegexp = regexp.MustCompile(`^(\S+)\s+(in|notin)\s*\((.*)\)$`)
+)
+
+// ListContexts returns the contexts whose labels match the Kubernetes style label selector, e.g.
+// "env=prod,team in (a,b)". The selector supports the key, !key, key=value, key==value, key!=value,
+// key in (v1,v2) and key notin (v1,v2) requirements. An empty selector matches all the contexts.
+func ListContexts(selector string) ([]*configtypes.Context, error) {
+	labelSelector, err := ParseLabelSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	node, err := getClientConfigNode()
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := convertNodeToClientConfig(node)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*configtypes.Context
+	for _, ctx := range cfg.KnownContexts {
+		if !labelSelector.Matches(ctx.Labels) {
+			continue
+		}
+		ctx, err := rehydrateContextSecrets(ctx)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, ctx)
+	}
+	return results, nil
+}
+
+// SetContextLabels adds or updates the labels of the context, keeping its other labels
+func SetContextLabels(ctxName string, labels map[string]string) error {
+	if err := validateLabels(labels); err != nil {
+		return err
+	}
+	return Update(func(tx *ConfigTx) error {
+		persist, err := setContextLabelsNode(tx.node, ctxName, labels)
+		if err != nil {
+			return err
+		}
+		tx.markPersist(persist)
+		return nil
+	})
+}
+
+// RemoveContextLabels removes the labels of the context by key. Removing a missing label is a no-op.
+func RemoveContextLabels(ctxName string, keys ...string) error {
+	return Update(func(tx *ConfigTx) error {
+		persist, err := removeContextLabelsNode(tx.node, ctxName, keys)
+		if err != nil {
+			return err
+		}
+		tx.markPersist(persist)
+		return nil
+	})
+}
+
+func setContextLabelsNode(node *yaml.Node, ctxName string, labels map[string]string) (persist bool, err error) {
+	if ctxName == "" {
+		return false, errors.New("context name cannot be empty")
+	}
+	contextNode := findContextNode(node, ctxName)
+	if contextNode == nil {
+		return false, fmt.Errorf("context %v not found", ctxName)
+	}
+	if len(labels) == 0 {
+		return false, nil
+	}
+	labelsNode := nodeutils.FindNode(contextNode, nodeutils.WithForceCreate(), nodeutils.WithKeys([]nodeutils.Key{{Name: KeyLabels, Type: yaml.MappingNode}}))
+	for _, key := range sortedKeys(labels) {
+		value := labels[key]
+		if index := nodeutils.GetNodeIndex(labelsNode.Content, key); index != -1 {
+			if labelsNode.Content[index].Value != value {
+				labelsNode.Content[index] = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+				persist = true
+			}
+			continue
+		}
+		labelsNode.Content = append(labelsNode.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key},
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value})
+		persist = true
+	}
+	return persist, nil
+}
+
+func removeContextLabelsNode(node *yaml.Node, ctxName string, keys []string) (persist bool, err error) {
+	if ctxName == "" {
+		return false, errors.New("context name cannot be empty")
+	}
+	contextNode := findContextNode(node, ctxName)
+	if contextNode == nil {
+		return false, fmt.Errorf("context %v not found", ctxName)
+	}
+	labelsNode := nodeutils.FindNode(contextNode, nodeutils.WithKeys([]nodeutils.Key{{Name: KeyLabels}}))
+	if labelsNode == nil {
+		return false, nil
+	}
+	for _, key := range keys {
+		if index := nodeutils.GetNodeIndex(labelsNode.Content, key); index != -1 {
+			labelsNode.Content = append(labelsNode.Content[:index-1], labelsNode.Content[index+1:]...)
+			persist = true
+		}
+	}
+	if len(labelsNode.Content) == 0 {
+		// Drop the empty labels of the context
+		if index := nodeutils.GetNodeIndex(contextNode.Content, KeyLabels); index != -1 {
+			contextNode.Content = append(contextNode.Content[:index-1], contextNode.Content[index+1:]...)
+			persist = true
+		}
+	}
+	return persist, nil
+}
+
+// validateLabels checks that the label keys and values follow the Kubernetes label syntax
+func validateLabels(labels map[string]string) error {
+	for _, key := range sortedKeys(labels) {
+		if err := validateLabelKey(key); err != nil {
+			return err
+		}
+		if err := validateLabelValue(key, labels[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateLabelKey checks that the label key is an optional DNS subdomain prefix followed by "/" and a name
+func validateLabelKey(key string) error {
+	name := key
+	if i := strings.LastIndex(key, "/"); i != -1 {
+		prefix := key[:i]
+		name = key[i+1:]
+		if len(prefix) > labelPrefixMaxLength || !labelPrefixRegexp.MatchString(prefix) {
+			return errors.Errorf("invalid label key %q, the prefix must be a DNS subdomain of at most %d characters", key, labelPrefixMaxLength)
+		}
+	}
+	if len(name) > labelNameMaxLength || !labelNameRegexp.MatchString(name) {
+		return errors.Errorf("invalid label key %q, the name must be at most %d characters, start and end with a letter or digit and only contain letters, digits, '-', '_' and '.'", key, labelNameMaxLength)
+	}
+	return nil
+}
+
+func validateLabelValue(key, value string) error {
+	if value == "" {
+		return nil
+	}
+	if len(value) > labelNameMaxLength || !labelNameRegexp.MatchString(value) {
+		return errors.Errorf("invalid value %q of label %s, the value must be at most %d characters, start and end with a letter or digit and only contain letters, digits, '-', '_' and '.'", value, key, labelNameMaxLength)
+	}
+	return nil
+}
+
+// labelOperator is the operator of a label selector requirement
+type labelOperator string
+
+const (
+	labelOpExists       labelOperator = "exists"
+	labelOpDoesNotExist labelOperator = "!"
+	labelOpEquals       labelOperator = "="
+	labelOpNotEquals    labelOperator = "!="
+	labelOpIn           labelOperator = "in"
+	labelOpNotIn        labelOperator = "notin"
+)
+
+// labelRequirement is a requirement of a label selector on the label of a key
+type labelRequirement struct {
+	key      string
+	operator labelOperator
+	values   []string
+}
+
+// LabelSelector selects the contexts by their labels, all the requirements of the selector must match
+type LabelSelector struct {
+	requirements []labelRequirement
+}
+
+// ParseLabelSelector parses a Kubernetes style label selector, e.g. "env=prod,team in (a,b),!deprecated"
+func ParseLabelSelector(selector string) (*LabelSelector, error) {
+	s := &LabelSelector{}
+	for _, term := range splitLabelSelector(selector) {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			if strings.TrimSpace(selector) == "" {
+				continue
+			}
+			return nil, errors.Errorf("invalid label selector %q: empty requirement", selector)
+		}
+		r, err := parseLabelRequirement(term)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid label selector %q", selector)
+		}
+		s.requirements = append(s.requirements, r)
+	}
+	return s, nil
+}
+
+// Matches returns true if the labels satisfy all the requirements of the selector
+func (s *LabelSelector) Matches(labels map[string]string) bool {
+	for _, r := range s.requirements {
+		if !r.matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// splitLabelSelector splits the selector at the commas outside of the value sets
+func splitLabelSelector(selector string) []string {
+	var terms []string
+	depth, start := 0, 0
+	for i, c := range selector {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				terms = append(terms, selector[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(terms, selector[start:])
+}
+
+func parseLabelRequirement(term string) (labelRequirement, error) {
+	if m := labelSetRequirementRegexp.FindStringSubmatch(term); m != nil {
+		r := labelRequirement{key: m[1], operator: labelOperator(m[2])}
+		for _, value := range strings.Split(m[3], ",") {
+			value = strings.TrimSpace(value)
+			if err := validateLabelValue(r.key, value); err != nil {
+				return r, err
+			}
+			r.values = append(r.values, value)
+		}
+		return r, validateLabelKey(r.key)
+	}
+	if strings.HasPrefix(term, "!") && !strings.Contains(term, "=") {
+		r := labelRequirement{key: strings.TrimSpace(term[1:]), operator: labelOpDoesNotExist}
+		return r, validateLabelKey(r.key)
+	}
+
+	var r labelRequirement
+	var key, value string
+	switch {
+	case strings.Contains(term, "!="):
+		key, value, _ = strings.Cut(term, "!=")
+		r.operator = labelOpNotEquals
+	case strings.Contains(term, "=="):
+		key, value, _ = strings.Cut(term, "==")
+		r.operator = labelOpEquals
+	case strings.Contains(term, "="):
+		key, value, _ = strings.Cut(term, "=")
+		r.operator = labelOpEquals
+	default:
+		r = labelRequirement{key: term, operator: labelOpExists}
+		if strings.ContainsAny(term, " ()") {
+			return r, errors.Errorf("unsupported requirement %q", term)
+		}
+		return r, validateLabelKey(r.key)
+	}
+	r.key = strings.TrimSpace(key)
+	value = strings.TrimSpace(value)
+	if err := validateLabelKey(r.key); err != nil {
+		return r, err
+	}
+	if err := validateLabelValue(r.key, value); err != nil {
+		return r, err
+	}
+	r.values = []string{value}
+	return r, nil
+}
+
+func (r *labelRequirement) matches(labels map[string]string) bool {
+	value, ok := labels[r.key]
+	switch r.operator {
+	case labelOpExists:
+		return ok
+	case labelOpDoesNotExist:
+		return !ok
+	case labelOpEquals, labelOpIn:
+		return ok && collectionutils.Contains(r.values, value)
+	case labelOpNotEquals, labelOpNotIn:
+		return !ok || !collectionutils.Contains(r.values, value)
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	configtypes "github.com/vmware-tanzu/tanzu-plugin-runtime/config/types"
+)
+
+func contextNames(contexts []*configtypes.Context) []string {
+	var names []string
+	for _, c := range contexts {
+		names = append(names, c.Name)
+	}
+	return names
+}
+
+func TestSetContextLabels(t *testing.T) {
+	err := setupForGetContext()
+	require.NoError(t, err)
+	defer cleanupTestingDir(t)
+
+	assert.NoError(t, SetContextLabels("test-mc", map[string]string{"env": "prod", "team": "a"}))
+	assert.NoError(t, SetContextLabels("test-mc", map[string]string{"team": "b", "example.com/owner": "alice"}))
+	c, err := GetContext("test-mc")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"env": "prod", "team": "b", "example.com/owner": "alice"}, c.Labels)
+
+	// SetContext merges the labels with the existing labels
+	assert.NoError(t, SetContext(&configtypes.Context{Name: "test-mc", ContextType: configtypes.ContextTypeK8s, ClusterOpts: &configtypes.ClusterServer{Path: "new-path"}}, false))
+	assert.NoError(t, SetContext(&configtypes.Context{Name: "test-mc", Labels: map[string]string{"tier": "gold"}}, false))
+	c, err = GetContext("test-mc")
+	require.NoError(t, err)
+	assert.Equal(t, "new-path", c.ClusterOpts.Path)
+	assert.Equal(t, map[string]string{"env": "prod", "team": "b", "example.com/owner": "alice", "tier": "gold"}, c.Labels)
+
+	assert.NoError(t, RemoveContextLabels("test-mc", "team", "missing"))
+	c, err = GetContext("test-mc")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"env": "prod", "example.com/owner": "alice", "tier": "gold"}, c.Labels)
+	assert.NoError(t, RemoveContextLabels("test-mc", "env", "example.com/owner", "tier"))
+	c, err = GetContext("test-mc")
+	require.NoError(t, err)
+	assert.Nil(t, c.Labels)
+
+	err = SetContextLabels("test-mc", map[string]string{"-env": "prod"})
+	assert.ErrorContains(t, err, `invalid label key "-env"`)
+	err = SetContextLabels("test-mc", map[string]string{"env": "prod value"})
+	assert.ErrorContains(t, err, `invalid value "prod value" of label env`)
+	err = SetContext(&configtypes.Context{Name: "test-mc", Labels: map[string]string{"Example.com/env": "prod"}}, false)
+	assert.ErrorContains(t, err, `invalid label key "Example.com/env"`)
+	err = SetContextLabels("missing", map[string]string{"env": "prod"})
+	assert.EqualError(t, err, "context missing not found")
+}
+
+func TestListContexts(t *testing.T) {
+	err := setupForGetContext()
+	require.NoError(t, err)
+	defer cleanupTestingDir(t)
+
+	require.NoError(t, SetContextLabels("test-mc", map[string]string{"env": "prod", "team": "a"}))
+	require.NoError(t, SetContextLabels("test-mc-2", map[string]string{"env": "dev", "team": "b"}))
+	require.NoError(t, SetContextLabels("test-tmc", map[string]string{"env": "prod", "team": "c", "deprecated": ""}))
+
+	tests := []struct {
+		selector string
+		expected []string
+	}{
+		{"", []string{"test-mc", "test-mc-2", "test-tmc", "test-tanzu"}},
+		{"env=prod", []string{"test-mc", "test-tmc"}},
+		{"env==prod,team in (a,b)", []string{"test-mc"}},
+		{"env!=prod", []string{"test-mc-2", "test-tanzu"}},
+		{"team notin (a, c)", []string{"test-mc-2", "test-tanzu"}},
+		{"deprecated", []string{"test-tmc"}},
+		{"env, !deprecated", []string{"test-mc", "test-mc-2"}},
+		{"env=staging", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			contexts, err := ListContexts(tt.selector)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, contextNames(contexts))
+		})
+	}
+
+	for _, selector := range []string{"env=prod,", "env in prod", "env=a b", "team in (a,-b)"} {
+		_, err = ListContexts(selector)
+		assert.ErrorContains(t, err, "invalid label selector", selector)
+	}
+}
@@ -0,0 +1,207 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+
+	"github.com/vmware-tanzu/tanzu-plugin-runtime/config/nodeutils"
+)
+
+// contextMetadataNamespaceSeparator separates the namespace from the key of namespaced context metadata
+const contextMetadataNamespaceSeparator = "/"
+
+// ErrContextMetadataNotFound is returned when a context has no metadata for the key
+var ErrContextMetadataNotFound = errors.New("context metadata not found")
+
+type contextMetadataOptions struct {
+	namespace string
+}
+
+type ContextMetadataOptions func(o *contextMetadataOptions)
+
+// WithMetadataNamespace scopes the metadata key to the namespace, typically the plugin name,
+// so that the metadata keys of different plugins cannot collide. The metadata is stored in
+// AdditionalMetadata under the "<namespace>/<key>" key.
+func WithMetadataNamespace(namespace string) ContextMetadataOptions {
+	return func(o *contextMetadataOptions) {
+		o.namespace = namespace
+	}
+}
+
+// GetContextMetadata decodes the additional metadata of the context stored for the key into a value of type T.
+// Structs, slices and maps are supported through their YAML representation. ErrContextMetadataNotFound is
+// returned if the context has no metadata for the key, and an error if the metadata cannot be decoded as T.
+func GetContextMetadata[T any](ctxName, key string, opts ...ContextMetadataOptions) (T, error) {
+	var value T
+	metadataKey, err := contextMetadataKey(key, opts...)
+	if err != nil {
+		return value, err
+	}
+	// Retrieve client config node
+	node, err := getClientConfigNode()
+	if err != nil {
+		return value, err
+	}
+	valueNode, err := getContextMetadataNode(node, ctxName, metadataKey)
+	if err != nil {
+		return value, err
+	}
+	if err := decodeNodeStrict(valueNode, &value); err != nil {
+		return value, errors.Wrapf(err, "failed to decode metadata %s of context %s as %T", metadataKey, ctxName, value)
+	}
+	return value, nil
+}
+
+// SetContextMetadata stores the value, encoded through its YAML representation, in the additional metadata of the
+// context for the key. An existing value of the key is replaced.
+func SetContextMetadata[T any](ctxName, key string, value T, opts ...ContextMetadataOptions) error {
+	metadataKey, err := contextMetadataKey(key, opts...)
+	if err != nil {
+		return err
+	}
+	valueNode := &yaml.Node{}
+	if err := valueNode.Encode(value); err != nil {
+		return errors.Wrapf(err, "failed to encode metadata %s of context %s", metadataKey, ctxName)
+	}
+	return Update(func(tx *ConfigTx) error {
+		persist, err := setContextMetadataNode(tx.node, ctxName, metadataKey, valueNode)
+		if err != nil {
+			return err
+		}
+		tx.markPersist(persist)
+		return nil
+	})
+}
+
+// DeleteContextMetadata removes the additional metadata of the context stored for the key
+func DeleteContextMetadata(ctxName, key string, opts ...ContextMetadataOptions) error {
+	metadataKey, err := contextMetadataKey(key, opts...)
+	if err != nil {
+		return err
+	}
+	return Update(func(tx *ConfigTx) error {
+		persist, err := deleteContextMetadataNode(tx.node, ctxName, metadataKey)
+		if err != nil {
+			return err
+		}
+		tx.markPersist(persist)
+		return nil
+	})
+}
+
+// contextMetadataKey returns the key of the metadata in AdditionalMetadata
+func contextMetadataKey(key string, opts ...ContextMetadataOptions) (string, error) {
+	options := &contextMetadataOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	if key == "" {
+		return "", errors.New("metadata key cannot be empty")
+	}
+	if options.namespace == "" {
+		return key, nil
+	}
+	if strings.Contains(options.namespace, contextMetadataNamespaceSeparator) {
+		return "", errors.Errorf("metadata namespace %q cannot contain %q", options.namespace, contextMetadataNamespaceSeparator)
+	}
+	return options.namespace + contextMetadataNamespaceSeparator + key, nil
+}
+
+// findContextNode returns the node of the context by name, nil if not found
+func findContextNode(node *yaml.Node, name string) *yaml.Node {
+	keys := []nodeutils.Key{
+		{Name: KeyContexts},
+	}
+	contextsNode := nodeutils.FindNode(node.Content[0], nodeutils.WithKeys(keys))
+	if contextsNode == nil {
+		return nil
+	}
+	for _, contextNode := range contextsNode.Content {
+		if index := nodeutils.GetNodeIndex(contextNode.Content, "name"); index != -1 && contextNode.Content[index].Value == name {
+			return contextNode
+		}
+	}
+	return nil
+}
+
+func getContextMetadataNode(node *yaml.Node, ctxName, key string) (*yaml.Node, error) {
+	if ctxName == "" {
+		return nil, errors.New("context name cannot be empty")
+	}
+	contextNode := findContextNode(node, ctxName)
+	if contextNode == nil {
+		return nil, fmt.Errorf("context %v not found", ctxName)
+	}
+	metadataNode := nodeutils.FindNode(contextNode, nodeutils.WithKeys([]nodeutils.Key{{Name: KeyAdditionalMetadata}}))
+	if metadataNode == nil {
+		return nil, errors.Wrapf(ErrContextMetadataNotFound, "context %s has no metadata %s", ctxName, key)
+	}
+	index := nodeutils.GetNodeIndex(metadataNode.Content, key)
+	if index == -1 {
+		return nil, errors.Wrapf(ErrContextMetadataNotFound, "context %s has no metadata %s", ctxName, key)
+	}
+	return metadataNode.Content[index], nil
+}
+
+func setContextMetadataNode(node *yaml.Node, ctxName, key string, valueNode *yaml.Node) (persist bool, err error) {
+	if ctxName == "" {
+		return false, errors.New("context name cannot be empty")
+	}
+	contextNode := findContextNode(node, ctxName)
+	if contextNode == nil {
+		return false, fmt.Errorf("context %v not found", ctxName)
+	}
+	metadataNode := nodeutils.FindNode(contextNode, nodeutils.WithForceCreate(), nodeutils.WithKeys([]nodeutils.Key{{Name: KeyAdditionalMetadata, Type: yaml.MappingNode}}))
+	if index := nodeutils.GetNodeIndex(metadataNode.Content, key); index != -1 {
+		if equalValueNodes(metadataNode.Content[index], valueNode) {
+			return false, nil
+		}
+		metadataNode.Content[index] = valueNode
+		return true, nil
+	}
+	keyNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}
+	metadataNode.Content = append(metadataNode.Content, keyNode, valueNode)
+	return true, nil
+}
+
+func deleteContextMetadataNode(node *yaml.Node, ctxName, key string) (persist bool, err error) {
+	if _, err := getContextMetadataNode(node, ctxName, key); err != nil {
+		if errors.Is(err, ErrContextMetadataNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	contextNode := findContextNode(node, ctxName)
+	metadataNode := nodeutils.FindNode(contextNode, nodeutils.WithKeys([]nodeutils.Key{{Name: KeyAdditionalMetadata}}))
+	index := nodeutils.GetNodeIndex(metadataNode.Content, key)
+	metadataNode.Content = append(metadataNode.Content[:index-1], metadataNode.Content[index+1:]...)
+	return true, nil
+}
+
+// equalValueNodes checks whether the nodes, of any kind, decode to deep equal values
+func equalValueNodes(node1, node2 *yaml.Node) bool {
+	var v1, v2 interface{}
+	if node1.Decode(&v1) != nil || node2.Decode(&v2) != nil {
+		return false
+	}
+	return reflect.DeepEqual(v1, v2)
+}
+
+// decodeNodeStrict decodes the node into v rejecting the fields unknown to v
+func decodeNodeStrict(node *yaml.Node, v interface{}) error {
+	data, err := yaml.Marshal(node)
+	if err != nil {
+		return err
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	return decoder.Decode(v)
+}
@@ -0,0 +1,95 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	configtypes "github.com/vmware-tanzu/tanzu-plugin-runtime/config/types"
+)
+
+type testClusterInfo struct {
+	Name     string            `yaml:"name"`
+	Replicas int               `yaml:"replicas"`
+	Labels   map[string]string `yaml:"labels,omitempty"`
+}
+
+func TestSetGetContextMetadata(t *testing.T) {
+	// Setup config data
+	_, cleanUp := setupTestConfig(t, &CfgTestData{})
+
+	defer func() {
+		cleanUp()
+	}()
+
+	err := SetContext(&configtypes.Context{
+		Name:        "test-tanzu",
+		ContextType: configtypes.ContextTypeTanzu,
+		ClusterOpts: &configtypes.ClusterServer{Endpoint: "test-endpoint"},
+		AdditionalMetadata: map[string]interface{}{
+			OrgIDKey: "org-id",
+		},
+	}, true)
+	assert.NoError(t, err)
+
+	// Existing metadata can be read with the expected type
+	orgID, err := GetContextMetadata[string]("test-tanzu", OrgIDKey)
+	assert.NoError(t, err)
+	assert.Equal(t, "org-id", orgID)
+	_, err = GetContextMetadata[[]string]("test-tanzu", OrgIDKey)
+	assert.ErrorContains(t, err, "failed to decode metadata tanzuOrgID of context test-tanzu as []string")
+
+	// Structs, slices and maps round-trip
+	info := testClusterInfo{Name: "cluster1", Replicas: 3, Labels: map[string]string{"env": "dev"}}
+	assert.NoError(t, SetContextMetadata("test-tanzu", "cluster", info, WithMetadataNamespace("myplugin")))
+	assert.NoError(t, SetContextMetadata("test-tanzu", "regions", []string{"us", "eu"}))
+
+	gotInfo, err := GetContextMetadata[testClusterInfo]("test-tanzu", "cluster", WithMetadataNamespace("myplugin"))
+	assert.NoError(t, err)
+	assert.Equal(t, info, gotInfo)
+	gotMap, err := GetContextMetadata[map[string]interface{}]("test-tanzu", "cluster", WithMetadataNamespace("myplugin"))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, gotMap["replicas"])
+	regions, err := GetContextMetadata[[]string]("test-tanzu", "regions")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"us", "eu"}, regions)
+
+	// Fields unknown to the type are rejected
+	type otherInfo struct {
+		Name string `yaml:"name"`
+	}
+	_, err = GetContextMetadata[otherInfo]("test-tanzu", "cluster", WithMetadataNamespace("myplugin"))
+	assert.ErrorContains(t, err, "field replicas not found")
+
+	// Namespaced keys do not collide
+	_, err = GetContextMetadata[testClusterInfo]("test-tanzu", "cluster")
+	assert.True(t, errors.Is(err, ErrContextMetadataNotFound))
+	_, err = GetContextMetadata[testClusterInfo]("test-tanzu", "cluster", WithMetadataNamespace("otherplugin"))
+	assert.True(t, errors.Is(err, ErrContextMetadataNotFound))
+	_, err = GetContextMetadata[string]("test-tanzu", "cluster", WithMetadataNamespace("my/plugin"))
+	assert.ErrorContains(t, err, "cannot contain")
+
+	// The other metadata of the context is preserved
+	c, err := GetContext("test-tanzu")
+	assert.NoError(t, err)
+	assert.Equal(t, "org-id", c.AdditionalMetadata[OrgIDKey])
+	assert.Contains(t, c.AdditionalMetadata, "myplugin/cluster")
+
+	// Replacing a value drops its previous fields
+	assert.NoError(t, SetContextMetadata("test-tanzu", "cluster", testClusterInfo{Name: "cluster2"}, WithMetadataNamespace("myplugin")))
+	gotInfo, err = GetContextMetadata[testClusterInfo]("test-tanzu", "cluster", WithMetadataNamespace("myplugin"))
+	assert.NoError(t, err)
+	assert.Equal(t, testClusterInfo{Name: "cluster2"}, gotInfo)
+
+	assert.NoError(t, DeleteContextMetadata("test-tanzu", "cluster", WithMetadataNamespace("myplugin")))
+	assert.NoError(t, DeleteContextMetadata("test-tanzu", "cluster", WithMetadataNamespace("myplugin")))
+	_, err = GetContextMetadata[testClusterInfo]("test-tanzu", "cluster", WithMetadataNamespace("myplugin"))
+	assert.True(t, errors.Is(err, ErrContextMetadataNotFound))
+
+	err = SetContextMetadata("unknown", "key", "value")
+	assert.EqualError(t, err, "context unknown not found")
+}
@@ -15,6 +15,8 @@ import (
 // This is needed when reading the config file persisted by an older core or plugin,
 // so that it is forwards compatible with a new core plugin.
 // Returns true if there was any delta.
+// Deprecated: The servers of the config are converted to contexts by the config migrations
+// when the config is first loaded.
 func PopulateContexts(cfg *configtypes.ClientConfig) bool {
 	if cfg == nil || len(cfg.KnownServers) == 0 {
 		return false
@@ -165,15 +167,14 @@ func convertNodeToClientConfig(node *yaml.Node) (obj *configtypes.ClientConfig,
 	if obj == nil {
 		return &configtypes.ClientConfig{}, err
 	}
+	// The contexts written by an older runtime after the config was migrated have no contextType
 	fillContextTypeIfMissingInConfig(obj)
 	return obj, err
 }
 
 func fillContextTypeIfMissingInConfig(obj *configtypes.ClientConfig) {
 	for i := range obj.KnownContexts {
-		if obj.KnownContexts[i].ContextType == "" {
-			obj.KnownContexts[i].ContextType = configtypes.ConvertTargetToContextType(obj.KnownContexts[i].Target)
-		}
+		fillMissingContextTypeInContext(obj.KnownContexts[i])
 	}
 }
 
@@ -0,0 +1,68 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"gopkg.in/yaml.v3"
+
+	"github.com/vmware-tanzu/tanzu-plugin-runtime/config/nodeutils"
+	configtypes "github.com/vmware-tanzu/tanzu-plugin-runtime/config/types"
+)
+
+// Diff returns the changes from the client config a to the client config b. The contexts, servers and
+// repositories are matched by name, the certs by host and the discovery sources by type and name,
+// e.g. contexts[test-mc].clusterOpts.path or cli.discoverySources[oci:default].oci.image.
+// Use nodeutils.RenderUnified to render the changes as text.
+func Diff(a, b *configtypes.ClientConfig) ([]nodeutils.Change, error) {
+	if a == nil {
+		a = &configtypes.ClientConfig{}
+	}
+	if b == nil {
+		b = &configtypes.ClientConfig{}
+	}
+	src, err := convertObjectToNode(a)
+	if err != nil {
+		return nil, err
+	}
+	dst, err := convertObjectToNode(b)
+	if err != nil {
+		return nil, err
+	}
+	return nodeutils.Diff(src, dst, clientConfigDiffOptions()...)
+}
+
+// clientConfigDiffOptions returns the keys of the sequences of the client config that are not keyed by name
+func clientConfigDiffOptions() []nodeutils.DiffOpts {
+	opts := []nodeutils.DiffOpts{
+		nodeutils.WithSequenceKey(KeyCerts, nodeutils.FieldSequenceKey("host")),
+	}
+	for _, pattern := range []string{
+		"cli.discoverySources",
+		"clientOptions.cli.discoverySources",
+		"contexts[].discoverySources",
+		"servers[].discoverySources",
+	} {
+		opts = append(opts, nodeutils.WithSequenceKey(pattern, discoverySourceSequenceKey))
+	}
+	return opts
+}
+
+// discoverySourceSequenceKey identifies a discovery source by <type>:<name>, e.g. oci:default
+func discoverySourceSequenceKey(item *yaml.Node) (string, bool) {
+	if item.Kind != yaml.MappingNode {
+		return "", false
+	}
+	for _, backend := range pluginDiscoveryBackends {
+		backendNode := nodeutils.FindNode(item, nodeutils.WithKeys([]nodeutils.Key{{Name: backend}}))
+		if backendNode == nil || backendNode.Tag == "!!null" {
+			continue
+		}
+		name, ok := nodeutils.FieldSequenceKey("name")(backendNode)
+		if !ok {
+			return "", false
+		}
+		return backend + ":" + name, true
+	}
+	return "", false
+}
@@ -0,0 +1,62 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vmware-tanzu/tanzu-plugin-runtime/config/nodeutils"
+	configtypes "github.com/vmware-tanzu/tanzu-plugin-runtime/config/types"
+)
+
+func TestDiff(t *testing.T) {
+	a := &configtypes.ClientConfig{
+		KnownContexts: []*configtypes.Context{
+			{Name: "test-mc", ContextType: configtypes.ContextTypeK8s, ClusterOpts: &configtypes.ClusterServer{Path: "test-path"}},
+			{Name: "test-tmc", ContextType: configtypes.ContextTypeTMC},
+		},
+		Certs: []*configtypes.Cert{{Host: "test-host", SkipCertVerify: "false"}},
+		CoreCliOptions: &configtypes.CoreCliOptions{
+			DiscoverySources: []configtypes.PluginDiscovery{
+				{OCI: &configtypes.OCIDiscovery{Name: "default", Image: "image:v1"}},
+				{Local: &configtypes.LocalDiscovery{Name: "default", Path: "local-path"}},
+			},
+		},
+	}
+	b := &configtypes.ClientConfig{
+		KnownContexts: []*configtypes.Context{
+			{Name: "test-tmc", ContextType: configtypes.ContextTypeTMC},
+			{Name: "test-mc", ContextType: configtypes.ContextTypeK8s, ClusterOpts: &configtypes.ClusterServer{Path: "new-path"}},
+		},
+		Certs: []*configtypes.Cert{{Host: "test-host", SkipCertVerify: "true"}},
+		CoreCliOptions: &configtypes.CoreCliOptions{
+			DiscoverySources: []configtypes.PluginDiscovery{
+				{Local: &configtypes.LocalDiscovery{Name: "default", Path: "local-path"}},
+				{OCI: &configtypes.OCIDiscovery{Name: "default", Image: "image:v2"}},
+			},
+		},
+		ClientOptions: &configtypes.ClientOptions{Env: map[string]string{"FOO": "bar"}},
+	}
+
+	changes, err := Diff(a, b)
+	assert.NoError(t, err)
+	assert.Equal(t, []nodeutils.Change{
+		{Type: nodeutils.ChangeModified, Path: "contexts[test-mc].clusterOpts.path", Old: "test-path", New: "new-path"},
+		{Type: nodeutils.ChangeModified, Path: "cli.discoverySources[oci:default].oci.image", Old: "image:v1", New: "image:v2"},
+		{Type: nodeutils.ChangeModified, Path: "certs[test-host].skipCertVerify", Old: "false", New: "true"},
+		{Type: nodeutils.ChangeAdded, Path: "clientOptions", New: map[string]interface{}{"env": map[string]interface{}{"FOO": "bar"}}},
+	}, changes)
+
+	changes, err = Diff(nil, &configtypes.ClientConfig{KnownContexts: a.KnownContexts[:1]})
+	assert.NoError(t, err)
+	assert.Len(t, changes, 1)
+	assert.Equal(t, nodeutils.ChangeAdded, changes[0].Type)
+	assert.Equal(t, "contexts", changes[0].Path)
+
+	changes, err = Diff(a, a)
+	assert.NoError(t, err)
+	assert.Empty(t, changes)
+}
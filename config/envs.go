@@ -10,14 +10,19 @@ import (
 	"github.com/vmware-tanzu/tanzu-plugin-runtime/config/nodeutils"
 )
 
-// GetAllEnvs retrieves all env values from config
+// GetAllEnvs retrieves all env values from config, merged with the TANZU_CONFIG_ENV_<KEY> environment variables
 func GetAllEnvs() (map[string]string, error) {
 	// Retrieve client config node
 	node, err := getClientConfigNode()
 	if err != nil {
 		return nil, err
 	}
-	return getAllEnvs(node)
+	overlay := getConfigOverlay()
+	envs, err := getAllEnvs(node)
+	if err != nil && len(overlay.env) == 0 {
+		return nil, err
+	}
+	return overlay.mergeEnvs(envs), nil
 }
 
 func getAllEnvs(node *yaml.Node) (map[string]string, error) {
@@ -31,8 +36,12 @@ func getAllEnvs(node *yaml.Node) (map[string]string, error) {
 	return nil, errors.New("not found")
 }
 
-// GetEnv retrieves env value by key
+// GetEnv retrieves env value by key.
+// The TANZU_CONFIG_ENV_<KEY> environment variable takes precedence over the config.
 func GetEnv(key string) (string, error) {
+	if val, ok := getConfigOverlay().getEnv(key); ok {
+		return val, nil
+	}
 	// Retrieve client config node
 	node, err := getClientConfigNode()
 	if err != nil {
@@ -63,8 +72,11 @@ func getEnv(node *yaml.Node, key string) (string, error) {
 // DeleteEnv delete the env entry of specified key
 func DeleteEnv(key string) error {
 	// Retrieve client config node
-	AcquireTanzuConfigLock()
-	defer ReleaseTanzuConfigLock()
+	unlock, err := acquireTanzuConfigLock()
+	if err != nil {
+		return err
+	}
+	defer unlock.Unlock()
 	node, err := getClientConfigNodeNoLock()
 	if err != nil {
 		return err
@@ -113,8 +125,11 @@ func deleteEnv(node *yaml.Node, key string) (err error) {
 // SetEnv add or update a env key and value
 func SetEnv(key, value string) (err error) {
 	// Retrieve client config node
-	AcquireTanzuConfigLock()
-	defer ReleaseTanzuConfigLock()
+	unlock, err := acquireTanzuConfigLock()
+	if err != nil {
+		return err
+	}
+	defer unlock.Unlock()
 	node, err := getClientConfigNodeNoLock()
 	if err != nil {
 		return err
@@ -13,8 +13,12 @@ import (
 	"github.com/vmware-tanzu/tanzu-plugin-runtime/config/nodeutils"
 )
 
-// IsFeatureEnabled checks and returns whether specific plugin and key is true
+// IsFeatureEnabled checks and returns whether specific plugin and key is true.
+// The TANZU_CONFIG_FEATURE_<PLUGIN>_<KEY> environment variable takes precedence over the config.
 func IsFeatureEnabled(plugin, key string) (bool, error) {
+	if val, ok := getConfigOverlay().getFeature(plugin, key); ok && plugin != "" && key != "" {
+		return strings.EqualFold(val, "true"), nil
+	}
 	// Retrieve client config node
 	node, err := getClientConfigNode()
 	if err != nil {
@@ -57,8 +61,11 @@ func getFeature(node *yaml.Node, plugin, key string) (string, error) {
 // DeleteFeature deletes the specified plugin key
 func DeleteFeature(plugin, key string) error {
 	// Retrieve client config node
-	AcquireTanzuConfigLock()
-	defer ReleaseTanzuConfigLock()
+	unlock, err := acquireTanzuConfigLock()
+	if err != nil {
+		return err
+	}
+	defer unlock.Unlock()
 	node, err := getClientConfigNodeNoLock()
 	if err != nil {
 		return err
@@ -106,8 +113,11 @@ func deleteFeature(node *yaml.Node, plugin, key string) error {
 // SetFeature add or update plugin key value
 func SetFeature(plugin, key, value string) (err error) {
 	// Retrieve client config node
-	AcquireTanzuConfigLock()
-	defer ReleaseTanzuConfigLock()
+	unlock, err := acquireTanzuConfigLock()
+	if err != nil {
+		return err
+	}
+	defer unlock.Unlock()
 	node, err := getClientConfigNodeNoLock()
 	if err != nil {
 		return err
@@ -164,8 +174,11 @@ func setFeature(node *yaml.Node, plugin, key, value string) (persist bool, err e
 
 // ConfigureDefaultFeatureFlagsIfMissing add or update plugin features based on specified default feature flags
 func ConfigureDefaultFeatureFlagsIfMissing(plugin string, defaultFeatureFlags map[string]bool) error {
-	AcquireTanzuConfigLock()
-	defer ReleaseTanzuConfigLock()
+	unlock, err := acquireTanzuConfigLock()
+	if err != nil {
+		return err
+	}
+	defer unlock.Unlock()
 	node, err := getClientConfigNodeNoLock()
 	if err != nil {
 		return err
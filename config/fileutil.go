@@ -8,8 +8,13 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+
+	"github.com/vmware-tanzu/tanzu-plugin-runtime/config/internal/atomicfile"
 )
 
+// BackupFileSuffix is the suffix of the file that keeps the previous version of a config file
+const BackupFileSuffix = ".bak"
+
 // copyFile copies a file from source to destination while preserving permissions. If the destination file does not
 // exist, the file will be created. If the file exists, its contents will be *overwritten*.
 func copyFile(src, dst string) error {
@@ -100,3 +105,48 @@ func fileExists(filename string) (bool, error) {
 	}
 	return true, nil
 }
+
+// writeFileAtomic writes data to the named file so that readers either observe the previous
+// or the new content, never a partially written file. The previous content is kept in a
+// backup file with BackupFileSuffix.
+func writeFileAtomic(filename string, data []byte, perm os.FileMode) error {
+	staged, err := atomicfile.Stage(filename, data, perm)
+	if err != nil {
+		return err
+	}
+	if err := commitStagedFile(staged, filename); err != nil {
+		_ = os.Remove(staged)
+		return err
+	}
+	return nil
+}
+
+// commitStagedFile backs up the named file, if it exists, and atomically replaces it with the staged file.
+// The staged file is left in place on failure, the journaled commits retry it on recovery.
+func commitStagedFile(staged, filename string) error {
+	if err := backupFile(filename); err != nil {
+		return err
+	}
+	return atomicfile.Commit(staged, filename)
+}
+
+// backupFile atomically copies the content of the named file to the backup file.
+// This is a no-op if the named file does not exist.
+func backupFile(filename string) error {
+	info, err := os.Stat(filename)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	// Do not replace a good backup with an empty or truncated file
+	if len(data) == 0 {
+		return nil
+	}
+	return atomicfile.WriteFile(filename+BackupFileSuffix, data, info.Mode().Perm())
+}
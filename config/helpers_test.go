@@ -4,8 +4,17 @@
 package config
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -51,6 +60,11 @@ func setupTestConfig(t *testing.T, data *CfgTestData) (files []*os.File, cleanup
 
 		err = os.Remove(cfgMetadataFile.Name())
 		assert.NoError(t, err)
+
+		// Remove the backups of the previous versions
+		for _, f := range []*os.File{cfgFile, cfgNextGenFile, cfgMetadataFile} {
+			_ = os.Remove(f.Name() + BackupFileSuffix)
+		}
 	}
 
 	return []*os.File{cfgFile, cfgNextGenFile, cfgMetadataFile}, cleanup
@@ -193,3 +207,22 @@ contexts:
 `
 	return cfg, cfg2
 }
+
+// newTestCACertData returns the base64 encoded PEM of a self signed CA certificate expiring at notAfter
+func newTestCACertData(t *testing.T, commonName string, notAfter time.Time) string {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: commonName},
+		DNSNames:              []string{commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              notAfter,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	return base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
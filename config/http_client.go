@@ -0,0 +1,218 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/http/httpproxy"
+
+	configtypes "github.com/vmware-tanzu/tanzu-plugin-runtime/config/types"
+)
+
+// NewTLSConfigForHost returns the TLS config to connect to the host, which may include a port, built
+// from the cert configuration resolved by ResolveCert. The system root CAs are trusted in addition to
+// the CACertData of the cert configuration. The certificate of the host is not verified if the cert
+// configuration sets SkipCertVerify or Insecure, as the Tanzu CLI does for insecure hosts. The default
+// TLS config is returned if no cert configuration matches the host.
+func NewTLSConfigForHost(host string) (*tls.Config, error) {
+	if host == "" {
+		return nil, errors.New("host is empty")
+	}
+	certs, err := GetCerts()
+	if err != nil {
+		return nil, err
+	}
+	var cert *configtypes.Cert
+	if match, err := resolveCert(certs, host); err == nil {
+		cert = match.Cert
+	}
+	return newTLSConfig(host, cert)
+}
+
+// NewHTTPClientForContext returns an HTTP client to connect to the endpoint of the context.
+// The client uses the TLS config of the endpoint host, the proxy settings of the config env
+// falling back to the proxy environment variables, and authenticates with the access token of
+// the context if any. The access token is refreshed when expired or rejected with a 401.
+func NewHTTPClientForContext(name string) (*http.Client, error) {
+	c, err := GetContext(name)
+	if err != nil {
+		return nil, err
+	}
+	endpoint, err := EndpointFromContext(c)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig, err := NewTLSConfigForHost(endpointHost(endpoint))
+	if err != nil {
+		return nil, err
+	}
+	envs, err := GetAllEnvs()
+	if err != nil {
+		envs = map[string]string{}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	transport.Proxy = proxyFuncFromEnvs(envs)
+
+	var rt http.RoundTripper = transport
+	if c.GlobalOpts != nil && c.GlobalOpts.Auth.AccessToken != "" {
+		tokenClient, err := newTokenHTTPClient(c.GlobalOpts.Auth.Issuer, envs)
+		if err != nil {
+			return nil, err
+		}
+		rt = &bearerTokenRoundTripper{contextName: name, base: transport, tokenClient: tokenClient}
+	}
+	return &http.Client{Transport: rt}, nil
+}
+
+// newTokenHTTPClient returns the HTTP client to refresh the tokens against the issuer, using the TLS
+// config of the issuer host and the proxy settings of the config envs. The default client is returned
+// if there is no issuer, as the tokens cannot be refreshed anyway.
+func newTokenHTTPClient(issuer string, envs map[string]string) (*http.Client, error) {
+	if issuer == "" {
+		return http.DefaultClient, nil
+	}
+	tlsConfig, err := NewTLSConfigForHost(endpointHost(issuer))
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	transport.Proxy = proxyFuncFromEnvs(envs)
+	return &http.Client{Transport: transport}, nil
+}
+
+// newTLSConfig returns the TLS config to connect to the host with the cert configuration, which may be nil
+func newTLSConfig(host string, cert *configtypes.Cert) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	tlsConfig.ServerName = host
+	if cert == nil {
+		return tlsConfig, nil
+	}
+
+	skipVerify, _ := strconv.ParseBool(cert.SkipCertVerify)
+	// An insecure host may serve plain http or a certificate that cannot be verified
+	insecure, _ := strconv.ParseBool(cert.Insecure)
+	if skipVerify || insecure {
+		tlsConfig.InsecureSkipVerify = true //nolint:gosec
+	}
+	if cert.CACertData != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(decodeCACertData(cert.CACertData)) {
+			return nil, errors.Errorf("invalid CA certificate data for host %s", cert.Host)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	return tlsConfig, nil
+}
+
+// decodeCACertData returns the PEM encoded CA certificates of the cert configuration,
+// which are stored either base64 encoded or as is
+func decodeCACertData(data string) []byte {
+	if decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(data)); err == nil {
+		return decoded
+	}
+	return []byte(data)
+}
+
+// endpointHost returns the host, including the port if any, of the endpoint which may not have a scheme
+func endpointHost(endpoint string) string {
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "https://" + endpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return endpoint
+	}
+	return u.Host
+}
+
+// proxyFuncFromEnvs returns the proxy function using the proxy settings of the config envs,
+// falling back to the proxy environment variables
+func proxyFuncFromEnvs(envs map[string]string) func(*http.Request) (*url.URL, error) {
+	lookup := func(keys ...string) string {
+		for _, key := range keys {
+			if val, ok := envs[key]; ok && val != "" {
+				return val
+			}
+		}
+		for _, key := range keys {
+			if val := os.Getenv(key); val != "" {
+				return val
+			}
+		}
+		return ""
+	}
+	proxyConfig := &httpproxy.Config{
+		HTTPProxy:  lookup("HTTP_PROXY", "http_proxy"),
+		HTTPSProxy: lookup("HTTPS_PROXY", "https_proxy"),
+		NoProxy:    lookup("NO_PROXY", "no_proxy"),
+	}
+	proxyFunc := proxyConfig.ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		return proxyFunc(req.URL)
+	}
+}
+
+// bearerTokenRoundTripper authenticates the requests with the access token of a context
+type bearerTokenRoundTripper struct {
+	contextName string
+	base        http.RoundTripper
+	// tokenClient is the HTTP client used to refresh the access token against the issuer
+	tokenClient *http.Client
+}
+
+func (rt *bearerTokenRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := GetValidAccessToken(req.Context(), rt.contextName, WithTokenHTTPClient(rt.tokenClient))
+	if err != nil {
+		return nil, err
+	}
+	resp, err := rt.base.RoundTrip(withBearerToken(req, token))
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+
+	// The token was rejected, refresh it and retry once if the request body can be replayed
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return resp, nil
+	}
+	newToken, err := GetValidAccessToken(req.Context(), rt.contextName, WithTokenHTTPClient(rt.tokenClient), withRejectedToken(token))
+	if err != nil || newToken == token {
+		return resp, nil
+	}
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return resp, nil
+		}
+		retry.Body = body
+	}
+	_ = resp.Body.Close()
+	return rt.base.RoundTrip(withBearerToken(retry, newToken))
+}
+
+// withBearerToken returns a copy of the request with the bearer token authorization header
+func withBearerToken(req *http.Request, token string) *http.Request {
+	r := req.Clone(req.Context())
+	r.Header.Set("Authorization", "Bearer "+token)
+	return r
+}
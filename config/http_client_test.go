@@ -0,0 +1,154 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"encoding/base64"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	configtypes "github.com/vmware-tanzu/tanzu-plugin-runtime/config/types"
+)
+
+func TestNewTLSConfigForHost(t *testing.T) {
+	// Setup config data
+	_, cleanUp := setupTestConfig(t, &CfgTestData{})
+
+	defer func() {
+		cleanUp()
+	}()
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "https://")
+
+	// The test server certificate is not trusted without cert configuration
+	tlsConfig, err := NewTLSConfigForHost(host)
+	assert.NoError(t, err)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+	_, err = client.Get(server.URL)
+	assert.Error(t, err)
+
+	caData := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	assert.NoError(t, SetCert(&configtypes.Cert{Host: host, CACertData: base64.StdEncoding.EncodeToString(caData)}))
+
+	tlsConfig, err = NewTLSConfigForHost(host)
+	assert.NoError(t, err)
+	client = &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+	resp, err := client.Get(server.URL)
+	assert.NoError(t, err)
+	_ = resp.Body.Close()
+
+	// A cert configuration of the host name applies to any port
+	assert.NoError(t, SetCert(&configtypes.Cert{Host: "example.com", SkipCertVerify: "true"}))
+	tlsConfig, err = NewTLSConfigForHost("example.com:8443")
+	assert.NoError(t, err)
+	assert.True(t, tlsConfig.InsecureSkipVerify)
+	assert.Equal(t, "example.com", tlsConfig.ServerName)
+
+	// The certificate of an insecure host is not verified
+	assert.NoError(t, SetCert(&configtypes.Cert{Host: host, Insecure: "true"}))
+	tlsConfig, err = NewTLSConfigForHost(host)
+	assert.NoError(t, err)
+	assert.True(t, tlsConfig.InsecureSkipVerify)
+	client = &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+	resp, err = client.Get(server.URL)
+	assert.NoError(t, err)
+	_ = resp.Body.Close()
+
+	tlsConfig, err = newTLSConfig("example.org", &configtypes.Cert{Host: "example.org", Insecure: "false"})
+	assert.NoError(t, err)
+	assert.False(t, tlsConfig.InsecureSkipVerify)
+
+	_, err = newTLSConfig("invalid.com", &configtypes.Cert{Host: "invalid.com", CACertData: "invalid"})
+	assert.ErrorContains(t, err, "invalid CA certificate data")
+}
+
+func TestNewHTTPClientForContext(t *testing.T) {
+	// Setup config data
+	_, cleanUp := setupTestConfig(t, &CfgTestData{})
+
+	defer func() {
+		cleanUp()
+	}()
+
+	// The issuer is only trusted through its cert configuration
+	var refreshes int32
+	oidcServer := newUnstartedTestOIDCServer(t, &refreshes)
+	oidcServer.StartTLS()
+	defer oidcServer.Close()
+	oidcCAData := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: oidcServer.Certificate().Raw})
+	assert.NoError(t, SetCert(&configtypes.Cert{
+		Host:       strings.TrimPrefix(oidcServer.URL, "https://"),
+		CACertData: base64.StdEncoding.EncodeToString(oidcCAData),
+	}))
+
+	var requests int32
+	apiServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.Header.Get("Authorization") != "Bearer new-access-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer apiServer.Close()
+	host := strings.TrimPrefix(apiServer.URL, "https://")
+
+	assert.NoError(t, SetCert(&configtypes.Cert{Host: host, SkipCertVerify: "true"}))
+	assert.NoError(t, SetContext(&configtypes.Context{
+		Name:        "test-tmc",
+		ContextType: configtypes.ContextTypeTMC,
+		GlobalOpts: &configtypes.GlobalServer{
+			Endpoint: host,
+			Auth: configtypes.GlobalServerAuth{
+				Issuer:       oidcServer.URL,
+				AccessToken:  "access-token",
+				RefreshToken: "refresh-token",
+				Expiration:   time.Now().Add(time.Hour),
+			},
+		},
+	}, true))
+
+	client, err := NewHTTPClientForContext("test-tmc")
+	assert.NoError(t, err)
+
+	// The rejected token is refreshed and the request retried
+	resp, err := client.Post(apiServer.URL, "text/plain", strings.NewReader("body"))
+	assert.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int32(2), requests)
+	assert.Equal(t, int32(1), refreshes)
+
+	resp, err = client.Get(apiServer.URL)
+	assert.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int32(3), requests)
+	assert.Equal(t, int32(1), refreshes)
+}
+
+func TestProxyFuncFromEnvs(t *testing.T) {
+	proxyFunc := proxyFuncFromEnvs(map[string]string{
+		"HTTPS_PROXY": "http://proxy.example.com:3128",
+		"NO_PROXY":    "internal.example.com",
+	})
+
+	proxy, err := proxyFunc(&http.Request{URL: &url.URL{Scheme: "https", Host: "api.example.com"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "http://proxy.example.com:3128", proxy.String())
+
+	proxy, err = proxyFunc(&http.Request{URL: &url.URL{Scheme: "https", Host: "internal.example.com"}})
+	assert.NoError(t, err)
+	assert.Nil(t, proxy)
+}
@@ -0,0 +1,80 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package atomicfile writes files so that readers observe either the previous or the new content
+// of a file, never a partially written file, including after a crash of the process or the machine.
+package atomicfile
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// WriteFile atomically replaces the content of the named file with data.
+// The data is written to a temporary file flushed to disk, which is renamed to the named file.
+func WriteFile(filename string, data []byte, perm os.FileMode) error {
+	staged, err := Stage(filename, data, perm)
+	if err != nil {
+		return err
+	}
+	if err := Commit(staged, filename); err != nil {
+		_ = os.Remove(staged)
+		return err
+	}
+	return nil
+}
+
+// Stage writes data to a temporary file in the directory of the named file and flushes
+// it to disk. It returns the path of the temporary file, which is to be passed to Commit.
+func Stage(filename string, data []byte, perm os.FileMode) (string, error) {
+	f, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".tmp-*")
+	if err != nil {
+		return "", err
+	}
+	staged := f.Name()
+	cleanup := func() {
+		_ = f.Close()
+		_ = os.Remove(staged)
+	}
+	if _, err := f.Write(data); err != nil {
+		cleanup()
+		return "", err
+	}
+	if err := f.Sync(); err != nil {
+		cleanup()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(staged)
+		return "", err
+	}
+	if err := os.Chmod(staged, perm); err != nil {
+		_ = os.Remove(staged)
+		return "", err
+	}
+	return staged, nil
+}
+
+// Commit atomically replaces the named file with the staged file and flushes the rename to disk.
+// The staged file is left in place if it cannot be renamed, so that a journaled commit can be retried.
+func Commit(staged, filename string) error {
+	if err := os.Rename(staged, filename); err != nil {
+		return err
+	}
+	return SyncDir(filepath.Dir(filename))
+}
+
+// SyncDir flushes the directory entry changes (e.g. renames) of the directory to disk
+func SyncDir(dir string) error {
+	// Directories cannot be opened for syncing on windows
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
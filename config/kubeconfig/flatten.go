@@ -0,0 +1,83 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package kubeconfig
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// authInfoFileReferences maps the user fields referencing a file to the fields holding the inline data
+var authInfoFileReferences = map[string]string{
+	"client-certificate": "client-certificate-data",
+	"client-key":         "client-key-data",
+}
+
+// Flatten replaces the file references of the clusters and users of the kubeconfig with the
+// inline content of the files. Relative paths are resolved against baseDir, typically the
+// directory of the kubeconfig file.
+func Flatten(kubeconfig *Config, baseDir string) error {
+	for _, cluster := range kubeconfig.Clusters {
+		if cluster.Cluster.CertificateAuthority == "" {
+			continue
+		}
+		data, err := readReferencedFile(baseDir, cluster.Cluster.CertificateAuthority)
+		if err != nil {
+			return errors.Wrapf(err, "failed to flatten the certificate authority of cluster %q", cluster.Name)
+		}
+		cluster.Cluster.CertificateAuthorityData = base64.StdEncoding.EncodeToString(data)
+		cluster.Cluster.CertificateAuthority = ""
+	}
+
+	for _, authInfo := range kubeconfig.AuthInfos {
+		user, ok := authInfo.AuthInfo.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for fileKey, dataKey := range authInfoFileReferences {
+			path, ok := user[fileKey].(string)
+			if !ok || path == "" {
+				continue
+			}
+			data, err := readReferencedFile(baseDir, path)
+			if err != nil {
+				return errors.Wrapf(err, "failed to flatten the %s of user %q", fileKey, authInfo.Name)
+			}
+			user[dataKey] = base64.StdEncoding.EncodeToString(data)
+			delete(user, fileKey)
+		}
+		if path, ok := user["tokenFile"].(string); ok && path != "" {
+			data, err := readReferencedFile(baseDir, path)
+			if err != nil {
+				return errors.Wrapf(err, "failed to flatten the token file of user %q", authInfo.Name)
+			}
+			user["token"] = strings.TrimSpace(string(data))
+			delete(user, "tokenFile")
+		}
+	}
+	return nil
+}
+
+// FlattenFile reads the kubeconfig file and returns it with the file references replaced by their inline content
+func FlattenFile(path string) (*Config, error) {
+	kubeconfig, err := ReadKubeConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := Flatten(kubeconfig, filepath.Dir(path)); err != nil {
+		return nil, err
+	}
+	return kubeconfig, nil
+}
+
+func readReferencedFile(baseDir, path string) ([]byte, error) {
+	if !filepath.IsAbs(path) && baseDir != "" {
+		path = filepath.Join(baseDir, path)
+	}
+	return os.ReadFile(path)
+}
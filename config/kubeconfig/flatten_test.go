@@ -0,0 +1,69 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package kubeconfig
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlattenFile(t *testing.T) {
+	dir, err := os.MkdirTemp("", "kubeconfig")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "ca.crt"), []byte("ca-data"), 0o600))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "client.crt"), []byte("client-cert"), 0o600))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "client.key"), []byte("client-key"), 0o600))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "token"), []byte("token-data\n"), 0o600))
+
+	path := filepath.Join(dir, "config")
+	content := `apiVersion: v1
+kind: Config
+clusters:
+- name: cluster1
+  cluster:
+    server: https://server
+    certificate-authority: ca.crt
+users:
+- name: user1
+  user:
+    client-certificate: client.crt
+    client-key: ` + filepath.Join(dir, "client.key") + `
+- name: user2
+  user:
+    tokenFile: token
+contexts:
+- name: ctx1
+  context:
+    cluster: cluster1
+    user: user1
+current-context: ctx1
+`
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	kc, err := FlattenFile(path)
+	assert.NoError(t, err)
+	cluster := GetCluster(kc, "cluster1")
+	assert.Empty(t, cluster.Cluster.CertificateAuthority)
+	assert.Equal(t, base64.StdEncoding.EncodeToString([]byte("ca-data")), cluster.Cluster.CertificateAuthorityData)
+
+	user1 := GetAuthInfo(kc, "user1").AuthInfo.(map[string]interface{})
+	assert.Equal(t, base64.StdEncoding.EncodeToString([]byte("client-cert")), user1["client-certificate-data"])
+	assert.Equal(t, base64.StdEncoding.EncodeToString([]byte("client-key")), user1["client-key-data"])
+	assert.NotContains(t, user1, "client-certificate")
+	assert.NotContains(t, user1, "client-key")
+
+	user2 := GetAuthInfo(kc, "user2").AuthInfo.(map[string]interface{})
+	assert.Equal(t, "token-data", user2["token"])
+	assert.NotContains(t, user2, "tokenFile")
+
+	assert.NoError(t, os.Remove(filepath.Join(dir, "ca.crt")))
+	_, err = FlattenFile(path)
+	assert.ErrorContains(t, err, `failed to flatten the certificate authority of cluster "cluster1"`)
+}
@@ -84,3 +84,71 @@ func GetAuthInfo(kubeconfig *Config, userName string) *AuthInfo {
 	}
 	return nil
 }
+
+// SetClusterServer updates the server URL of the cluster referenced by the kube context in the kubeconfig file.
+// The file is updated in place at the yaml node level to preserve the fields unknown to Config and replaced atomically.
+func SetClusterServer(path, kubeContextName, server string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return errors.Wrap(err, "failed to parse the kubeconfig")
+	}
+	if len(doc.Content) == 0 {
+		return errors.Errorf("context %q missing in the kubeconfig", kubeContextName)
+	}
+
+	contextNode := findNamedItem(mappingValue(doc.Content[0], "contexts"), kubeContextName)
+	if contextNode == nil {
+		return errors.Errorf("context %q missing in the kubeconfig", kubeContextName)
+	}
+	clusterRef := mappingValue(mappingValue(contextNode, "context"), "cluster")
+	if clusterRef == nil {
+		return errors.Errorf("context %q does not reference a cluster in the kubeconfig", kubeContextName)
+	}
+	clusterNode := mappingValue(findNamedItem(mappingValue(doc.Content[0], "clusters"), clusterRef.Value), "cluster")
+	if clusterNode == nil {
+		return errors.Errorf("cluster %q missing in the kubeconfig", clusterRef.Value)
+	}
+	if serverNode := mappingValue(clusterNode, "server"); serverNode != nil {
+		serverNode.Value = server
+	} else {
+		clusterNode.Content = append(clusterNode.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: "server"},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: server})
+	}
+
+	out, err := yaml.Marshal(&doc)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal the kubeconfig")
+	}
+	return writeFileAtomic(path, out)
+}
+
+// mappingValue returns the value node of the key in the mapping node, nil if not found
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// findNamedItem returns the item of the sequence node with the name, nil if not found
+func findNamedItem(node *yaml.Node, name string) *yaml.Node {
+	if node == nil || node.Kind != yaml.SequenceNode {
+		return nil
+	}
+	for _, item := range node.Content {
+		if nameNode := mappingValue(item, "name"); nameNode != nil && nameNode.Value == name {
+			return item
+		}
+	}
+	return nil
+}
@@ -76,7 +76,7 @@ contexts:
 current-context: foo-context
 `
 
-	testKubeconfiFilePath := "../../../fakes/config/kubeconfig-1.yaml"
+	testKubeconfiFilePath := "../../fakes/config/kubeconfig-1.yaml"
 	kubeconfigFilePath, err := os.CreateTemp("", "config")
 	assert.NoError(t, err)
 	copyFile(t, testKubeconfiFilePath, kubeconfigFilePath.Name())
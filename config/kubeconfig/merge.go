@@ -0,0 +1,272 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package kubeconfig
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+type mergeOptions struct {
+	setCurrentContext bool
+}
+
+type MergeOptions func(o *mergeOptions)
+
+// WithSetCurrentContext sets the current context of the destination to the current context of the merged kubeconfig
+func WithSetCurrentContext() MergeOptions {
+	return func(o *mergeOptions) {
+		o.setCurrentContext = true
+	}
+}
+
+// Merge adds the clusters, users and contexts of src to dst. The entries of dst with the same names are replaced.
+func Merge(dst, src *Config, opts ...MergeOptions) {
+	options := &mergeOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	for _, cluster := range src.Clusters {
+		if existing := GetCluster(dst, cluster.Name); existing != nil {
+			*existing = *cluster
+			continue
+		}
+		dst.Clusters = append(dst.Clusters, cluster)
+	}
+	for _, authInfo := range src.AuthInfos {
+		if existing := GetAuthInfo(dst, authInfo.Name); existing != nil {
+			*existing = *authInfo
+			continue
+		}
+		dst.AuthInfos = append(dst.AuthInfos, authInfo)
+	}
+	for _, context := range src.Contexts {
+		if existing := GetContext(dst, context.Name); existing != nil {
+			*existing = *context
+			continue
+		}
+		dst.Contexts = append(dst.Contexts, context)
+	}
+	if options.setCurrentContext && src.CurrentContext != "" {
+		dst.CurrentContext = src.CurrentContext
+	}
+}
+
+// MergeIntoFile merges the kubeconfig into the kubeconfig file, e.g. DefaultKubeConfigPath, and writes it back
+// atomically. The file is created if it does not exist. The file is merged at the yaml node level, so that the
+// fields unknown to Config and the entries not replaced are preserved.
+func MergeIntoFile(src *Config, path string, opts ...MergeOptions) error {
+	options := &mergeOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	doc, err := readKubeConfigNodeOrEmpty(path)
+	if err != nil {
+		return err
+	}
+	root := doc.Content[0]
+	for _, cluster := range src.Clusters {
+		if err := setNamedItem(root, "clusters", cluster.Name, cluster); err != nil {
+			return err
+		}
+	}
+	for _, authInfo := range src.AuthInfos {
+		if err := setNamedItem(root, "users", authInfo.Name, authInfo); err != nil {
+			return err
+		}
+	}
+	for _, context := range src.Contexts {
+		if err := setNamedItem(root, "contexts", context.Name, context); err != nil {
+			return err
+		}
+	}
+	if options.setCurrentContext && src.CurrentContext != "" {
+		setScalar(root, "current-context", src.CurrentContext)
+	}
+	return writeKubeConfigNode(doc, path)
+}
+
+// DeleteContext removes the context from the kubeconfig and unsets the current context if it referenced it.
+// It returns false if the context does not exist.
+func DeleteContext(kubeconfig *Config, kubeContextName string) bool {
+	for idx := range kubeconfig.Contexts {
+		if kubeconfig.Contexts[idx].Name == kubeContextName {
+			kubeconfig.Contexts = append(kubeconfig.Contexts[:idx], kubeconfig.Contexts[idx+1:]...)
+			if kubeconfig.CurrentContext == kubeContextName {
+				kubeconfig.CurrentContext = ""
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// DeleteCluster removes the cluster from the kubeconfig. It returns false if the cluster does not exist.
+func DeleteCluster(kubeconfig *Config, clusterName string) bool {
+	for idx := range kubeconfig.Clusters {
+		if kubeconfig.Clusters[idx].Name == clusterName {
+			kubeconfig.Clusters = append(kubeconfig.Clusters[:idx], kubeconfig.Clusters[idx+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// DeleteAuthInfo removes the user from the kubeconfig. It returns false if the user does not exist.
+func DeleteAuthInfo(kubeconfig *Config, userName string) bool {
+	for idx := range kubeconfig.AuthInfos {
+		if kubeconfig.AuthInfos[idx].Name == userName {
+			kubeconfig.AuthInfos = append(kubeconfig.AuthInfos[:idx], kubeconfig.AuthInfos[idx+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// RemoveContextFromFile removes the context from the kubeconfig file, along with its cluster and user
+// if no other context references them, and writes it back atomically. The fields unknown to Config are preserved.
+func RemoveContextFromFile(path, kubeContextName string) error {
+	doc, err := readKubeConfigNode(path)
+	if err != nil {
+		return err
+	}
+	root := doc.Content[0]
+	contextNode := findNamedItem(mappingValue(root, "contexts"), kubeContextName)
+	if contextNode == nil {
+		return errors.Errorf("context %q missing in the kubeconfig", kubeContextName)
+	}
+	clusterName := scalarValue(mappingValue(mappingValue(contextNode, "context"), "cluster"))
+	userName := scalarValue(mappingValue(mappingValue(contextNode, "context"), "user"))
+	removeNamedItem(root, "contexts", kubeContextName)
+	if currentContext := mappingValue(root, "current-context"); currentContext != nil && currentContext.Value == kubeContextName {
+		currentContext.Value = ""
+	}
+
+	clusterInUse, userInUse := false, false
+	if contextsNode := mappingValue(root, "contexts"); contextsNode != nil {
+		for _, c := range contextsNode.Content {
+			clusterInUse = clusterInUse || scalarValue(mappingValue(mappingValue(c, "context"), "cluster")) == clusterName
+			userInUse = userInUse || scalarValue(mappingValue(mappingValue(c, "context"), "user")) == userName
+		}
+	}
+	if !clusterInUse {
+		removeNamedItem(root, "clusters", clusterName)
+	}
+	if !userInUse {
+		removeNamedItem(root, "users", userName)
+	}
+	return writeKubeConfigNode(doc, path)
+}
+
+// readKubeConfigNode reads the yaml document of the kubeconfig file
+func readKubeConfigNode(path string) (*yaml.Node, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	doc := &yaml.Node{}
+	if err := yaml.Unmarshal(data, doc); err != nil {
+		return nil, errors.Wrap(err, "failed to parse the kubeconfig")
+	}
+	if len(doc.Content) == 0 {
+		return emptyKubeConfigNode(), nil
+	}
+	if doc.Content[0].Kind != yaml.MappingNode {
+		return nil, errors.New("failed to parse the kubeconfig: expected a mapping")
+	}
+	return doc, nil
+}
+
+// readKubeConfigNodeOrEmpty reads the yaml document of the kubeconfig file, returning an empty kubeconfig
+// if the file does not exist
+func readKubeConfigNodeOrEmpty(path string) (*yaml.Node, error) {
+	doc, err := readKubeConfigNode(path)
+	if os.IsNotExist(err) {
+		return emptyKubeConfigNode(), nil
+	}
+	return doc, err
+}
+
+// emptyKubeConfigNode returns the yaml document of an empty kubeconfig
+func emptyKubeConfigNode() *yaml.Node {
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	setScalar(root, "apiVersion", "v1")
+	setScalar(root, "kind", "Config")
+	return &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{root}}
+}
+
+// writeKubeConfigNode writes the yaml document of the kubeconfig to the file atomically, creating the
+// parent directory if needed
+func writeKubeConfigNode(doc *yaml.Node, path string) error {
+	data, err := yaml.Marshal(doc)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal the kubeconfig")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+// setNamedItem replaces the item with the name of the sequence of the key with the value, or appends it if missing
+func setNamedItem(root *yaml.Node, key, name string, value interface{}) error {
+	itemNode := &yaml.Node{}
+	if err := itemNode.Encode(value); err != nil {
+		return errors.Wrapf(err, "failed to encode %s %q", key, name)
+	}
+	sequenceNode := mappingValue(root, key)
+	if sequenceNode == nil {
+		sequenceNode = &yaml.Node{Kind: yaml.SequenceNode}
+		root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, sequenceNode)
+	} else if sequenceNode.Kind != yaml.SequenceNode {
+		// e.g. clusters: null
+		*sequenceNode = yaml.Node{Kind: yaml.SequenceNode}
+	}
+	for i, item := range sequenceNode.Content {
+		if nameNode := mappingValue(item, "name"); nameNode != nil && nameNode.Value == name {
+			sequenceNode.Content[i] = itemNode
+			return nil
+		}
+	}
+	sequenceNode.Content = append(sequenceNode.Content, itemNode)
+	return nil
+}
+
+// removeNamedItem removes the item with the name from the sequence of the key, if any
+func removeNamedItem(root *yaml.Node, key, name string) {
+	sequenceNode := mappingValue(root, key)
+	if sequenceNode == nil || sequenceNode.Kind != yaml.SequenceNode {
+		return
+	}
+	for i, item := range sequenceNode.Content {
+		if nameNode := mappingValue(item, "name"); nameNode != nil && nameNode.Value == name {
+			sequenceNode.Content = append(sequenceNode.Content[:i], sequenceNode.Content[i+1:]...)
+			return
+		}
+	}
+}
+
+// setScalar sets the string value of the key of the mapping node, adding the key if missing
+func setScalar(node *yaml.Node, key, value string) {
+	if valueNode := mappingValue(node, key); valueNode != nil {
+		*valueNode = yaml.Node{Kind: yaml.ScalarNode, Value: value}
+		return
+	}
+	node.Content = append(node.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Value: key},
+		&yaml.Node{Kind: yaml.ScalarNode, Value: value})
+}
+
+// scalarValue returns the value of the scalar node, empty if nil
+func scalarValue(node *yaml.Node) string {
+	if node == nil {
+		return ""
+	}
+	return node.Value
+}
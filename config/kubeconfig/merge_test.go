@@ -0,0 +1,168 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package kubeconfig
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeIntoFileAndRemoveContext(t *testing.T) {
+	dir, err := os.MkdirTemp("", "kubeconfig")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, ".kube", "config")
+	copyFile(t, "../../fakes/config/kubeconfig-1.yaml", filepath.Join(dir, "source"))
+	source, err := ReadKubeConfig(filepath.Join(dir, "source"))
+	assert.NoError(t, err)
+	generated, err := MinifyKubeConfig(source, "tanzu-cli-mytanzu")
+	assert.NoError(t, err)
+
+	// Merging into a missing file creates it
+	err = MergeIntoFile(generated, path)
+	assert.NoError(t, err)
+	kc, err := ReadKubeConfig(path)
+	assert.NoError(t, err)
+	assert.Len(t, kc.Contexts, 1)
+	assert.Empty(t, kc.CurrentContext)
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	// Merging replaces the entries with the same names and keeps the others
+	other := &Config{
+		Clusters:  []*Cluster{{Name: "other-cluster"}},
+		AuthInfos: []*AuthInfo{{Name: "other-user", AuthInfo: map[string]interface{}{"token": "abc"}}},
+		Contexts:  []*Context{{Name: "other"}},
+	}
+	other.Contexts[0].Context.Cluster = "other-cluster"
+	other.Contexts[0].Context.AuthInfo = "other-user"
+	other.CurrentContext = "other"
+	assert.NoError(t, MergeIntoFile(other, path, WithSetCurrentContext()))
+
+	generated.Clusters[0].Cluster.Server = "https://updated"
+	assert.NoError(t, MergeIntoFile(generated, path))
+
+	kc, err = ReadKubeConfig(path)
+	assert.NoError(t, err)
+	assert.Len(t, kc.Contexts, 2)
+	assert.Len(t, kc.Clusters, 2)
+	assert.Len(t, kc.AuthInfos, 2)
+	assert.Equal(t, "other", kc.CurrentContext)
+	assert.Equal(t, "https://updated", GetCluster(kc, "tanzu-cli-mytanzu/current").Cluster.Server)
+
+	// Removing a context removes its cluster and user and unsets the current context
+	assert.NoError(t, RemoveContextFromFile(path, "other"))
+	kc, err = ReadKubeConfig(path)
+	assert.NoError(t, err)
+	assert.Len(t, kc.Contexts, 1)
+	assert.Nil(t, GetCluster(kc, "other-cluster"))
+	assert.Nil(t, GetAuthInfo(kc, "other-user"))
+	assert.Empty(t, kc.CurrentContext)
+
+	err = RemoveContextFromFile(path, "other")
+	assert.EqualError(t, err, `context "other" missing in the kubeconfig`)
+}
+
+func TestSetClusterServerPreservesUnknownFields(t *testing.T) {
+	dir, err := os.MkdirTemp("", "kubeconfig")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "config")
+	content := `apiVersion: v1
+kind: Config
+clusters:
+- name: cluster1
+  cluster:
+    server: https://old
+    extensions:
+    - name: ext
+      extension:
+        key: value
+contexts:
+- name: ctx1
+  context:
+    cluster: cluster1
+    user: user1
+users:
+- name: user1
+  user:
+    token: abc
+current-context: ctx1
+`
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	assert.NoError(t, SetClusterServer(path, "ctx1", "https://new"))
+	kc, err := ReadKubeConfig(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://new", GetCluster(kc, "cluster1").Cluster.Server)
+	assert.Len(t, GetCluster(kc, "cluster1").Cluster.Extensions, 1)
+
+	err = SetClusterServer(path, "missing", "https://new")
+	assert.EqualError(t, err, `context "missing" missing in the kubeconfig`)
+}
+
+func TestMergeIntoFilePreservesUnknownFields(t *testing.T) {
+	dir, err := os.MkdirTemp("", "kubeconfig")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "config")
+	content := `apiVersion: v1
+kind: Config
+preferences:
+  colors: true
+extensions:
+- name: ext
+  extension:
+    key: value
+clusters:
+- name: cluster1
+  cluster:
+    server: https://cluster1
+    proxy-url: https://proxy
+contexts:
+- name: ctx1
+  context:
+    cluster: cluster1
+    user: user1
+    namespace: ns1
+users:
+- name: user1
+  user:
+    token: abc
+current-context: ctx1
+`
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+
+	other := &Config{
+		Clusters:  []*Cluster{{Name: "other-cluster"}},
+		AuthInfos: []*AuthInfo{{Name: "other-user", AuthInfo: map[string]interface{}{"token": "def"}}},
+		Contexts:  []*Context{{Name: "other"}},
+	}
+	other.Contexts[0].Context.Cluster = "other-cluster"
+	other.Contexts[0].Context.AuthInfo = "other-user"
+	assert.NoError(t, MergeIntoFile(other, path))
+	assert.NoError(t, RemoveContextFromFile(path, "other"))
+
+	// The fields unknown to Config and the permissions of the file are preserved
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "preferences:\n    colors: true")
+	assert.Contains(t, string(data), "proxy-url: https://proxy")
+	assert.Contains(t, string(data), "namespace: ns1")
+	assert.Contains(t, string(data), "key: value")
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o644), info.Mode().Perm())
+	kc, err := ReadKubeConfig(path)
+	assert.NoError(t, err)
+	assert.Len(t, kc.Contexts, 1)
+	assert.Equal(t, "ctx1", kc.CurrentContext)
+}
@@ -9,7 +9,7 @@ package kubeconfig
 
 // Config holds the information needed to establish connection to remote kubernetes clusters as a given user
 //
-// (Note: !!modified Clusters,AuthInfos,Contexts from map to array to fit the Yaml marshaling and removed the extensions field)
+// (Note: !!modified Clusters,AuthInfos,Contexts from map to array to fit the Yaml marshaling and changed the extensions to an opaque value)
 type Config struct {
 	Kind       string `json:"kind,omitempty" yaml:"kind,omitempty"`
 	APIVersion string `json:"apiVersion,omitempty" yaml:"apiVersion,omitempty"`
@@ -23,11 +23,25 @@ type Config struct {
 	Contexts []*Context `json:"contexts" yaml:"contexts"`
 	// CurrentContext is the name of the context that you would like to use by default
 	CurrentContext string `json:"current-context" yaml:"current-context"`
+	// Extensions holds additional information. This is useful for extenders so that reads and writes don't clobber unknown fields
+	// +optional
+	Extensions []NamedExtension `json:"extensions,omitempty" yaml:"extensions,omitempty"`
 }
 
 type Preferences struct {
 	// +optional
 	Colors bool `json:"colors,omitempty" yaml:"colors,omitempty"`
+	// Extensions holds additional information. This is useful for extenders so that reads and writes don't clobber unknown fields
+	// +optional
+	Extensions []NamedExtension `json:"extensions,omitempty" yaml:"extensions,omitempty"`
+}
+
+// NamedExtension relates nicknames to extension information
+type NamedExtension struct {
+	// Name is the nickname for this Extension
+	Name string `json:"name" yaml:"name"`
+	// Extension holds the extension information
+	Extension interface{} `json:"extension" yaml:"extension"`
 }
 
 // Cluster contains information about how to communicate with a kubernetes cluster
@@ -64,6 +78,9 @@ type Cluster struct {
 		// compression (server-side) and decompression (client-side): https://github.com/kubernetes/kubernetes/issues/112296.
 		// +optional
 		DisableCompression bool `json:"disable-compression,omitempty" yaml:"disable-compression,omitempty"`
+		// Extensions holds additional information. This is useful for extenders so that reads and writes don't clobber unknown fields
+		// +optional
+		Extensions []NamedExtension `json:"extensions,omitempty" yaml:"extensions,omitempty"`
 	} `json:"cluster" yaml:"cluster"`
 }
 
@@ -85,5 +102,8 @@ type Context struct {
 		// Namespace is the default namespace to use on unspecified requests
 		// +optional
 		Namespace string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+		// Extensions holds additional information. This is useful for extenders so that reads and writes don't clobber unknown fields
+		// +optional
+		Extensions []NamedExtension `json:"extensions,omitempty" yaml:"extensions,omitempty"`
 	} `json:"context" yaml:"context"`
 }
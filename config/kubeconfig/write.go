@@ -0,0 +1,67 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package kubeconfig
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+
+	"github.com/vmware-tanzu/tanzu-plugin-runtime/config/internal/atomicfile"
+)
+
+// RecommendedHomeDir and RecommendedFileName compose the default kubeconfig path ~/.kube/config
+const (
+	RecommendedHomeDir  = ".kube"
+	RecommendedFileName = "config"
+	// EnvKubeConfig is the environment variable listing the kubeconfig files
+	EnvKubeConfig = "KUBECONFIG"
+)
+
+// DefaultKubeConfigPath returns the kubeconfig file that kubectl writes to, the first file
+// of the KUBECONFIG environment variable if set, ~/.kube/config otherwise
+func DefaultKubeConfigPath() (string, error) {
+	if paths := filepath.SplitList(os.Getenv(EnvKubeConfig)); len(paths) != 0 {
+		for _, path := range paths {
+			if path != "" {
+				return path, nil
+			}
+		}
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", errors.Wrap(err, "could not locate the home directory")
+	}
+	return filepath.Join(home, RecommendedHomeDir, RecommendedFileName), nil
+}
+
+// WriteKubeConfig writes the kubeconfig to the file atomically, creating the parent directory if needed.
+// The permissions of an existing file are preserved.
+func WriteKubeConfig(kubeconfig *Config, path string) error {
+	if kubeconfig.Kind == "" {
+		kubeconfig.Kind = "Config"
+	}
+	if kubeconfig.APIVersion == "" {
+		kubeconfig.APIVersion = "v1"
+	}
+	data, err := yaml.Marshal(kubeconfig)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal the kubeconfig")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+// writeFileAtomic replaces the content of the file atomically, preserving the permissions of an existing file
+func writeFileAtomic(path string, data []byte) error {
+	perm := os.FileMode(0o600)
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	}
+	return atomicfile.WriteFile(path, data, perm)
+}
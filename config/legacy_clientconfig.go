@@ -24,7 +24,7 @@ func CopyLegacyConfigDir() error {
 	if err != nil {
 		return err
 	}
-	newPath, err := LocalDir()
+	newPath, err := baseLocalDir()
 	if err != nil {
 		return err
 	}
@@ -60,6 +60,11 @@ func storeConfigToLegacyDir(data []byte) {
 		}
 	}()
 
+	// The legacy location only mirrors the default profile
+	profile, err := ActiveProfile()
+	if err != nil || profile != DefaultProfileName {
+		return
+	}
 	legacyDir, err = legacyLocalDir()
 	if err != nil {
 		return
@@ -12,7 +12,9 @@ import (
 	configtypes "github.com/vmware-tanzu/tanzu-plugin-runtime/config/types"
 )
 
-// GetClientConfig retrieves the config from the local directory with file lock
+// GetClientConfig retrieves the config from the local directory with file lock.
+// The features and env variables overridden by the TANZU_CONFIG_FEATURE_<PLUGIN>_<KEY> and
+// TANZU_CONFIG_ENV_<KEY> environment variables are merged into the config.
 func GetClientConfig() (cfg *configtypes.ClientConfig, err error) {
 	// Retrieve client config node
 	node, err := getClientConfigNode()
@@ -24,6 +26,7 @@ func GetClientConfig() (cfg *configtypes.ClientConfig, err error) {
 	if err != nil {
 		return nil, err
 	}
+	getConfigOverlay().apply(cfg)
 
 	return cfg, nil
 }
@@ -45,18 +48,24 @@ func GetClientConfigNoLock() (cfg *configtypes.ClientConfig, err error) {
 // StoreClientConfig stores the config in the local directory.
 // Make sure to Acquire and Release tanzu lock when reading/writing to the
 // tanzu client configuration
+// The features and env variables still set to the values of their environment variable overrides are not stored.
 // Deprecated: StoreClientConfig is deprecated. Avoid using this method for Delete operations. Use New Config API methods.
 func StoreClientConfig(cfg *configtypes.ClientConfig) error {
 	// new plugins would be setting only contexts, so populate servers for backwards compatibility
 	populateServers(cfg)
-	// old plugins would be setting only servers, so populate contexts for forwards compatibility
-	PopulateContexts(cfg)
 
 	node, err := getClientConfigNodeNoLock()
 	if err != nil {
 		return err
 	}
 
+	// Never persist the environment variable overrides merged by GetClientConfig
+	persisted, err := convertNodeToClientConfig(node)
+	if err != nil {
+		return err
+	}
+	cfg = getConfigOverlay().strip(cfg, persisted)
+
 	err = setServers(node, cfg.KnownServers)
 	if err != nil {
 		return err
@@ -79,6 +88,11 @@ func StoreClientConfig(cfg *configtypes.ClientConfig) error {
 	if err != nil {
 		return err
 	}
+	// old plugins would be setting only servers, so convert them to contexts as the config migrations do
+	err = migratePopulateContexts(node)
+	if err != nil {
+		return err
+	}
 	return persistConfig(node)
 }
 
@@ -154,7 +154,8 @@ servers:
 current: test-mc
 `
 
-	expectedCfg2 := `contexts:
+	expectedCfg2 := `apiVersion: config.tanzu.vmware.com/v1alpha3
+contexts:
     - name: test-mc
       target: kubernetes
       group: one
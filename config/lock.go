@@ -4,6 +4,7 @@
 package config
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -12,81 +13,354 @@ import (
 
 	"github.com/juju/fslock"
 	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
 )
 
 const (
 	LocalTanzuFileLock = ".tanzu.lock"
 	// DefaultLockTimeout is the default time waiting on the filelock
 	DefaultLockTimeout = 10 * time.Minute
+
+	// lockOwnerFileSuffix is the suffix of the file, next to the lock file, recording the lock holder
+	lockOwnerFileSuffix = ".owner"
 )
 
-var tanzuConfigLockFile string
+var (
+	// lockRetryInterval is the interval between two attempts to acquire a file lock
+	lockRetryInterval = 100 * time.Millisecond
+	// staleLockGracePeriod is the time a lock held by a dead process is observed before it is reclaimed
+	staleLockGracePeriod = 2 * time.Second
+)
 
-// tanzuConfigLock used as a static lock variable that stores fslock
-// This is used for interprocess locking of the config file
-var tanzuConfigLock *fslock.Lock
+// Unlocker releases a lock acquired with one of the context aware lock functions
+type Unlocker interface {
+	Unlock() error
+}
 
-// mutex is used to handle the locking behavior between concurrent calls
-// within the existing process trying to acquire the lock
-var mutex sync.Mutex
+// LockHolder describes the process holding a tanzu lock file
+type LockHolder struct {
+	// PID of the process holding the lock
+	PID int `json:"pid" yaml:"pid"`
+	// Host on which the process holding the lock is running
+	Host string `json:"host" yaml:"host"`
+	// Command of the process holding the lock
+	Command string `json:"command,omitempty" yaml:"command,omitempty"`
+	// StartTime is the time at which the lock was acquired
+	StartTime time.Time `json:"startTime" yaml:"startTime"`
+	// LockFileID identifies the lock file locked by the holder, the stale locks are only
+	// reclaimed when the record was written by the holder of the current lock file
+	LockFileID string `json:"lockFileID,omitempty" yaml:"lockFileID,omitempty"`
+}
 
-// AcquireTanzuConfigLock tries to acquire lock to update tanzu config file with timeout
-func AcquireTanzuConfigLock() {
-	var err error
+func (h *LockHolder) String() string {
+	return fmt.Sprintf("pid %d (%s) on host %q since %s", h.PID, h.Command, h.Host, h.StartTime.Format(time.RFC3339))
+}
 
-	if tanzuConfigLockFile == "" {
-		path, err := ClientConfigPath()
-		if err != nil {
-			panic(fmt.Sprintf("cannot get config path while acquiring lock on tanzu config file, reason: %v", err))
-		}
-		tanzuConfigLockFile = filepath.Join(filepath.Dir(path), LocalTanzuFileLock)
-	}
+// LockTimeoutError is returned when a lock could not be acquired before the context is done
+type LockTimeoutError struct {
+	// LockFile is the path of the lock file
+	LockFile string
+	// Holder is the process holding the lock, nil if unknown
+	Holder *LockHolder
+	// Err is the error of the context
+	Err error
+}
 
-	// using fslock to handle interprocess locking
-	lock, err := getFileLockWithTimeOut(tanzuConfigLockFile, DefaultLockTimeout)
-	if err != nil {
-		panic(fmt.Sprintf("cannot acquire lock for tanzu config file, reason: %v", err))
+func (e *LockTimeoutError) Error() string {
+	if e.Holder == nil {
+		return fmt.Sprintf("timed out waiting for lock %s: %v", e.LockFile, e.Err)
 	}
+	return fmt.Sprintf("timed out waiting for lock %s held by %s: %v", e.LockFile, e.Holder, e.Err)
+}
 
-	// Lock the mutex to prevent concurrent calls to acquire and configure the tanzuConfigLock
-	mutex.Lock()
-	tanzuConfigLock = lock
+func (e *LockTimeoutError) Unwrap() error {
+	return e.Err
+}
 
-	// Get lock on config-ng.yaml
-	AcquireTanzuConfigNextGenLock()
+// tanzuLock is an interprocess file lock that is also serialized across the goroutines of the process
+type tanzuLock struct {
+	// description of the file protected by the lock used in error messages
+	description string
+	// lockFileGetter returns the path of the lock file. It is resolved on every acquisition
+	// as the path depends on the active config profile.
+	lockFileGetter func() (string, error)
 }
 
-// ReleaseTanzuConfigLock releases the lock if the tanzuConfigLock was acquired
-func ReleaseTanzuConfigLock() {
-	if tanzuConfigLock == nil {
-		return
+func newTanzuLock(description string, lockFileGetter func() (string, error)) *tanzuLock {
+	return &tanzuLock{
+		description:    description,
+		lockFileGetter: lockFileGetter,
 	}
-	if errUnlock := tanzuConfigLock.Unlock(); errUnlock != nil {
-		panic(fmt.Sprintf("cannot release lock for tanzu config file, reason: %v", errUnlock))
+}
+
+var (
+	// lockSemaphores serialize the concurrent acquisitions of a lock file within the process, keyed by lock file
+	lockSemaphores   = map[string]chan struct{}{}
+	lockSemaphoresMu sync.Mutex
+)
+
+// lockSemaphore returns the semaphore of the lock file
+func lockSemaphore(lockFile string) chan struct{} {
+	lockSemaphoresMu.Lock()
+	defer lockSemaphoresMu.Unlock()
+	sem, ok := lockSemaphores[lockFile]
+	if !ok {
+		sem = make(chan struct{}, 1)
+		lockSemaphores[lockFile] = sem
 	}
+	return sem
+}
 
-	tanzuConfigLock = nil
-	// Unlock the mutex to allow other concurrent calls to acquire and configure the tanzuConfigLock
-	mutex.Unlock()
+// tanzuLockUnlocker releases an acquired tanzuLock
+type tanzuLockUnlocker struct {
+	description string
+	lockFile    string
+	sem         chan struct{}
+	fileLock    *fslock.Lock
+	released    bool
+}
+
+func (u *tanzuLockUnlocker) Unlock() error {
+	if u.released {
+		return nil
+	}
+	u.released = true
+	// Unlock the semaphore to allow other concurrent calls to acquire the lock
+	defer func() { <-u.sem }()
 
-	// Release lock on config-ng.yaml
-	ReleaseTanzuConfigNextGenLock()
+	_ = os.Remove(u.lockFile + lockOwnerFileSuffix)
+	if err := u.fileLock.Unlock(); err != nil {
+		return errors.Wrapf(err, "cannot release lock for %s", u.description)
+	}
+	return nil
 }
 
-// getFileLockWithTimeOut returns a file lock with timeout
-func getFileLockWithTimeOut(lockPath string, lockDuration time.Duration) (*fslock.Lock, error) {
-	dir := filepath.Dir(lockPath)
+// acquire tries to acquire the lock until the context is done.
+// The lock held by a process that is no longer running on this host is reclaimed.
+func (l *tanzuLock) acquire(ctx context.Context) (Unlocker, error) {
+	lockFile, err := l.lockFileGetter()
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot get lock file path while acquiring lock on %s", l.description)
+	}
+	if lockFile, err = filepath.Abs(lockFile); err != nil {
+		return nil, err
+	}
 
+	// Lock the semaphore to prevent concurrent calls to acquire the lock
+	sem := lockSemaphore(lockFile)
+	select {
+	case sem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, &LockTimeoutError{LockFile: lockFile, Err: ctx.Err()}
+	}
+
+	fileLock, err := l.acquireFileLock(ctx, lockFile)
+	if err != nil {
+		<-sem
+		return nil, err
+	}
+	return &tanzuLockUnlocker{description: l.description, lockFile: lockFile, sem: sem, fileLock: fileLock}, nil
+}
+
+func (l *tanzuLock) acquireFileLock(ctx context.Context, lockFile string) (*fslock.Lock, error) {
+	dir := filepath.Dir(lockFile)
 	if _, err := os.Stat(dir); os.IsNotExist(err) {
 		if err := os.MkdirAll(dir, 0o700); err != nil {
 			return nil, err
 		}
 	}
 
-	lock := fslock.New(lockPath)
+	var staleHolder *LockHolder
+	var staleSince time.Time
+	for {
+		// using fslock to handle interprocess locking
+		fileLock := fslock.New(lockFile)
+		err := fileLock.TryLock()
+		if err == nil {
+			// Without a holder record the lock could be reclaimed based on a leftover record
+			if err := writeLockHolder(lockFile); err != nil {
+				_ = os.Remove(lockFile + lockOwnerFileSuffix)
+				_ = fileLock.Unlock()
+				return nil, errors.Wrapf(err, "cannot record the holder of the lock for %s", l.description)
+			}
+			return fileLock, nil
+		}
+		if err != fslock.ErrLocked {
+			return nil, errors.Wrapf(err, "cannot acquire lock for %s", l.description)
+		}
+
+		holder := readLockHolder(lockFile)
+		if holder != nil && holder.isStale() && holder.ownsLockFile(lockFile) {
+			if staleHolder == nil || *staleHolder != *holder {
+				staleHolder, staleSince = holder, time.Now()
+			} else if time.Since(staleSince) >= staleLockGracePeriod {
+				// The holder is gone but the lock is still held, e.g. by a child process that
+				// inherited the lock file descriptor. Reclaim the lock with a new lock file.
+				reclaimStaleLockFile(lockFile, staleHolder)
+				staleHolder = nil
+				continue
+			}
+		} else {
+			staleHolder = nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, &LockTimeoutError{LockFile: lockFile, Holder: holder, Err: ctx.Err()}
+		case <-time.After(lockRetryInterval):
+		}
+	}
+}
+
+// reclaimStaleLockFile removes the lock file of the stale holder. Another process may reclaim the
+// same lock concurrently, so the lock file is first moved aside and only removed if it is still the
+// lock file of the stale holder, otherwise the lock file created by the other process is restored.
+func reclaimStaleLockFile(lockFile string, stale *LockHolder) {
+	aside := fmt.Sprintf("%s.stale-%d-%d", lockFile, os.Getpid(), time.Now().UnixNano())
+	if err := os.Rename(lockFile, aside); err != nil {
+		return
+	}
+	if id, err := lockFileID(aside); err != nil || id != stale.LockFileID {
+		// Linking fails if yet another lock file was created meanwhile, which is then kept
+		_ = os.Link(aside, lockFile)
+		_ = os.Remove(aside)
+		return
+	}
+	_ = os.Remove(aside)
+	if holder := readLockHolder(lockFile); holder != nil && holder.LockFileID == stale.LockFileID {
+		_ = os.Remove(lockFile + lockOwnerFileSuffix)
+	}
+}
 
-	if err := lock.LockWithTimeout(lockDuration); err != nil {
-		return nil, errors.Wrap(err, "failed to acquire a lock with timeout")
+// isStale returns true if the holder is a process of this host that is no longer running
+func (h *LockHolder) isStale() bool {
+	host, err := os.Hostname()
+	if err != nil || host != h.Host || h.PID <= 0 {
+		return false
+	}
+	return !processExists(h.PID)
+}
+
+// ownsLockFile returns true if the holder record was written by the holder of the current lock file
+func (h *LockHolder) ownsLockFile(lockFile string) bool {
+	if h.LockFileID == "" {
+		return false
+	}
+	id, err := lockFileID(lockFile)
+	return err == nil && id == h.LockFileID
+}
+
+// writeLockHolder records the current process as the holder of the lock file
+func writeLockHolder(lockFile string) error {
+	id, err := lockFileID(lockFile)
+	if err != nil {
+		return err
+	}
+	host, _ := os.Hostname()
+	holder := &LockHolder{
+		PID:        os.Getpid(),
+		Host:       host,
+		Command:    filepath.Base(os.Args[0]),
+		StartTime:  time.Now(),
+		LockFileID: id,
+	}
+	data, err := yaml.Marshal(holder)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(lockFile+lockOwnerFileSuffix, data, 0600)
+}
+
+// readLockHolder returns the recorded holder of the lock file, nil if unknown
+func readLockHolder(lockFile string) *LockHolder {
+	data, err := os.ReadFile(lockFile + lockOwnerFileSuffix)
+	if err != nil {
+		return nil
+	}
+	holder := &LockHolder{}
+	if err := yaml.Unmarshal(data, holder); err != nil || holder.PID == 0 {
+		return nil
+	}
+	return holder
+}
+
+// multiUnlocker releases a set of locks in order
+type multiUnlocker []Unlocker
+
+func (m multiUnlocker) Unlock() error {
+	var errs []error
+	for _, u := range m {
+		if err := u.Unlock(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) != 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// AcquireFileLockContext acquires the interprocess lock on the given lock file, waiting until the
+// context is done. The lock is also serialized across the goroutines of the process and a lock held
+// by a process that is no longer running is reclaimed. The returned Unlocker must be used to release the lock.
+func AcquireFileLockContext(ctx context.Context, lockFile string) (Unlocker, error) {
+	return newTanzuLock(lockFile, func() (string, error) { return lockFile, nil }).acquire(ctx)
+}
+
+// tanzuConfigLock used as a static lock that is used for interprocess locking of the config file
+var tanzuConfigLock = newTanzuLock("tanzu config file", func() (string, error) {
+	path, err := ClientConfigPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(path), LocalTanzuFileLock), nil
+})
+
+// tanzuConfigUnlocker stores the unlocker of the lock acquired with AcquireTanzuConfigLock
+var tanzuConfigUnlocker Unlocker
+
+// AcquireTanzuConfigLockContext acquires the lock to update tanzu config files, config.yaml and
+// config-ng.yaml, waiting until the context is done. A lock held by a process that is no longer
+// running is reclaimed. The returned Unlocker must be used to release the lock.
+func AcquireTanzuConfigLockContext(ctx context.Context) (Unlocker, error) {
+	cfgUnlocker, err := tanzuConfigLock.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Get lock on config-ng.yaml
+	cfgNextGenUnlocker, err := AcquireTanzuConfigNextGenLockContext(ctx)
+	if err != nil {
+		_ = cfgUnlocker.Unlock()
+		return nil, err
+	}
+	return multiUnlocker{cfgUnlocker, cfgNextGenUnlocker}, nil
+}
+
+// acquireTanzuConfigLock acquires the tanzu config lock waiting up to DefaultLockTimeout
+func acquireTanzuConfigLock() (Unlocker, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultLockTimeout)
+	defer cancel()
+	return AcquireTanzuConfigLockContext(ctx)
+}
+
+// AcquireTanzuConfigLock tries to acquire lock to update tanzu config file with timeout.
+// It panics on failure, use AcquireTanzuConfigLockContext to handle the error instead.
+func AcquireTanzuConfigLock() {
+	unlocker, err := acquireTanzuConfigLock()
+	if err != nil {
+		panic(fmt.Sprintf("cannot acquire lock for tanzu config file, reason: %v", err))
+	}
+	tanzuConfigUnlocker = unlocker
+}
+
+// ReleaseTanzuConfigLock releases the lock if the tanzuConfigLock was acquired
+func ReleaseTanzuConfigLock() {
+	if tanzuConfigUnlocker == nil {
+		return
+	}
+	unlocker := tanzuConfigUnlocker
+	tanzuConfigUnlocker = nil
+	if errUnlock := unlocker.Unlock(); errUnlock != nil {
+		panic(fmt.Sprintf("cannot release lock for tanzu config file, reason: %v", errUnlock))
 	}
-	return lock, nil
 }
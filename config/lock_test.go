@@ -0,0 +1,282 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/juju/fslock"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func newTestLock(t *testing.T) (*tanzuLock, func()) {
+	dir, err := os.MkdirTemp("", "tanzu_lock")
+	assert.NoError(t, err)
+	l := newTanzuLock("test file", func() (string, error) {
+		return filepath.Join(dir, ".test.lock"), nil
+	})
+	return l, func() {
+		_ = os.RemoveAll(dir)
+	}
+}
+
+// holdFileLock holds the lock file through another file descriptor recording the holder
+func holdFileLock(t *testing.T, lockFile string, holder *LockHolder) *fslock.Lock {
+	fileLock := fslock.New(lockFile)
+	assert.NoError(t, fileLock.TryLock())
+	if holder.LockFileID == "" {
+		id, err := lockFileID(lockFile)
+		assert.NoError(t, err)
+		holder.LockFileID = id
+	}
+	data, err := yaml.Marshal(holder)
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(lockFile+lockOwnerFileSuffix, data, 0600))
+	return fileLock
+}
+
+func TestTanzuLockAcquireRelease(t *testing.T) {
+	l, cleanup := newTestLock(t)
+	defer cleanup()
+
+	lockFile, err := l.lockFileGetter()
+	assert.NoError(t, err)
+	unlocker, err := l.acquire(context.Background())
+	assert.NoError(t, err)
+
+	holder := readLockHolder(lockFile)
+	assert.NotNil(t, holder)
+	assert.Equal(t, os.Getpid(), holder.PID)
+
+	// Concurrent acquisition within the process honors the context
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = l.acquire(ctx)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+
+	assert.NoError(t, unlocker.Unlock())
+	assert.Nil(t, readLockHolder(lockFile))
+	// Unlocking twice is a no-op
+	assert.NoError(t, unlocker.Unlock())
+
+	unlocker, err = l.acquire(context.Background())
+	assert.NoError(t, err)
+	assert.NoError(t, unlocker.Unlock())
+}
+
+func TestTanzuLockResolvesLockFileOnAcquire(t *testing.T) {
+	dir, err := os.MkdirTemp("", "tanzu_lock")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// The lock file depends on the active profile, e.g. after UseProfile
+	lockFile := filepath.Join(dir, "default", ".test.lock")
+	l := newTanzuLock("test file", func() (string, error) {
+		return lockFile, nil
+	})
+	unlocker, err := l.acquire(context.Background())
+	assert.NoError(t, err)
+	defer unlocker.Unlock()
+
+	lockFile = filepath.Join(dir, "prod", ".test.lock")
+	prodUnlocker, err := l.acquire(context.Background())
+	assert.NoError(t, err)
+	assert.NotNil(t, readLockHolder(lockFile))
+	assert.NoError(t, prodUnlocker.Unlock())
+
+	// The lock of the same file is still serialized across the locks of the process
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = AcquireFileLockContext(ctx, filepath.Join(dir, "default", ".test.lock"))
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+}
+
+func TestTanzuLockTimeoutNamesHolder(t *testing.T) {
+	l, cleanup := newTestLock(t)
+	defer cleanup()
+	lockFile, err := l.lockFileGetter()
+	assert.NoError(t, err)
+
+	host, err := os.Hostname()
+	assert.NoError(t, err)
+	holder := &LockHolder{PID: os.Getpid(), Host: host, Command: "tanzu-plugin", StartTime: time.Now().Truncate(time.Second)}
+	fileLock := holdFileLock(t, lockFile, holder)
+	defer fileLock.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	_, err = l.acquire(ctx)
+
+	var timeoutErr *LockTimeoutError
+	assert.True(t, errors.As(err, &timeoutErr))
+	assert.Equal(t, lockFile, timeoutErr.LockFile)
+	assert.Equal(t, holder.PID, timeoutErr.Holder.PID)
+	assert.Equal(t, "tanzu-plugin", timeoutErr.Holder.Command)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Contains(t, err.Error(), "held by pid")
+}
+
+func TestTanzuLockReclaimStaleLock(t *testing.T) {
+	l, cleanup := newTestLock(t)
+	defer cleanup()
+	lockFile, err := l.lockFileGetter()
+	assert.NoError(t, err)
+
+	gracePeriod := staleLockGracePeriod
+	staleLockGracePeriod = 100 * time.Millisecond
+	defer func() {
+		staleLockGracePeriod = gracePeriod
+	}()
+
+	// Record a holder that is no longer running
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	assert.NoError(t, cmd.Run())
+	host, err := os.Hostname()
+	assert.NoError(t, err)
+	fileLock := holdFileLock(t, lockFile, &LockHolder{PID: cmd.Process.Pid, Host: host, StartTime: time.Now()})
+	defer fileLock.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	unlocker, err := l.acquire(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, os.Getpid(), readLockHolder(lockFile).PID)
+	assert.NoError(t, unlocker.Unlock())
+}
+
+func TestReclaimStaleLockFileKeepsReclaimedLock(t *testing.T) {
+	l, cleanup := newTestLock(t)
+	defer cleanup()
+	lockFile, err := l.lockFileGetter()
+	assert.NoError(t, err)
+
+	// Another process already reclaimed the lock of the stale holder with a new lock file
+	fileLock := holdFileLock(t, lockFile, &LockHolder{PID: os.Getpid(), StartTime: time.Now()})
+	defer fileLock.Unlock()
+	id, err := lockFileID(lockFile)
+	assert.NoError(t, err)
+	reclaimStaleLockFile(lockFile, &LockHolder{PID: os.Getpid(), LockFileID: "0:0"})
+
+	newID, err := lockFileID(lockFile)
+	assert.NoError(t, err)
+	assert.Equal(t, id, newID)
+	assert.FileExists(t, lockFile+lockOwnerFileSuffix)
+	matches, err := filepath.Glob(lockFile + ".stale-*")
+	assert.NoError(t, err)
+	assert.Empty(t, matches)
+
+	// The lock file of the stale holder is removed
+	reclaimStaleLockFile(lockFile, &LockHolder{PID: os.Getpid(), LockFileID: id})
+	assert.NoFileExists(t, lockFile)
+	assert.NoFileExists(t, lockFile+lockOwnerFileSuffix)
+}
+
+func TestTanzuLockDoesNotReclaimWithLeftoverHolder(t *testing.T) {
+	l, cleanup := newTestLock(t)
+	defer cleanup()
+	lockFile, err := l.lockFileGetter()
+	assert.NoError(t, err)
+
+	gracePeriod := staleLockGracePeriod
+	staleLockGracePeriod = 10 * time.Millisecond
+	defer func() {
+		staleLockGracePeriod = gracePeriod
+	}()
+
+	// The lock is held by a live holder while the record names a process that is no longer
+	// running and was written for another lock file
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	assert.NoError(t, cmd.Run())
+	host, err := os.Hostname()
+	assert.NoError(t, err)
+	fileLock := holdFileLock(t, lockFile, &LockHolder{PID: cmd.Process.Pid, Host: host, StartTime: time.Now(), LockFileID: "0:0"})
+	defer fileLock.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	_, err = l.acquire(ctx)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.FileExists(t, lockFile)
+}
+
+func TestTanzuLockReleasedWhenHolderNotRecorded(t *testing.T) {
+	l, cleanup := newTestLock(t)
+	defer cleanup()
+	lockFile, err := l.lockFileGetter()
+	assert.NoError(t, err)
+
+	// The holder record cannot be written over a directory
+	assert.NoError(t, os.MkdirAll(filepath.Join(lockFile+lockOwnerFileSuffix, "dir"), 0o700))
+	_, err = l.acquire(context.Background())
+	assert.ErrorContains(t, err, "cannot record the holder of the lock for test file")
+
+	// The lock was released
+	fileLock := fslock.New(lockFile)
+	assert.NoError(t, fileLock.TryLock())
+	assert.NoError(t, fileLock.Unlock())
+	assert.NoError(t, os.RemoveAll(lockFile+lockOwnerFileSuffix))
+	unlocker, err := l.acquire(context.Background())
+	assert.NoError(t, err)
+	assert.NoError(t, unlocker.Unlock())
+}
+
+func TestUpdateContextCanceled(t *testing.T) {
+	// Setup config data
+	_, cleanUp := setupTestConfig(t, &CfgTestData{})
+
+	defer func() {
+		cleanUp()
+	}()
+
+	unlocker, err := AcquireTanzuConfigLockContext(context.Background())
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = UpdateContext(ctx, func(tx *ConfigTx) error {
+		return tx.SetEnv("env1", "val1")
+	})
+	assert.True(t, errors.Is(err, context.Canceled))
+
+	assert.NoError(t, unlocker.Unlock())
+
+	err = UpdateContext(context.Background(), func(tx *ConfigTx) error {
+		return tx.SetEnv("env1", "val1")
+	})
+	assert.NoError(t, err)
+}
+
+func TestAcquireFileLockContext(t *testing.T) {
+	dir, err := os.MkdirTemp("", "tanzu_lock")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	lockFile := filepath.Join(dir, "nested", ".test.lock")
+
+	unlocker, err := AcquireFileLockContext(context.Background(), lockFile)
+	assert.NoError(t, err)
+
+	// The same lock file is serialized within the process
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = AcquireFileLockContext(ctx, lockFile)
+	var timeoutErr *LockTimeoutError
+	assert.True(t, errors.As(err, &timeoutErr))
+
+	// Another lock file is independent
+	other, err := AcquireFileLockContext(context.Background(), filepath.Join(dir, ".other.lock"))
+	assert.NoError(t, err)
+	assert.NoError(t, other.Unlock())
+
+	assert.NoError(t, unlocker.Unlock())
+	unlocker, err = AcquireFileLockContext(context.Background(), lockFile)
+	assert.NoError(t, err)
+	assert.NoError(t, unlocker.Unlock())
+}
@@ -0,0 +1,37 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !windows
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// processExists returns true if a process with the pid is running
+func processExists(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	err = p.Signal(syscall.Signal(0))
+	// EPERM means the process exists but is owned by another user
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// lockFileID returns the identity of the file, its device and inode numbers
+func lockFileID(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return "", errors.New("cannot get the inode of " + path)
+	}
+	return fmt.Sprintf("%d:%d", st.Dev, st.Ino), nil
+}
@@ -36,8 +36,11 @@ func GetConfigMetadata() (*configtypes.ConfigMetadata, error) {
 // GetConfigMetadataPatchStrategy retrieves patch strategies
 func GetConfigMetadataPatchStrategy() (map[string]string, error) {
 	// Retrieve config metadata node
-	AcquireTanzuMetadataLock()
-	defer ReleaseTanzuMetadataLock()
+	unlock, err := acquireTanzuMetadataLock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock.Unlock()
 	node, err := getMetadataNodeNoLock()
 	if err != nil {
 		return nil, err
@@ -48,8 +51,11 @@ func GetConfigMetadataPatchStrategy() (map[string]string, error) {
 // SetConfigMetadataPatchStrategy add or update patch strategy specified by key-value pair
 func SetConfigMetadataPatchStrategy(key, value string) error {
 	// Retrieve config metadata node
-	AcquireTanzuMetadataLock()
-	defer ReleaseTanzuMetadataLock()
+	unlockMetadata, err := acquireTanzuMetadataLock()
+	if err != nil {
+		return err
+	}
+	defer unlockMetadata.Unlock()
 	node, err := getMetadataNodeNoLock()
 	if err != nil {
 		return err
@@ -66,8 +72,11 @@ func SetConfigMetadataPatchStrategy(key, value string) error {
 // SetConfigMetadataPatchStrategies add or update map of patch strategies
 func SetConfigMetadataPatchStrategies(patchStrategies map[string]string) error {
 	// Retrieve config metadata node
-	AcquireTanzuMetadataLock()
-	defer ReleaseTanzuMetadataLock()
+	unlock, err := acquireTanzuMetadataLock()
+	if err != nil {
+		return err
+	}
+	defer unlock.Unlock()
 	node, err := getMetadataNodeNoLock()
 	if err != nil {
 		return err
@@ -18,8 +18,11 @@ import (
 // getMetadataNode retrieves the config from the local directory with lock
 func getMetadataNode() (*yaml.Node, error) {
 	// Retrieve config metadata node
-	AcquireTanzuMetadataLock()
-	defer ReleaseTanzuMetadataLock()
+	unlock, err := acquireTanzuMetadataLock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock.Unlock()
 	return getMetadataNodeNoLock()
 }
 
@@ -5,12 +5,10 @@
 package config
 
 import (
+	"context"
 	"fmt"
 	"path/filepath"
-	"sync"
 	"time"
-
-	"github.com/juju/fslock"
 )
 
 const (
@@ -19,49 +17,49 @@ const (
 	DefaultMetadataLockTimeout = 10 * time.Minute
 )
 
-var tanzuMetadataLockFile string
-
-// tanzuMetadataLock used as a static lock variable that stores fslock
-// This is used for interprocess locking of the config file
-var tanzuMetadataLock *fslock.Lock
+// tanzuMetadataLock used as a static lock that is used for interprocess locking of the config metadata file
+var tanzuMetadataLock = newTanzuLock("tanzu config metadata file", func() (string, error) {
+	path, err := CfgMetadataFilePath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(path), LocalTanzuMetadataFileLock), nil
+})
 
-// mutexMetadata is used to handle the locking behavior between concurrent calls
-// within the existing process trying to acquire the lock
-var mutexMetadata sync.Mutex
+// tanzuMetadataUnlocker stores the unlocker of the lock acquired with AcquireTanzuMetadataLock
+var tanzuMetadataUnlocker Unlocker
 
-// AcquireTanzuMetadataLock tries to acquire lock to update tanzu config metadata file with timeout
-func AcquireTanzuMetadataLock() {
-	var err error
+// AcquireTanzuMetadataLockContext acquires the lock to update tanzu config metadata file waiting until
+// the context is done. The returned Unlocker must be used to release the lock.
+func AcquireTanzuMetadataLockContext(ctx context.Context) (Unlocker, error) {
+	return tanzuMetadataLock.acquire(ctx)
+}
 
-	if tanzuMetadataLockFile == "" {
-		path, err := CfgMetadataFilePath()
-		if err != nil {
-			panic(fmt.Sprintf("cannot get config path while acquiring lock on tanzu config metadata file, reason: %v", err))
-		}
-		tanzuMetadataLockFile = filepath.Join(filepath.Dir(path), LocalTanzuMetadataFileLock)
-	}
+// acquireTanzuMetadataLock acquires the tanzu metadata lock waiting up to DefaultMetadataLockTimeout
+func acquireTanzuMetadataLock() (Unlocker, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultMetadataLockTimeout)
+	defer cancel()
+	return AcquireTanzuMetadataLockContext(ctx)
+}
 
-	// using fslock to handle interprocess locking
-	lock, err := getFileLockWithTimeOut(tanzuMetadataLockFile, DefaultMetadataLockTimeout)
+// AcquireTanzuMetadataLock tries to acquire lock to update tanzu config metadata file with timeout.
+// It panics on failure, use AcquireTanzuMetadataLockContext to handle the error instead.
+func AcquireTanzuMetadataLock() {
+	unlocker, err := acquireTanzuMetadataLock()
 	if err != nil {
 		panic(fmt.Sprintf("cannot acquire lock for tanzu config metadata file, reason: %v", err))
 	}
-
-	// Lock the mutex to prevent concurrent calls to acquire and configure the tanzuMetadataLock
-	mutexMetadata.Lock()
-	tanzuMetadataLock = lock
+	tanzuMetadataUnlocker = unlocker
 }
 
 // ReleaseTanzuMetadataLock releases the lock if the tanzuMetadataLock was acquired
 func ReleaseTanzuMetadataLock() {
-	if tanzuMetadataLock == nil {
+	if tanzuMetadataUnlocker == nil {
 		return
 	}
-	if errUnlock := tanzuMetadataLock.Unlock(); errUnlock != nil {
+	unlocker := tanzuMetadataUnlocker
+	tanzuMetadataUnlocker = nil
+	if errUnlock := unlocker.Unlock(); errUnlock != nil {
 		panic(fmt.Sprintf("cannot release lock for tanzu config metadata file, reason: %v", errUnlock))
 	}
-
-	tanzuMetadataLock = nil
-	// Unlock the mutex to allow other concurrent calls to acquire and configure the tanzuMetadataLock
-	mutexMetadata.Unlock()
 }
@@ -58,8 +58,11 @@ func UseUnifiedConfig() (bool, error) {
 // DeleteConfigMetadataSetting delete the env entry of specified key
 func DeleteConfigMetadataSetting(key string) error {
 	// Retrieve config metadata node
-	AcquireTanzuMetadataLock()
-	defer ReleaseTanzuMetadataLock()
+	unlock, err := acquireTanzuMetadataLock()
+	if err != nil {
+		return err
+	}
+	defer unlock.Unlock()
 	node, err := getMetadataNodeNoLock()
 	if err != nil {
 		return err
@@ -76,8 +79,11 @@ func DeleteConfigMetadataSetting(key string) error {
 // SetConfigMetadataSetting add or update a env key and value
 func SetConfigMetadataSetting(key, value string) (err error) {
 	// Retrieve config metadata node
-	AcquireTanzuMetadataLock()
-	defer ReleaseTanzuMetadataLock()
+	unlock, err := acquireTanzuMetadataLock()
+	if err != nil {
+		return err
+	}
+	defer unlock.Unlock()
 	node, err := getMetadataNodeNoLock()
 	if err != nil {
 		return err
@@ -0,0 +1,383 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"os"
+	"sync"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+
+	"github.com/vmware-tanzu/tanzu-plugin-runtime/config/nodeutils"
+	configtypes "github.com/vmware-tanzu/tanzu-plugin-runtime/config/types"
+	"github.com/vmware-tanzu/tanzu-plugin-runtime/log"
+)
+
+const (
+	// BaseConfigAPIVersion is the apiVersion of a config-ng.yaml without apiVersion, written before the
+	// config schema was versioned. It has the schema of config.yaml.
+	BaseConfigAPIVersion = "config.tanzu.vmware.com/v1alpha1"
+
+	// CurrentConfigAPIVersion is the apiVersion of config-ng.yaml once all the migrations known by this
+	// runtime are applied
+	CurrentConfigAPIVersion = "config.tanzu.vmware.com/v1alpha3"
+)
+
+// ErrUnsupportedConfigAPIVersion is returned when config-ng.yaml was migrated by a newer runtime
+var ErrUnsupportedConfigAPIVersion = errors.New("unsupported config apiVersion")
+
+// configMigration converts the client config from the previous apiVersion to the apiVersion of the migration
+type configMigration struct {
+	// apiVersion is the apiVersion of config-ng.yaml once the migration is applied
+	apiVersion string
+	// name identifies the migration and its test fixtures under fakes/config/migrations/<name>
+	name string
+	// migrate converts the client config node in place. Applying it again must not change the node.
+	migrate func(node *yaml.Node) error
+}
+
+// configMigrations are the migrations of the client config in the order of the apiVersions.
+// A new migration is appended with the next apiVersion, which becomes CurrentConfigAPIVersion,
+// and a before.yaml and after.yaml fixture.
+var configMigrations = []configMigration{
+	{
+		apiVersion: "config.tanzu.vmware.com/v1alpha2",
+		name:       "populate-contexts",
+		migrate:    migratePopulateContexts,
+	},
+	{
+		apiVersion: "config.tanzu.vmware.com/v1alpha3",
+		name:       "fill-context-types",
+		migrate:    migrateFillContextTypes,
+	},
+}
+
+var (
+	// migratedConfigs records the config-ng.yaml files whose pending migrations were applied by the process
+	migratedConfigs = map[string]bool{}
+	// inMemoryMigratedConfigs records the config-ng.yaml files whose migrations could not be persisted
+	// on read, e.g. in a read-only config dir, and are applied in memory by the reads instead
+	inMemoryMigratedConfigs = map[string]bool{}
+	migratedConfigsMu       sync.Mutex
+)
+
+// GetConfigAPIVersion returns the apiVersion of config-ng.yaml, BaseConfigAPIVersion if not set
+func GetConfigAPIVersion() (string, error) {
+	node, err := getClientConfigNextGenNode()
+	if err != nil {
+		return "", err
+	}
+	return getConfigAPIVersion(node), nil
+}
+
+// MigrateConfig applies the migrations of the client config from the apiVersion of config-ng.yaml to
+// CurrentConfigAPIVersion, in order, and records the applied apiVersion in config-ng.yaml and in the config
+// metadata file. It holds the tanzu config lock so that the migrations run once. It returns the apiVersions
+// applied, none if the config is up to date, and ErrUnsupportedConfigAPIVersion if the config was migrated
+// by a newer runtime.
+func MigrateConfig() ([]string, error) {
+	// Acquire tanzu config lock
+	unlock, err := acquireTanzuConfigLock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock.Unlock()
+	return migrateConfigNoLock()
+}
+
+// ensureConfigMigrated applies the pending migrations of the client config the first time the config
+// is loaded by the process. The tanzu config lock is only acquired if the config was not checked yet.
+func ensureConfigMigrated() error {
+	migrated, err := isConfigMigrated()
+	if err != nil || migrated {
+		return err
+	}
+	// Acquire tanzu config lock
+	unlock, err := acquireTanzuConfigLock()
+	if err != nil {
+		return err
+	}
+	defer unlock.Unlock()
+	return ensureConfigMigratedNoLock()
+}
+
+// ensureConfigMigratedOnRead applies the pending migrations of the client config like ensureConfigMigrated.
+// A failure to persist the migrations is logged once and does not fail the reads, it returns true if the
+// reads have to apply the migrations in memory.
+func ensureConfigMigratedOnRead() bool {
+	if inMemory, _ := isConfigFlagged(inMemoryMigratedConfigs); inMemory {
+		return true
+	}
+	if err := ensureConfigMigrated(); err != nil {
+		log.Warningf("Failed to migrate the tanzu config, the config is migrated in memory until it can be written: %v", err)
+		_ = flagConfig(inMemoryMigratedConfigs)
+		return true
+	}
+	return false
+}
+
+// migrateConfigNodeInMemory applies the pending migrations to the client config node without persisting it.
+// A config migrated by a newer runtime is left as is.
+func migrateConfigNodeInMemory(node *yaml.Node) error {
+	cfgNextGenNode, err := getClientConfigNextGenNodeNoLock()
+	if err != nil {
+		return err
+	}
+	pending, err := pendingConfigMigrations(getConfigAPIVersion(cfgNextGenNode))
+	if err != nil {
+		return nil
+	}
+	for _, m := range pending {
+		if err := m.migrate(node); err != nil {
+			return errors.Wrapf(err, "failed to migrate the config to %s (%s)", m.apiVersion, m.name)
+		}
+	}
+	return nil
+}
+
+// ensureConfigMigratedNoLock applies the pending migrations of the client config the first time the
+// config is loaded by the process. A config migrated by a newer runtime is left as is, it can be read
+// but writing it is refused.
+// Pre-reqs: tanzu config lock is acquired
+func ensureConfigMigratedNoLock() error {
+	migrated, err := isConfigMigrated()
+	if err != nil || migrated {
+		return err
+	}
+	exists, err := clientConfigExists()
+	if err != nil {
+		return err
+	}
+	// There is nothing to migrate in a new config, it is written with the current schema
+	if exists {
+		if _, err := migrateConfigNoLock(); err != nil && !errors.Is(err, ErrUnsupportedConfigAPIVersion) {
+			return err
+		}
+	}
+	if err := flagConfig(migratedConfigs); err != nil {
+		return err
+	}
+	// The reads no longer need to migrate the config in memory
+	return unflagConfig(inMemoryMigratedConfigs)
+}
+
+// isConfigMigrated returns true if the pending migrations of the current config-ng.yaml were applied
+func isConfigMigrated() (bool, error) {
+	return isConfigFlagged(migratedConfigs)
+}
+
+// isConfigFlagged returns true if the current config-ng.yaml is recorded in the configs
+func isConfigFlagged(configs map[string]bool) (bool, error) {
+	cfgNextGenPath, err := ClientConfigNextGenPath()
+	if err != nil {
+		return false, errors.Wrap(err, "failed getting client config ng path")
+	}
+	migratedConfigsMu.Lock()
+	defer migratedConfigsMu.Unlock()
+	return configs[cfgNextGenPath], nil
+}
+
+// flagConfig records the current config-ng.yaml in the configs
+func flagConfig(configs map[string]bool) error {
+	cfgNextGenPath, err := ClientConfigNextGenPath()
+	if err != nil {
+		return errors.Wrap(err, "failed getting client config ng path")
+	}
+	migratedConfigsMu.Lock()
+	defer migratedConfigsMu.Unlock()
+	configs[cfgNextGenPath] = true
+	return nil
+}
+
+// unflagConfig removes the current config-ng.yaml from the configs
+func unflagConfig(configs map[string]bool) error {
+	cfgNextGenPath, err := ClientConfigNextGenPath()
+	if err != nil {
+		return errors.Wrap(err, "failed getting client config ng path")
+	}
+	migratedConfigsMu.Lock()
+	defer migratedConfigsMu.Unlock()
+	delete(configs, cfgNextGenPath)
+	return nil
+}
+
+// clientConfigExists returns true if config.yaml or config-ng.yaml exists and is not empty
+func clientConfigExists() (bool, error) {
+	for _, pathGetter := range []func() (string, error){ClientConfigPath, ClientConfigNextGenPath} {
+		path, err := pathGetter()
+		if err != nil {
+			return false, err
+		}
+		info, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		if info.Size() != 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// migrateConfigNoLock applies the pending migrations of the client config, see MigrateConfig
+// Pre-reqs: tanzu config lock is acquired
+func migrateConfigNoLock() ([]string, error) {
+	cfgNextGenNode, err := getClientConfigNextGenNodeNoLock()
+	if err != nil {
+		return nil, err
+	}
+	pending, err := pendingConfigMigrations(getConfigAPIVersion(cfgNextGenNode))
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []string
+	if len(pending) != 0 {
+		node, err := readClientConfigNodeNoLock()
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range pending {
+			if err := m.migrate(node); err != nil {
+				return nil, errors.Wrapf(err, "failed to migrate the config to %s (%s)", m.apiVersion, m.name)
+			}
+			applied = append(applied, m.apiVersion)
+		}
+		if err := persistConfigWithAPIVersion(node, CurrentConfigAPIVersion); err != nil {
+			return nil, err
+		}
+	}
+	return applied, recordConfigAPIVersion(CurrentConfigAPIVersion)
+}
+
+// pendingConfigMigrations returns the migrations to apply to the client config of the apiVersion
+func pendingConfigMigrations(apiVersion string) ([]configMigration, error) {
+	if apiVersion == BaseConfigAPIVersion {
+		return configMigrations, nil
+	}
+	for i, m := range configMigrations {
+		if m.apiVersion == apiVersion {
+			return configMigrations[i+1:], nil
+		}
+	}
+	return nil, errors.Wrapf(ErrUnsupportedConfigAPIVersion, "the config was migrated to %s by a newer version of the Tanzu CLI, this version supports up to %s", apiVersion, CurrentConfigAPIVersion)
+}
+
+// checkConfigAPIVersion checks that the config-ng.yaml node can be written by this runtime
+func checkConfigAPIVersion(cfgNextGenNode *yaml.Node) error {
+	apiVersion := getConfigAPIVersion(cfgNextGenNode)
+	if _, err := pendingConfigMigrations(apiVersion); err != nil {
+		return errors.Wrap(err, "refusing to write the config")
+	}
+	return nil
+}
+
+// getConfigAPIVersion returns the apiVersion of the config-ng.yaml node, BaseConfigAPIVersion if not set
+func getConfigAPIVersion(cfgNextGenNode *yaml.Node) string {
+	if len(cfgNextGenNode.Content) == 0 {
+		return BaseConfigAPIVersion
+	}
+	if index := nodeutils.GetNodeIndex(cfgNextGenNode.Content[0].Content, KeyAPIVersion); index != -1 && cfgNextGenNode.Content[0].Content[index].Value != "" {
+		return cfgNextGenNode.Content[0].Content[index].Value
+	}
+	return BaseConfigAPIVersion
+}
+
+// setConfigAPIVersion sets the apiVersion of the config-ng.yaml node
+func setConfigAPIVersion(cfgNextGenNode *yaml.Node, apiVersion string) {
+	content := cfgNextGenNode.Content[0].Content
+	if index := nodeutils.GetNodeIndex(content, KeyAPIVersion); index != -1 {
+		content[index].Value = apiVersion
+		return
+	}
+	// The apiVersion is the first key of the file
+	cfgNextGenNode.Content[0].Content = append([]*yaml.Node{
+		{Kind: yaml.ScalarNode, Tag: "!!str", Value: KeyAPIVersion},
+		{Kind: yaml.ScalarNode, Tag: "!!str", Value: apiVersion},
+	}, content...)
+}
+
+// recordConfigAPIVersion records the apiVersion of the client config in the config metadata file
+func recordConfigAPIVersion(apiVersion string) error {
+	unlockMetadata, err := acquireTanzuMetadataLock()
+	if err != nil {
+		return err
+	}
+	defer unlockMetadata.Unlock()
+	node, err := getMetadataNodeNoLock()
+	if err != nil {
+		return err
+	}
+	keys := []nodeutils.Key{
+		{Name: KeyConfigMetadata, Type: yaml.MappingNode},
+		{Name: KeyConfigAPIVersion, Type: yaml.ScalarNode},
+	}
+	versionNode := nodeutils.FindNode(node.Content[0], nodeutils.WithForceCreate(), nodeutils.WithKeys(keys))
+	if versionNode == nil || versionNode.Value == apiVersion {
+		return nil
+	}
+	versionNode.Value = apiVersion
+	return persistConfigMetadata(node)
+}
+
+// migratePopulateContexts converts the servers written by an older CLI to contexts
+func migratePopulateContexts(node *yaml.Node) error {
+	cfg, err := convertNodeToClientConfig(node)
+	if err != nil {
+		return err
+	}
+	for _, s := range cfg.KnownServers {
+		if cfg.HasContext(s.Name) {
+			continue
+		}
+		c := convertServerToContext(s)
+		if _, err := setContext(node, c); err != nil {
+			return errors.Wrapf(err, "failed to convert server %s", s.Name)
+		}
+		if s.Name == cfg.CurrentServer && cfg.CurrentContext[c.ContextType] == "" {
+			if _, err := setCurrentContext(node, c.Name, c.ContextType); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// migrateFillContextTypes sets the contextType of the contexts from their target, and the target
+// from the contextType for the older CLIs
+func migrateFillContextTypes(node *yaml.Node) error {
+	contextsNode := nodeutils.FindNode(node.Content[0], nodeutils.WithKeys([]nodeutils.Key{{Name: KeyContexts}}))
+	for _, contextNode := range sequenceItems(contextsNode) {
+		if contextNode.Kind != yaml.MappingNode {
+			continue
+		}
+		c := &configtypes.Context{}
+		if err := contextNode.Decode(c); err != nil {
+			return errors.Wrap(err, "failed to decode context")
+		}
+		if c.ContextType == "" && c.Target != "" {
+			setMappingScalar(contextNode, "contextType", string(configtypes.ConvertTargetToContextType(c.Target)))
+		}
+		if c.Target == "" && c.ContextType != "" {
+			setMappingScalar(contextNode, "target", string(configtypes.ConvertContextTypeToTarget(c.ContextType)))
+		}
+	}
+	return nil
+}
+
+// setMappingScalar sets the string value of the key of the mapping node
+func setMappingScalar(node *yaml.Node, key, value string) {
+	if index := nodeutils.GetNodeIndex(node.Content, key); index != -1 {
+		valueNode := node.Content[index]
+		valueNode.Kind, valueNode.Tag, valueNode.Value, valueNode.Content = yaml.ScalarNode, "!!str", value, nil
+		return
+	}
+	node.Content = append(node.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key},
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value})
+}
@@ -0,0 +1,244 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/vmware-tanzu/tanzu-plugin-runtime/config/nodeutils"
+	configtypes "github.com/vmware-tanzu/tanzu-plugin-runtime/config/types"
+	"github.com/vmware-tanzu/tanzu-plugin-runtime/log"
+)
+
+const migrationFixturesDir = "../fakes/config/migrations"
+
+func TestConfigMigrationsRegistry(t *testing.T) {
+	require.NotEmpty(t, configMigrations)
+	assert.Equal(t, CurrentConfigAPIVersion, configMigrations[len(configMigrations)-1].apiVersion)
+
+	seen := map[string]bool{BaseConfigAPIVersion: true}
+	for _, m := range configMigrations {
+		assert.False(t, seen[m.apiVersion], "duplicate apiVersion %s", m.apiVersion)
+		seen[m.apiVersion] = true
+		assert.FileExists(t, filepath.Join(migrationFixturesDir, m.name, "before.yaml"))
+		assert.FileExists(t, filepath.Join(migrationFixturesDir, m.name, "after.yaml"))
+	}
+}
+
+func TestConfigMigrations(t *testing.T) {
+	for _, m := range configMigrations {
+		t.Run(m.name, func(t *testing.T) {
+			// The migrations read the patch strategies of the config metadata
+			_, cleanUp := setupTestConfig(t, &CfgTestData{})
+			defer cleanUp()
+
+			before, err := os.ReadFile(filepath.Join(migrationFixturesDir, m.name, "before.yaml"))
+			require.NoError(t, err)
+			after, err := os.ReadFile(filepath.Join(migrationFixturesDir, m.name, "after.yaml"))
+			require.NoError(t, err)
+
+			var node yaml.Node
+			require.NoError(t, yaml.Unmarshal(before, &node))
+			require.NoError(t, m.migrate(&node))
+			migrated, err := yaml.Marshal(&node)
+			require.NoError(t, err)
+			assert.Equal(t, string(after), string(migrated))
+
+			// The migration is idempotent
+			require.NoError(t, m.migrate(&node))
+			migrated, err = yaml.Marshal(&node)
+			require.NoError(t, err)
+			assert.Equal(t, string(after), string(migrated))
+		})
+	}
+}
+
+func TestMigrateConfig(t *testing.T) {
+	cfg := `apiVersion: config.tanzu.vmware.com/v1alpha1
+kind: ClientConfig
+servers:
+  - name: test-mc
+    type: managementcluster
+    managementClusterOpts:
+      path: test-path
+      context: test-context
+current: test-mc
+`
+	cfgNextGen := `contexts:
+  - name: test-tmc
+    target: mission-control
+    globalOpts:
+      endpoint: test-endpoint
+`
+	files, cleanUp := setupTestConfig(t, &CfgTestData{cfg: cfg, cfgNextGen: cfgNextGen})
+	defer cleanUp()
+
+	apiVersion, err := GetConfigAPIVersion()
+	assert.NoError(t, err)
+	assert.Equal(t, BaseConfigAPIVersion, apiVersion)
+
+	applied, err := MigrateConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"config.tanzu.vmware.com/v1alpha2", "config.tanzu.vmware.com/v1alpha3"}, applied)
+
+	apiVersion, err = GetConfigAPIVersion()
+	assert.NoError(t, err)
+	assert.Equal(t, CurrentConfigAPIVersion, apiVersion)
+	metadata, err := GetConfigMetadata()
+	assert.NoError(t, err)
+	assert.Equal(t, CurrentConfigAPIVersion, metadata.ConfigAPIVersion)
+
+	// The apiVersion of config.yaml is unchanged
+	data, err := os.ReadFile(files[0].Name())
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "apiVersion: config.tanzu.vmware.com/v1alpha1")
+
+	c, err := GetContext("test-mc")
+	assert.NoError(t, err)
+	assert.Equal(t, configtypes.ContextTypeK8s, c.ContextType)
+	current, err := GetActiveContext(configtypes.ContextTypeK8s)
+	assert.NoError(t, err)
+	assert.Equal(t, "test-mc", current.Name)
+
+	// The migrations run once
+	applied, err = MigrateConfig()
+	assert.NoError(t, err)
+	assert.Empty(t, applied)
+
+	// The config can still be written
+	err = SetEnv("test", "value")
+	assert.NoError(t, err)
+	apiVersion, err = GetConfigAPIVersion()
+	assert.NoError(t, err)
+	assert.Equal(t, CurrentConfigAPIVersion, apiVersion)
+}
+
+func TestConfigMigratedOnFirstLoad(t *testing.T) {
+	cfg := `servers:
+  - name: test-mc
+    type: managementcluster
+    managementClusterOpts:
+      path: test-path
+      context: test-context
+current: test-mc
+`
+	cfgNextGen := `contexts:
+  - name: test-tmc
+    target: mission-control
+`
+	_, cleanUp := setupTestConfig(t, &CfgTestData{cfg: cfg, cfgNextGen: cfgNextGen})
+	defer cleanUp()
+
+	// Reading the config applies the pending migrations
+	c, err := GetContext("test-tmc")
+	assert.NoError(t, err)
+	assert.Equal(t, configtypes.ContextTypeTMC, c.ContextType)
+	current, err := GetActiveContext(configtypes.ContextTypeK8s)
+	assert.NoError(t, err)
+	assert.Equal(t, "test-mc", current.Name)
+
+	apiVersion, err := GetConfigAPIVersion()
+	assert.NoError(t, err)
+	assert.Equal(t, CurrentConfigAPIVersion, apiVersion)
+	applied, err := MigrateConfig()
+	assert.NoError(t, err)
+	assert.Empty(t, applied)
+}
+
+func TestMigrateConfigWithNewerAPIVersion(t *testing.T) {
+	cfgNextGen := `apiVersion: config.tanzu.vmware.com/v9
+contexts:
+  - name: test-tmc
+    contextType: mission-control
+`
+	files, cleanUp := setupTestConfig(t, &CfgTestData{cfgNextGen: cfgNextGen})
+	defer cleanUp()
+
+	_, err := MigrateConfig()
+	assert.ErrorIs(t, err, ErrUnsupportedConfigAPIVersion)
+
+	// The config can be read but not written
+	c, err := GetContext("test-tmc")
+	assert.NoError(t, err)
+	assert.Equal(t, configtypes.ContextTypeTMC, c.ContextType)
+
+	err = SetEnv("test", "value")
+	assert.ErrorIs(t, err, ErrUnsupportedConfigAPIVersion)
+	assert.ErrorContains(t, err, "refusing to write the config")
+
+	data, err := os.ReadFile(files[1].Name())
+	assert.NoError(t, err)
+	assert.Equal(t, cfgNextGen, string(data))
+}
+
+func TestConfigMigratedInMemoryWhenNotWritable(t *testing.T) {
+	cfg := `servers:
+  - name: test-mc
+    type: managementcluster
+    managementClusterOpts:
+      path: test-path
+      context: test-context
+current: test-mc
+`
+	files, cleanUp := setupTestConfig(t, &CfgTestData{cfg: cfg})
+	var stderr bytes.Buffer
+	log.SetStderr(&stderr)
+	defer func() {
+		cleanUp()
+		log.SetStderr(os.Stderr)
+	}()
+
+	// The commit journal cannot be written, so the migrations cannot be persisted
+	journalPath, err := commitJournalPath()
+	assert.NoError(t, err)
+	assert.NoError(t, os.MkdirAll(filepath.Join(journalPath, "blocker"), 0755))
+
+	// The reads are served from the config migrated in memory
+	c, err := GetContext("test-mc")
+	assert.NoError(t, err)
+	assert.Equal(t, configtypes.ContextTypeK8s, c.ContextType)
+	current, err := GetActiveContext(configtypes.ContextTypeK8s)
+	assert.NoError(t, err)
+	assert.Equal(t, "test-mc", current.Name)
+	assert.Contains(t, stderr.String(), "Failed to migrate the tanzu config")
+	data, err := os.ReadFile(files[0].Name())
+	assert.NoError(t, err)
+	assert.Equal(t, cfg, string(data))
+
+	// The migrations are persisted by the next write once the config can be written
+	assert.NoError(t, os.RemoveAll(journalPath))
+	assert.NoError(t, SetEnv("env1", "val1"))
+	apiVersion, err := GetConfigAPIVersion()
+	assert.NoError(t, err)
+	assert.Equal(t, CurrentConfigAPIVersion, apiVersion)
+}
+
+func TestContextTypeFilledAfterMigration(t *testing.T) {
+	_, cleanUp := setupTestConfig(t, &CfgTestData{cfgNextGen: "apiVersion: " + CurrentConfigAPIVersion + "\n"})
+	defer cleanUp()
+
+	// A context written by an older runtime once the config is migrated has no contextType
+	err := Update(func(tx *ConfigTx) error {
+		contexts := nodeutils.FindNode(tx.node.Content[0], nodeutils.WithForceCreate(), nodeutils.WithKeys([]nodeutils.Key{{Name: KeyContexts, Type: yaml.SequenceNode}}))
+		contexts.Content = append(contexts.Content, &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+			{Kind: yaml.ScalarNode, Value: "name"}, {Kind: yaml.ScalarNode, Value: "test-tmc"},
+			{Kind: yaml.ScalarNode, Value: "target"}, {Kind: yaml.ScalarNode, Value: "mission-control"},
+		}})
+		tx.markPersist(true)
+		return nil
+	})
+	assert.NoError(t, err)
+
+	contexts, err := GetContextsByType(configtypes.ContextTypeTMC)
+	assert.NoError(t, err)
+	assert.Len(t, contexts, 1)
+	assert.Equal(t, configtypes.ContextTypeTMC, contexts[0].ContextType)
+}
@@ -0,0 +1,286 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package nodeutils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// ChangeType is the type of a change between two yaml nodes
+type ChangeType string
+
+const (
+	ChangeAdded    ChangeType = "added"
+	ChangeRemoved  ChangeType = "removed"
+	ChangeModified ChangeType = "modified"
+)
+
+// Change is a difference between two yaml nodes
+type Change struct {
+	// Type of the change from the source to the destination node
+	Type ChangeType `json:"type" yaml:"type"`
+	// Path is the dotted path of the changed value, e.g. contexts[test-mc].clusterOpts.path.
+	// The items of the keyed sequences are identified by their key, the others by their index.
+	Path string `json:"path" yaml:"path"`
+	// Old is the value of the source node, nil if added
+	Old interface{} `json:"old,omitempty" yaml:"old,omitempty"`
+	// New is the value of the destination node, nil if removed
+	New interface{} `json:"new,omitempty" yaml:"new,omitempty"`
+}
+
+// SequenceKeyFunc returns the key identifying an item of a sequence, false if the item has no key
+type SequenceKeyFunc func(item *yaml.Node) (string, bool)
+
+// DiffOptions are the options of Diff
+type DiffOptions struct {
+	// SequenceKeys are the key functions of the sequences by path pattern, the path of the sequence
+	// with [] for the items of the sequences, e.g. contexts[].discoverySources
+	SequenceKeys map[string]SequenceKeyFunc
+}
+
+type DiffOpts func(options *DiffOptions)
+
+// WithSequenceKey identifies the items of the sequences matching the path pattern with the key function
+func WithSequenceKey(pathPattern string, key SequenceKeyFunc) DiffOpts {
+	return func(options *DiffOptions) {
+		options.SequenceKeys[pathPattern] = key
+	}
+}
+
+// FieldSequenceKey returns a SequenceKeyFunc identifying the mapping items by the value of the scalar field
+func FieldSequenceKey(field string) SequenceKeyFunc {
+	return func(item *yaml.Node) (string, bool) {
+		if item.Kind != yaml.MappingNode {
+			return "", false
+		}
+		index := GetNodeIndex(item.Content, field)
+		if index == -1 || item.Content[index].Kind != yaml.ScalarNode || item.Content[index].Value == "" {
+			return "", false
+		}
+		return item.Content[index].Value, true
+	}
+}
+
+// defaultSequenceKey identifies the items of the sequences without key function by name
+var defaultSequenceKey = FieldSequenceKey("name")
+
+// Diff returns the changes from the src node to the dst node in the order of the nodes. The items of the
+// sequences are matched by key when every item of both sequences has a distinct key, by index otherwise.
+// The items are keyed by their name field unless a key function is set for the sequence with WithSequenceKey.
+// A value added or removed, including a mapping or sequence, is reported as a single change.
+func Diff(src, dst *yaml.Node, opts ...DiffOpts) ([]Change, error) {
+	options := &DiffOptions{SequenceKeys: map[string]SequenceKeyFunc{}}
+	for _, opt := range opts {
+		opt(options)
+	}
+	d := &differ{options: options}
+	if err := d.diff(unwrapDocument(src), unwrapDocument(dst), "", ""); err != nil {
+		return nil, err
+	}
+	return d.changes, nil
+}
+
+type differ struct {
+	options *DiffOptions
+	changes []Change
+}
+
+// diff compares the nodes at path. pattern is the path with [] for the items of the sequences.
+func (d *differ) diff(src, dst *yaml.Node, path, pattern string) error {
+	switch {
+	case src == nil && dst == nil:
+		return nil
+	case src == nil:
+		return d.add(ChangeAdded, path, nil, dst)
+	case dst == nil:
+		return d.add(ChangeRemoved, path, src, nil)
+	case src.Kind != dst.Kind:
+		return d.add(ChangeModified, path, src, dst)
+	}
+
+	switch src.Kind {
+	case yaml.MappingNode:
+		return d.diffMappings(src, dst, path, pattern)
+	case yaml.SequenceNode:
+		return d.diffSequences(src, dst, path, pattern)
+	default:
+		if src.Value != dst.Value || src.ShortTag() != dst.ShortTag() {
+			return d.add(ChangeModified, path, src, dst)
+		}
+	}
+	return nil
+}
+
+func (d *differ) diffMappings(src, dst *yaml.Node, path, pattern string) error {
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		key := src.Content[i].Value
+		var dstValue *yaml.Node
+		if index := GetNodeIndex(dst.Content, key); index != -1 {
+			dstValue = dst.Content[index]
+		}
+		if err := d.diff(src.Content[i+1], dstValue, joinPath(path, key), joinPath(pattern, key)); err != nil {
+			return err
+		}
+	}
+	for i := 0; i+1 < len(dst.Content); i += 2 {
+		key := dst.Content[i].Value
+		if GetNodeIndex(src.Content, key) == -1 {
+			if err := d.add(ChangeAdded, joinPath(path, key), nil, dst.Content[i+1]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (d *differ) diffSequences(src, dst *yaml.Node, path, pattern string) error {
+	itemPattern := pattern + "[]"
+	keyFunc, ok := d.options.SequenceKeys[pattern]
+	if !ok {
+		keyFunc = defaultSequenceKey
+	}
+	srcKeys, srcKeyed := sequenceKeys(src, keyFunc)
+	dstKeys, dstKeyed := sequenceKeys(dst, keyFunc)
+
+	if !srcKeyed || !dstKeyed {
+		for i := 0; i < len(src.Content) || i < len(dst.Content); i++ {
+			var srcItem, dstItem *yaml.Node
+			if i < len(src.Content) {
+				srcItem = src.Content[i]
+			}
+			if i < len(dst.Content) {
+				dstItem = dst.Content[i]
+			}
+			if err := d.diff(srcItem, dstItem, fmt.Sprintf("%s[%d]", path, i), itemPattern); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	dstItems := make(map[string]*yaml.Node, len(dst.Content))
+	for i, key := range dstKeys {
+		dstItems[key] = dst.Content[i]
+	}
+	srcItems := make(map[string]bool, len(src.Content))
+	for i, key := range srcKeys {
+		srcItems[key] = true
+		if err := d.diff(src.Content[i], dstItems[key], keyedPath(path, key), itemPattern); err != nil {
+			return err
+		}
+	}
+	for i, key := range dstKeys {
+		if !srcItems[key] {
+			if err := d.add(ChangeAdded, keyedPath(path, key), nil, dst.Content[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// sequenceKeys returns the keys of the items of the sequence, false if an item has no key or
+// the keys are not distinct
+func sequenceKeys(node *yaml.Node, keyFunc SequenceKeyFunc) ([]string, bool) {
+	keys := make([]string, 0, len(node.Content))
+	seen := make(map[string]bool, len(node.Content))
+	for _, item := range node.Content {
+		key, ok := keyFunc(item)
+		if !ok || seen[key] {
+			return nil, false
+		}
+		seen[key] = true
+		keys = append(keys, key)
+	}
+	return keys, true
+}
+
+func (d *differ) add(changeType ChangeType, path string, src, dst *yaml.Node) error {
+	change := Change{Type: changeType, Path: path}
+	if src != nil {
+		if err := src.Decode(&change.Old); err != nil {
+			return errors.Wrapf(err, "failed to decode the value of %s", path)
+		}
+	}
+	if dst != nil {
+		if err := dst.Decode(&change.New); err != nil {
+			return errors.Wrapf(err, "failed to decode the value of %s", path)
+		}
+	}
+	d.changes = append(d.changes, change)
+	return nil
+}
+
+// unwrapDocument returns the root node of a document node
+func unwrapDocument(node *yaml.Node) *yaml.Node {
+	if node != nil && node.Kind == yaml.DocumentNode {
+		if len(node.Content) == 0 {
+			return nil
+		}
+		return node.Content[0]
+	}
+	return node
+}
+
+// joinPath appends the mapping key to the path, quoted if it contains a path separator
+func joinPath(path, key string) string {
+	if strings.ContainsAny(key, ".[]\"") || key == "" {
+		return path + "[" + strconv.Quote(key) + "]"
+	}
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// keyedPath appends the key of a sequence item to the path
+func keyedPath(path, key string) string {
+	if strings.ContainsAny(key, "[]\"") {
+		key = strconv.Quote(key)
+	}
+	return path + "[" + key + "]"
+}
+
+// RenderUnified renders the changes as a unified text diff of the values, from srcName to dstName
+func RenderUnified(changes []Change, srcName, dstName string) (string, error) {
+	if len(changes) == 0 {
+		return "", nil
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", srcName, dstName)
+	for _, c := range changes {
+		path := c.Path
+		if path == "" {
+			path = "."
+		}
+		fmt.Fprintf(&b, "@@ %s (%s) @@\n", path, c.Type)
+		if err := renderValue(&b, "-", c.Old, c.Type != ChangeAdded); err != nil {
+			return "", err
+		}
+		if err := renderValue(&b, "+", c.New, c.Type != ChangeRemoved); err != nil {
+			return "", err
+		}
+	}
+	return b.String(), nil
+}
+
+// renderValue writes the YAML lines of the value prefixed by the prefix
+func renderValue(b *strings.Builder, prefix string, value interface{}, present bool) error {
+	if !present {
+		return nil
+	}
+	data, err := yaml.Marshal(value)
+	if err != nil {
+		return errors.Wrap(err, "failed to render the value")
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+		b.WriteString(prefix + line + "\n")
+	}
+	return nil
+}
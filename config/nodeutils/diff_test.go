@@ -0,0 +1,115 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package nodeutils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func unmarshalNode(t *testing.T, data string) *yaml.Node {
+	var node yaml.Node
+	assert.NoError(t, yaml.Unmarshal([]byte(data), &node))
+	return &node
+}
+
+func TestDiff(t *testing.T) {
+	src := unmarshalNode(t, `
+contexts:
+  - name: ctx-1
+    clusterOpts:
+      path: path-1
+  - name: ctx-2
+    target: kubernetes
+env:
+  FOO: foo
+  "a.b": x
+permissions: [read, write]
+`)
+	dst := unmarshalNode(t, `
+contexts:
+  - name: ctx-2
+    target: kubernetes
+  - name: ctx-1
+    clusterOpts:
+      path: path-2
+      context: ctx
+  - name: ctx-3
+env:
+  "a.b": y
+permissions: [read]
+features:
+  global:
+    foo: "true"
+`)
+
+	changes, err := Diff(src, dst)
+	assert.NoError(t, err)
+	assert.Equal(t, []Change{
+		{Type: ChangeModified, Path: "contexts[ctx-1].clusterOpts.path", Old: "path-1", New: "path-2"},
+		{Type: ChangeAdded, Path: "contexts[ctx-1].clusterOpts.context", New: "ctx"},
+		{Type: ChangeAdded, Path: "contexts[ctx-3]", New: map[string]interface{}{"name": "ctx-3"}},
+		{Type: ChangeRemoved, Path: "env.FOO", Old: "foo"},
+		{Type: ChangeModified, Path: `env["a.b"]`, Old: "x", New: "y"},
+		{Type: ChangeRemoved, Path: "permissions[1]", Old: "write"},
+		{Type: ChangeAdded, Path: "features", New: map[string]interface{}{"global": map[string]interface{}{"foo": "true"}}},
+	}, changes)
+
+	changes, err = Diff(src, src)
+	assert.NoError(t, err)
+	assert.Empty(t, changes)
+}
+
+func TestDiffSequenceKey(t *testing.T) {
+	src := unmarshalNode(t, `
+certs:
+  - host: a.org
+    insecure: "false"
+  - host: b.org
+`)
+	dst := unmarshalNode(t, `
+certs:
+  - host: b.org
+  - host: a.org
+    insecure: "true"
+`)
+
+	// The items without name are compared by index
+	changes, err := Diff(src, dst)
+	assert.NoError(t, err)
+	assert.Len(t, changes, 4)
+	assert.Equal(t, "certs[0].host", changes[0].Path)
+
+	changes, err = Diff(src, dst, WithSequenceKey("certs", FieldSequenceKey("host")))
+	assert.NoError(t, err)
+	assert.Equal(t, []Change{
+		{Type: ChangeModified, Path: "certs[a.org].insecure", Old: "false", New: "true"},
+	}, changes)
+}
+
+func TestRenderUnified(t *testing.T) {
+	out, err := RenderUnified([]Change{
+		{Type: ChangeModified, Path: "contexts[ctx-1].clusterOpts.path", Old: "path-1", New: "path-2"},
+		{Type: ChangeAdded, Path: "contexts[ctx-3]", New: map[string]interface{}{"name": "ctx-3", "target": "kubernetes"}},
+		{Type: ChangeRemoved, Path: "env.FOO", Old: "foo"},
+	}, "a/config.yaml", "b/config.yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, `--- a/config.yaml
++++ b/config.yaml
+@@ contexts[ctx-1].clusterOpts.path (modified) @@
+-path-1
++path-2
+@@ contexts[ctx-3] (added) @@
++name: ctx-3
++target: kubernetes
+@@ env.FOO (removed) @@
+-foo
+`, out)
+
+	out, err = RenderUnified(nil, "a", "b")
+	assert.NoError(t, err)
+	assert.Empty(t, out)
+}
@@ -0,0 +1,227 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/vmware-tanzu/tanzu-plugin-runtime/config/nodeutils"
+	configtypes "github.com/vmware-tanzu/tanzu-plugin-runtime/config/types"
+)
+
+const (
+	// EnvConfigFeaturePrefix is the prefix of the environment variables overriding the features of the
+	// client config. TANZU_CONFIG_FEATURE_<PLUGIN>_<KEY> overrides the feature key of the plugin, with the
+	// plugin and key names in upper case and their characters other than letters and digits replaced by '_'.
+	// e.g. TANZU_CONFIG_FEATURE_GLOBAL_CONTEXT_TARGET_V2 overrides the feature context-target-v2 of global.
+	EnvConfigFeaturePrefix = "TANZU_CONFIG_FEATURE_"
+
+	// EnvConfigEnvPrefix is the prefix of the environment variables overriding the env variables of the
+	// client config. TANZU_CONFIG_ENV_<KEY> overrides the env variable KEY.
+	EnvConfigEnvPrefix = "TANZU_CONFIG_ENV_"
+)
+
+// configOverlay holds the values of the client config overridden by environment variables. The overlay is
+// merged into the values read from the config and never persisted.
+type configOverlay struct {
+	// features maps the overlay names, see overlayFeatureName, to the values of the features
+	features map[string]string
+	// env maps the env variables to their values
+	env map[string]string
+}
+
+// getConfigOverlay returns the overlay defined by the environment variables of the process
+func getConfigOverlay() *configOverlay {
+	overlay := &configOverlay{features: map[string]string{}, env: map[string]string{}}
+	for _, kv := range os.Environ() {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok {
+			continue
+		}
+		if feature := strings.TrimPrefix(name, EnvConfigFeaturePrefix); feature != name && feature != "" {
+			overlay.features[feature] = value
+		}
+		if key := strings.TrimPrefix(name, EnvConfigEnvPrefix); key != name && key != "" {
+			overlay.env[key] = value
+		}
+	}
+	return overlay
+}
+
+// overlayFeatureName returns the suffix of the environment variable overriding the feature key of the plugin
+func overlayFeatureName(plugin, key string) string {
+	return normalizeOverlayName(plugin) + "_" + normalizeOverlayName(key)
+}
+
+func normalizeOverlayName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9'):
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
+
+// getFeature returns the overridden value of the feature key of the plugin
+func (o *configOverlay) getFeature(plugin, key string) (string, bool) {
+	value, ok := o.features[overlayFeatureName(plugin, key)]
+	return value, ok
+}
+
+// getEnv returns the overridden value of the env variable
+func (o *configOverlay) getEnv(key string) (string, bool) {
+	value, ok := o.env[key]
+	return value, ok
+}
+
+// mergeEnvs returns the env variables merged with the overridden env variables
+func (o *configOverlay) mergeEnvs(envs map[string]string) map[string]string {
+	if len(o.env) == 0 {
+		return envs
+	}
+	merged := make(map[string]string, len(envs)+len(o.env))
+	for key, value := range envs {
+		merged[key] = value
+	}
+	for key, value := range o.env {
+		merged[key] = value
+	}
+	return merged
+}
+
+// featureKeys returns the plugin and key of the feature overridden by the overlay name. The feature of the
+// client config with the same overlay name is used if any, else the plugin is the first segment of the name
+// and the key the rest, in lower case with '_' replaced by '-'.
+func featureKeys(cfg *configtypes.ClientConfig, name string) (plugin, key string) {
+	if cfg.ClientOptions != nil {
+		for plugin, features := range cfg.ClientOptions.Features {
+			for key := range features {
+				if overlayFeatureName(plugin, key) == name {
+					return plugin, key
+				}
+			}
+		}
+	}
+	plugin, key, _ = strings.Cut(strings.ToLower(name), "_")
+	return plugin, strings.ReplaceAll(key, "_", "-")
+}
+
+// apply merges the overlay into the client config
+func (o *configOverlay) apply(cfg *configtypes.ClientConfig) {
+	if len(o.features) == 0 && len(o.env) == 0 {
+		return
+	}
+	if cfg.ClientOptions == nil {
+		cfg.ClientOptions = &configtypes.ClientOptions{}
+	}
+	for name, value := range o.features {
+		plugin, key := featureKeys(cfg, name)
+		if key == "" {
+			continue
+		}
+		if cfg.ClientOptions.Features == nil {
+			cfg.ClientOptions.Features = map[string]configtypes.FeatureMap{}
+		}
+		if cfg.ClientOptions.Features[plugin] == nil {
+			cfg.ClientOptions.Features[plugin] = configtypes.FeatureMap{}
+		}
+		cfg.ClientOptions.Features[plugin][key] = value
+	}
+	cfg.ClientOptions.Env = o.mergeEnvs(cfg.ClientOptions.Env)
+}
+
+// applyNode merges the overlay into the client config node, as apply does for the client config
+func (o *configOverlay) applyNode(node *yaml.Node) error {
+	if len(o.features) == 0 && len(o.env) == 0 {
+		return nil
+	}
+	cfg, err := convertNodeToClientConfig(node)
+	if err != nil {
+		return err
+	}
+	for name, value := range o.features {
+		plugin, key := featureKeys(cfg, name)
+		if key == "" {
+			continue
+		}
+		setOverlayNode(node, value, KeyClientOptions, KeyFeatures, plugin, key)
+	}
+	for key, value := range o.env {
+		setOverlayNode(node, value, KeyClientOptions, KeyEnv, key)
+	}
+	return nil
+}
+
+// setOverlayNode sets the string value of the keys of the client config node, creating the missing keys
+func setOverlayNode(node *yaml.Node, value string, names ...string) {
+	keys := make([]nodeutils.Key, 0, len(names))
+	for i, name := range names {
+		kind := yaml.MappingNode
+		if i == len(names)-1 {
+			kind = yaml.ScalarNode
+		}
+		keys = append(keys, nodeutils.Key{Name: name, Type: kind})
+	}
+	valueNode := nodeutils.FindNode(node.Content[0], nodeutils.WithForceCreate(), nodeutils.WithKeys(keys))
+	if valueNode != nil {
+		valueNode.Kind, valueNode.Tag, valueNode.Value, valueNode.Content = yaml.ScalarNode, nodeutils.NodeTagStr, value, nil
+	}
+}
+
+// strip returns a copy of the client config without the overridden values, restoring the persisted
+// values of the features and env variables still set to the values of the overlay
+func (o *configOverlay) strip(cfg, persisted *configtypes.ClientConfig) *configtypes.ClientConfig {
+	if cfg.ClientOptions == nil || (len(o.features) == 0 && len(o.env) == 0) {
+		return cfg
+	}
+	persistedOptions := persisted.ClientOptions
+	if persistedOptions == nil {
+		persistedOptions = &configtypes.ClientOptions{}
+	}
+
+	copied := *cfg
+	clientOptions := *cfg.ClientOptions
+	copied.ClientOptions = &clientOptions
+	if clientOptions.Features != nil {
+		clientOptions.Features = make(map[string]configtypes.FeatureMap, len(cfg.ClientOptions.Features))
+		for plugin, features := range cfg.ClientOptions.Features {
+			clientOptions.Features[plugin] = configtypes.FeatureMap{}
+			for key, value := range features {
+				if overridden, ok := o.getFeature(plugin, key); ok && overridden == value {
+					persistedValue, persistedOK := persistedOptions.Features[plugin][key]
+					if !persistedOK {
+						continue
+					}
+					value = persistedValue
+				}
+				clientOptions.Features[plugin][key] = value
+			}
+			// A plugin only set by the overlay is not persisted
+			if _, persistedOK := persistedOptions.Features[plugin]; !persistedOK && len(clientOptions.Features[plugin]) == 0 {
+				delete(clientOptions.Features, plugin)
+			}
+		}
+	}
+	if clientOptions.Env != nil {
+		clientOptions.Env = make(map[string]string, len(cfg.ClientOptions.Env))
+		for key, value := range cfg.ClientOptions.Env {
+			if overridden, ok := o.getEnv(key); ok && overridden == value {
+				persistedValue, persistedOK := persistedOptions.Env[key]
+				if !persistedOK {
+					continue
+				}
+				value = persistedValue
+			}
+			clientOptions.Env[key] = value
+		}
+	}
+	return &copied
+}
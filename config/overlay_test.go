@@ -0,0 +1,100 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	configtypes "github.com/vmware-tanzu/tanzu-plugin-runtime/config/types"
+)
+
+func TestConfigOverlay(t *testing.T) {
+	unsetConfigFileEnvs()
+	LocalDirName = TestLocalDirName
+	defer cleanupTestingDir(t)
+
+	assert.NoError(t, SetFeature("global", "context-target-v2", "false"))
+	assert.NoError(t, SetEnv("PERSISTED", "persisted-value"))
+	assert.NoError(t, SetEnv("OVERRIDDEN", "persisted-value"))
+
+	t.Setenv("TANZU_CONFIG_FEATURE_GLOBAL_CONTEXT_TARGET_V2", "true")
+	t.Setenv("TANZU_CONFIG_FEATURE_MANAGEMENT_CLUSTER_DUAL_STACK", "true")
+	t.Setenv("TANZU_CONFIG_ENV_OVERRIDDEN", "overlay-value")
+	t.Setenv("TANZU_CONFIG_ENV_NEW", "new-value")
+
+	enabled, err := IsFeatureEnabled("global", "context-target-v2")
+	assert.NoError(t, err)
+	assert.True(t, enabled)
+	enabled, err = IsFeatureEnabled("management-cluster", "dual-stack")
+	assert.NoError(t, err)
+	assert.True(t, enabled)
+
+	value, err := GetEnv("OVERRIDDEN")
+	assert.NoError(t, err)
+	assert.Equal(t, "overlay-value", value)
+	value, err = GetEnv("NEW")
+	assert.NoError(t, err)
+	assert.Equal(t, "new-value", value)
+
+	envs, err := GetAllEnvs()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"PERSISTED": "persisted-value", "OVERRIDDEN": "overlay-value", "NEW": "new-value"}, envs)
+
+	cfg, err := GetClientConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, "true", cfg.ClientOptions.Features["global"]["context-target-v2"])
+	assert.Equal(t, "true", cfg.ClientOptions.Features["management"]["cluster-dual-stack"])
+	assert.Equal(t, "overlay-value", cfg.ClientOptions.Env["OVERRIDDEN"])
+
+	// A plugin only set by the overlay is stripped
+	stripped := getConfigOverlay().strip(cfg, &configtypes.ClientConfig{})
+	assert.NotContains(t, stripped.ClientOptions.Features, "management")
+	assert.Contains(t, cfg.ClientOptions.Features, "management")
+
+	// The overlay is never persisted by the setters
+	assert.NoError(t, SetEnv("PERSISTED", "updated-value"))
+	assert.NoError(t, SetFeature("global", "other", "true"))
+	cfg.ClientOptions.Env["STORED"] = "stored-value"
+	assert.NoError(t, StoreClientConfig(cfg))
+
+	data := readConfigFiles(t)
+	assert.NotContains(t, data, "overlay-value")
+	assert.NotContains(t, data, "new-value")
+	assert.NotContains(t, data, "dual-stack")
+	assert.NotContains(t, data, "management:")
+	assert.Contains(t, data, "stored-value")
+	assert.Contains(t, data, `context-target-v2: "false"`)
+
+	// The persisted values are visible without the overlay
+	for _, key := range []string{"TANZU_CONFIG_FEATURE_GLOBAL_CONTEXT_TARGET_V2", "TANZU_CONFIG_ENV_OVERRIDDEN"} {
+		assert.NoError(t, os.Unsetenv(key))
+	}
+	enabled, err = IsFeatureEnabled("global", "context-target-v2")
+	assert.NoError(t, err)
+	assert.False(t, enabled)
+	value, err = GetEnv("OVERRIDDEN")
+	assert.NoError(t, err)
+	assert.Equal(t, "persisted-value", value)
+}
+
+func TestOverlayFeatureName(t *testing.T) {
+	assert.Equal(t, "GLOBAL_CONTEXT_TARGET_V2", overlayFeatureName("global", "context-target-v2"))
+	assert.Equal(t, "CLUSTER_ALLOW_LEGACY_CLUSTER", overlayFeatureName("cluster", "allow.legacy.cluster"))
+}
+
+// readConfigFiles returns the content of config.yaml and config-ng.yaml
+func readConfigFiles(t *testing.T) string {
+	var content []byte
+	for _, pathGetter := range []func() (string, error){ClientConfigPath, ClientConfigNextGenPath} {
+		path, err := pathGetter()
+		assert.NoError(t, err)
+		data, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		content = append(content, data...)
+	}
+	return string(content)
+}
@@ -85,7 +85,8 @@ contexts:
 currentContext:
     kubernetes: test-mc
 `
-	expectedCfg2 := `cli:
+	expectedCfg2 := `apiVersion: config.tanzu.vmware.com/v1alpha3
+cli:
     discoverySources:
         - oci:
             name: test
@@ -112,6 +113,7 @@ contexts:
         endpoint: test-endpoint
         path: test-path
         context: test-context
+      contextType: kubernetes
 currentContext:
     kubernetes: test-mc
 `
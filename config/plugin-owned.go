@@ -6,6 +6,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"regexp"
 
 	"github.com/pkg/errors"
 )
@@ -13,8 +14,25 @@ import (
 var (
 	// PluginsBaseDir is the name of the plugins owned base directory in which plugin owned settings is stored.
 	PluginsBaseDir = "plugins"
+
+	// dirNameRegexp restricts the plugin and profile names to names usable as a directory name on every OS
+	dirNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
 )
 
+// ValidatePluginName checks that the plugin name can be used as the name of the plugin owned
+// directories, e.g. in the tanzu plugins config and cache directories
+func ValidatePluginName(name string) error {
+	return validateName("plugin", name)
+}
+
+// validateName checks that the name of the kind, e.g. plugin or profile, can be used as a directory name
+func validateName(kind, name string) error {
+	if !dirNameRegexp.MatchString(name) {
+		return errors.Errorf("invalid %s name %q, a %s name must start with a letter or digit and only contain letters, digits, '.', '_' and '-'", kind, name, kind)
+	}
+	return nil
+}
+
 // GetTanzuPluginConfigDir Retrieve the tanzu configuration directory that can be used by the plugins to // create a plugin specific directory to manage plugin owned configurations.
 // .config/tanzu/plugins
 func GetTanzuPluginConfigDir() (string, error) {
@@ -0,0 +1,463 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+
+	"github.com/vmware-tanzu/tanzu-plugin-runtime/config/internal/atomicfile"
+)
+
+const (
+	// EnvXDGCacheHomeKey is the environment variable of the base directory of the user cache files
+	EnvXDGCacheHomeKey = "XDG_CACHE_HOME"
+	// DefaultPluginCacheTTL is the default time to live of the plugin cache entries
+	DefaultPluginCacheTTL = 24 * time.Hour
+	// DefaultPluginCacheMaxSize is the default size budget, in bytes, of the cache of a plugin
+	DefaultPluginCacheMaxSize int64 = 100 * 1024 * 1024
+
+	// pluginCacheLockFile is the name of the lock file protecting the cache of a plugin
+	pluginCacheLockFile = ".cache.lock"
+	// pluginCacheEntriesDir is the directory of the entries in the cache of a plugin
+	pluginCacheEntriesDir = "entries"
+	// pluginCacheDataSuffix and pluginCacheMetadataSuffix are the suffixes of the files of a cache entry
+	pluginCacheDataSuffix     = ".data"
+	pluginCacheMetadataSuffix = ".meta"
+)
+
+var (
+	// ErrCacheEntryNotFound is returned when the cache has no entry for a key
+	ErrCacheEntryNotFound = errors.New("cache entry not found")
+	// ErrCacheEntryExpired is returned, along with the entry, when the entry of a key has expired.
+	// The ETag and LastModified of the entry can be used to revalidate the entry.
+	ErrCacheEntryExpired = errors.New("cache entry expired")
+)
+
+// GetTanzuPluginCacheDir Retrieve the tanzu cache directory that can be used by the plugins to cache
+// API responses and downloaded artifacts. $XDG_CACHE_HOME is used if set, the cache directory of the OS otherwise.
+// .cache/tanzu/plugins
+func GetTanzuPluginCacheDir() (string, error) {
+	cacheDir := os.Getenv(EnvXDGCacheHomeKey)
+	if !filepath.IsAbs(cacheDir) {
+		var err error
+		if cacheDir, err = os.UserCacheDir(); err != nil {
+			return "", errors.Wrap(err, "could not find the cache dir for OS")
+		}
+	}
+
+	pluginsCacheDir := filepath.Join(cacheDir, "tanzu", PluginsBaseDir)
+	if err := os.MkdirAll(pluginsCacheDir, 0755); err != nil {
+		return "", errors.Wrap(err, "could not make tanzu plugins cache directory")
+	}
+	return pluginsCacheDir, nil
+}
+
+// CacheEntryMetadata describes an entry of the cache of a plugin
+type CacheEntryMetadata struct {
+	// Key of the entry
+	Key string `json:"key" yaml:"key"`
+	// Size of the entry data in bytes
+	Size int64 `json:"size" yaml:"size"`
+	// ETag of the cached response
+	ETag string `json:"etag,omitempty" yaml:"etag,omitempty"`
+	// LastModified of the cached response, e.g. the value of the Last-Modified header
+	LastModified string `json:"lastModified,omitempty" yaml:"lastModified,omitempty"`
+	// CreatedAt is the time at which the entry was stored
+	CreatedAt time.Time `json:"createdAt" yaml:"createdAt"`
+	// LastAccessed is the time at which the entry was last read or stored
+	LastAccessed time.Time `json:"lastAccessed" yaml:"lastAccessed"`
+	// ExpiresAt is the time at which the entry expires, zero if the entry does not expire
+	ExpiresAt time.Time `json:"expiresAt,omitempty" yaml:"expiresAt,omitempty"`
+}
+
+// Expired returns true if the entry has expired
+func (m *CacheEntryMetadata) Expired() bool {
+	return !m.ExpiresAt.IsZero() && !time.Now().Before(m.ExpiresAt)
+}
+
+type pluginCacheOptions struct {
+	ttl         time.Duration
+	maxSize     int64
+	lockTimeout time.Duration
+}
+
+type PluginCacheOptions func(o *pluginCacheOptions)
+
+// WithCacheTTL sets the default time to live of the entries, 0 for entries that do not expire
+func WithCacheTTL(ttl time.Duration) PluginCacheOptions {
+	return func(o *pluginCacheOptions) {
+		o.ttl = ttl
+	}
+}
+
+// WithCacheMaxSize sets the size budget of the cache in bytes. The least recently used entries are
+// evicted when the cache grows beyond its budget.
+func WithCacheMaxSize(maxSize int64) PluginCacheOptions {
+	return func(o *pluginCacheOptions) {
+		o.maxSize = maxSize
+	}
+}
+
+// WithCacheLockTimeout sets the time waiting on the lock of the cache, DefaultLockTimeout by default
+func WithCacheLockTimeout(timeout time.Duration) PluginCacheOptions {
+	return func(o *pluginCacheOptions) {
+		o.lockTimeout = timeout
+	}
+}
+
+type cacheEntryOptions struct {
+	etag         string
+	lastModified string
+	ttl          *time.Duration
+}
+
+type CacheEntryOptions func(o *cacheEntryOptions)
+
+// WithETag records the ETag of the cached response
+func WithETag(etag string) CacheEntryOptions {
+	return func(o *cacheEntryOptions) {
+		o.etag = etag
+	}
+}
+
+// WithLastModified records the Last-Modified value of the cached response
+func WithLastModified(lastModified string) CacheEntryOptions {
+	return func(o *cacheEntryOptions) {
+		o.lastModified = lastModified
+	}
+}
+
+// WithEntryTTL overrides the time to live of the cache for the entry, 0 for an entry that does not expire
+func WithEntryTTL(ttl time.Duration) CacheEntryOptions {
+	return func(o *cacheEntryOptions) {
+		o.ttl = &ttl
+	}
+}
+
+// PluginCache is the cache of a plugin in the tanzu plugins cache directory.
+// Every access holds the lock of the cache, the cache is safe to use from multiple plugin processes.
+type PluginCache struct {
+	plugin string
+	opts   pluginCacheOptions
+}
+
+// NewPluginCache returns the cache of the plugin
+func NewPluginCache(plugin string, opts ...PluginCacheOptions) (*PluginCache, error) {
+	if err := ValidatePluginName(plugin); err != nil {
+		return nil, err
+	}
+	options := pluginCacheOptions{
+		ttl:         DefaultPluginCacheTTL,
+		maxSize:     DefaultPluginCacheMaxSize,
+		lockTimeout: DefaultLockTimeout,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.maxSize <= 0 {
+		return nil, errors.Errorf("invalid cache size %d, the size must be greater than 0", options.maxSize)
+	}
+	return &PluginCache{plugin: plugin, opts: options}, nil
+}
+
+// Dir returns the cache directory of the plugin, creating it if needed
+func (c *PluginCache) Dir() (string, error) {
+	cacheDir, err := GetTanzuPluginCacheDir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(cacheDir, c.plugin)
+	if err := os.MkdirAll(filepath.Join(dir, pluginCacheEntriesDir), 0755); err != nil {
+		return "", errors.Wrapf(err, "could not make the cache directory of plugin %s", c.plugin)
+	}
+	return dir, nil
+}
+
+// Get returns the data and the metadata of the entry of the key. ErrCacheEntryNotFound is returned if the
+// cache has no entry for the key and ErrCacheEntryExpired, along with the data and the metadata, if the entry has expired.
+func (c *PluginCache) Get(key string) ([]byte, *CacheEntryMetadata, error) {
+	var data []byte
+	var metadata *CacheEntryMetadata
+	err := c.withLock(func(entriesDir string) error {
+		var err error
+		metadata, err = readCacheEntryMetadata(entriesDir, key)
+		if err != nil {
+			return err
+		}
+		data, err = os.ReadFile(cacheEntryPath(entriesDir, key, pluginCacheDataSuffix))
+		if os.IsNotExist(err) {
+			return errors.Wrapf(ErrCacheEntryNotFound, "cache entry %s of plugin %s", key, c.plugin)
+		}
+		if err != nil {
+			return err
+		}
+		metadata.LastAccessed = time.Now()
+		if err := writeCacheEntryMetadata(entriesDir, metadata); err != nil {
+			return err
+		}
+		if metadata.Expired() {
+			return errors.Wrapf(ErrCacheEntryExpired, "cache entry %s of plugin %s", key, c.plugin)
+		}
+		return nil
+	})
+	return data, metadata, err
+}
+
+// Put stores the data of the key, evicting the expired and then the least recently used entries if the
+// cache grows beyond its size budget. An entry larger than the size budget is rejected.
+func (c *PluginCache) Put(key string, data []byte, opts ...CacheEntryOptions) (*CacheEntryMetadata, error) {
+	if key == "" {
+		return nil, errors.New("cache key cannot be empty")
+	}
+	if int64(len(data)) > c.opts.maxSize {
+		return nil, errors.Errorf("cache entry %s of %d bytes exceeds the cache size of plugin %s of %d bytes", key, len(data), c.plugin, c.opts.maxSize)
+	}
+	options := cacheEntryOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	ttl := c.opts.ttl
+	if options.ttl != nil {
+		ttl = *options.ttl
+	}
+
+	now := time.Now()
+	metadata := &CacheEntryMetadata{
+		Key:          key,
+		Size:         int64(len(data)),
+		ETag:         options.etag,
+		LastModified: options.lastModified,
+		CreatedAt:    now,
+		LastAccessed: now,
+	}
+	if ttl > 0 {
+		metadata.ExpiresAt = now.Add(ttl)
+	}
+	err := c.withLock(func(entriesDir string) error {
+		// Invalidate the previous entry first, so that an interrupted Put leaves a cache miss
+		// rather than the previous metadata describing the new data
+		if err := os.Remove(cacheEntryPath(entriesDir, key, pluginCacheMetadataSuffix)); err != nil && !os.IsNotExist(err) {
+			return errors.Wrapf(err, "failed to invalidate cache entry %s of plugin %s", key, c.plugin)
+		}
+		if err := atomicfile.WriteFile(cacheEntryPath(entriesDir, key, pluginCacheDataSuffix), data, 0600); err != nil {
+			return errors.Wrapf(err, "failed to write cache entry %s of plugin %s", key, c.plugin)
+		}
+		if err := writeCacheEntryMetadata(entriesDir, metadata); err != nil {
+			return err
+		}
+		return c.evict(entriesDir, key)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
+
+// Revalidate extends the expiry of the entry of the key by the time to live of the cache, e.g. once the
+// server confirmed with a 304 Not Modified response that the cached response is still valid.
+func (c *PluginCache) Revalidate(key string) (*CacheEntryMetadata, error) {
+	var metadata *CacheEntryMetadata
+	err := c.withLock(func(entriesDir string) error {
+		var err error
+		if metadata, err = readCacheEntryMetadata(entriesDir, key); err != nil {
+			return err
+		}
+		now := time.Now()
+		metadata.LastAccessed = now
+		metadata.ExpiresAt = time.Time{}
+		if c.opts.ttl > 0 {
+			metadata.ExpiresAt = now.Add(c.opts.ttl)
+		}
+		return writeCacheEntryMetadata(entriesDir, metadata)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
+
+// Delete removes the entry of the key. Deleting a missing entry is a no-op.
+func (c *PluginCache) Delete(key string) error {
+	return c.withLock(func(entriesDir string) error {
+		return removeCacheEntry(entriesDir, key)
+	})
+}
+
+// Entries returns the metadata of the entries of the cache sorted by key
+func (c *PluginCache) Entries() ([]*CacheEntryMetadata, error) {
+	var entries []*CacheEntryMetadata
+	err := c.withLock(func(entriesDir string) error {
+		var err error
+		entries, err = listCacheEntries(entriesDir)
+		return err
+	})
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Key < entries[j].Key
+	})
+	return entries, err
+}
+
+// Clear removes all the entries of the cache
+func (c *PluginCache) Clear() error {
+	return c.withLock(func(entriesDir string) error {
+		if err := os.RemoveAll(entriesDir); err != nil {
+			return errors.Wrapf(err, "failed to clear the cache of plugin %s", c.plugin)
+		}
+		return os.MkdirAll(entriesDir, 0755)
+	})
+}
+
+// withLock calls fn with the entries directory of the cache while holding the lock of the cache
+func (c *PluginCache) withLock(fn func(entriesDir string) error) error {
+	dir, err := c.Dir()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), c.opts.lockTimeout)
+	defer cancel()
+	unlocker, err := AcquireFileLockContext(ctx, filepath.Join(dir, pluginCacheLockFile))
+	if err != nil {
+		return errors.Wrapf(err, "cannot lock the cache of plugin %s", c.plugin)
+	}
+	defer func() { _ = unlocker.Unlock() }()
+	return fn(filepath.Join(dir, pluginCacheEntriesDir))
+}
+
+// evict removes the files left by interrupted writes, then the expired entries and then the least
+// recently used entries, other than the kept entry, until the cache fits in its size budget
+func (c *PluginCache) evict(entriesDir, keep string) error {
+	if err := sweepCacheEntries(entriesDir); err != nil {
+		return err
+	}
+	entries, err := listCacheEntries(entriesDir)
+	if err != nil {
+		return err
+	}
+	var size int64
+	for _, entry := range entries {
+		size += entry.Size
+	}
+	if size <= c.opts.maxSize {
+		return nil
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Expired() != entries[j].Expired() {
+			return entries[i].Expired()
+		}
+		return entries[i].LastAccessed.Before(entries[j].LastAccessed)
+	})
+	for _, entry := range entries {
+		if size <= c.opts.maxSize {
+			break
+		}
+		if entry.Key == keep {
+			continue
+		}
+		if err := removeCacheEntry(entriesDir, entry.Key); err != nil {
+			return err
+		}
+		size -= entry.Size
+	}
+	return nil
+}
+
+// cacheEntryPath returns the path of a file of the entry, named after the hash of the key
+func cacheEntryPath(entriesDir, key, suffix string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(entriesDir, hex.EncodeToString(sum[:])+suffix)
+}
+
+func readCacheEntryMetadata(entriesDir, key string) (*CacheEntryMetadata, error) {
+	data, err := os.ReadFile(cacheEntryPath(entriesDir, key, pluginCacheMetadataSuffix))
+	if os.IsNotExist(err) {
+		return nil, errors.Wrapf(ErrCacheEntryNotFound, "cache entry %s", key)
+	}
+	if err != nil {
+		return nil, err
+	}
+	metadata := &CacheEntryMetadata{}
+	if err := yaml.Unmarshal(data, metadata); err != nil {
+		return nil, errors.Wrapf(err, "failed to read the metadata of cache entry %s", key)
+	}
+	return metadata, nil
+}
+
+func writeCacheEntryMetadata(entriesDir string, metadata *CacheEntryMetadata) error {
+	data, err := yaml.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+	if err := atomicfile.WriteFile(cacheEntryPath(entriesDir, metadata.Key, pluginCacheMetadataSuffix), data, 0600); err != nil {
+		return errors.Wrapf(err, "failed to write the metadata of cache entry %s", metadata.Key)
+	}
+	return nil
+}
+
+// removeCacheEntry removes the metadata first so that a partially removed entry is a cache miss
+func removeCacheEntry(entriesDir, key string) error {
+	for _, suffix := range []string{pluginCacheMetadataSuffix, pluginCacheDataSuffix} {
+		if err := os.Remove(cacheEntryPath(entriesDir, key, suffix)); err != nil && !os.IsNotExist(err) {
+			return errors.Wrapf(err, "failed to remove cache entry %s", key)
+		}
+	}
+	return nil
+}
+
+// sweepCacheEntries removes the data files without metadata and the temporary files of interrupted writes.
+// Pre-reqs: the lock of the cache is acquired, so that no write is in progress
+func sweepCacheEntries(entriesDir string) error {
+	files, err := os.ReadDir(entriesDir)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		name := f.Name()
+		orphaned := strings.Contains(name, ".tmp-")
+		if strings.HasSuffix(name, pluginCacheDataSuffix) {
+			_, err := os.Stat(filepath.Join(entriesDir, strings.TrimSuffix(name, pluginCacheDataSuffix)+pluginCacheMetadataSuffix))
+			orphaned = os.IsNotExist(err)
+		}
+		if f.IsDir() || !orphaned {
+			continue
+		}
+		if err := os.Remove(filepath.Join(entriesDir, name)); err != nil && !os.IsNotExist(err) {
+			return errors.Wrapf(err, "failed to remove the orphaned cache file %s", name)
+		}
+	}
+	return nil
+}
+
+// listCacheEntries returns the metadata of the entries, skipping the unreadable entries
+func listCacheEntries(entriesDir string) ([]*CacheEntryMetadata, error) {
+	files, err := os.ReadDir(entriesDir)
+	if err != nil {
+		return nil, err
+	}
+	var entries []*CacheEntryMetadata
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), pluginCacheMetadataSuffix) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(entriesDir, f.Name()))
+		if err != nil {
+			continue
+		}
+		metadata := &CacheEntryMetadata{}
+		if yaml.Unmarshal(data, metadata) != nil || metadata.Key == "" {
+			continue
+		}
+		entries = append(entries, metadata)
+	}
+	return entries, nil
+}
@@ -0,0 +1,173 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetTanzuPluginCacheDir(t *testing.T) {
+	cacheHome := t.TempDir()
+	t.Setenv(EnvXDGCacheHomeKey, cacheHome)
+
+	dir, err := GetTanzuPluginCacheDir()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(cacheHome, "tanzu", "plugins"), dir)
+	assert.DirExists(t, dir)
+}
+
+func TestPluginCacheGetPut(t *testing.T) {
+	t.Setenv(EnvXDGCacheHomeKey, t.TempDir())
+	c, err := NewPluginCache("test-plugin", WithCacheTTL(time.Hour))
+	require.NoError(t, err)
+
+	_, _, err = c.Get("https://example.com/api")
+	assert.ErrorIs(t, err, ErrCacheEntryNotFound)
+
+	metadata, err := c.Put("https://example.com/api", []byte("response"), WithETag(`"v1"`), WithLastModified("Wed, 21 Oct 2015 07:28:00 GMT"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(8), metadata.Size)
+	assert.False(t, metadata.Expired())
+
+	data, metadata, err := c.Get("https://example.com/api")
+	assert.NoError(t, err)
+	assert.Equal(t, "response", string(data))
+	assert.Equal(t, `"v1"`, metadata.ETag)
+	assert.Equal(t, "Wed, 21 Oct 2015 07:28:00 GMT", metadata.LastModified)
+
+	// An expired entry is returned for revalidation
+	_, err = c.Put("artifact", []byte("binary"), WithETag(`"v2"`), WithEntryTTL(time.Millisecond))
+	require.NoError(t, err)
+	time.Sleep(5 * time.Millisecond)
+	data, metadata, err = c.Get("artifact")
+	assert.ErrorIs(t, err, ErrCacheEntryExpired)
+	assert.Equal(t, "binary", string(data))
+	assert.Equal(t, `"v2"`, metadata.ETag)
+
+	metadata, err = c.Revalidate("artifact")
+	require.NoError(t, err)
+	assert.False(t, metadata.Expired())
+	_, _, err = c.Get("artifact")
+	assert.NoError(t, err)
+
+	entries, err := c.Entries()
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "artifact", entries[0].Key)
+	assert.Equal(t, "https://example.com/api", entries[1].Key)
+
+	assert.NoError(t, c.Delete("artifact"))
+	assert.NoError(t, c.Delete("artifact"))
+	_, _, err = c.Get("artifact")
+	assert.ErrorIs(t, err, ErrCacheEntryNotFound)
+
+	assert.NoError(t, c.Clear())
+	entries, err = c.Entries()
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+
+	_, err = NewPluginCache("../test-plugin")
+	assert.ErrorContains(t, err, `invalid plugin name "../test-plugin"`)
+}
+
+func TestPluginCacheEviction(t *testing.T) {
+	t.Setenv(EnvXDGCacheHomeKey, t.TempDir())
+	c, err := NewPluginCache("test-plugin", WithCacheMaxSize(10), WithCacheTTL(0))
+	require.NoError(t, err)
+
+	for _, key := range []string{"a", "b", "c"} {
+		_, err = c.Put(key, []byte("xxx"))
+		require.NoError(t, err)
+	}
+	// Reading a refreshes its last access, b is the least recently used entry
+	_, _, err = c.Get("a")
+	require.NoError(t, err)
+	_, err = c.Put("d", []byte("xxx"))
+	require.NoError(t, err)
+
+	entries, err := c.Entries()
+	require.NoError(t, err)
+	var keys []string
+	for _, entry := range entries {
+		keys = append(keys, entry.Key)
+	}
+	assert.Equal(t, []string{"a", "c", "d"}, keys)
+
+	// The expired entries are evicted first
+	_, err = c.Put("e", []byte("x"), WithEntryTTL(time.Millisecond))
+	require.NoError(t, err)
+	_, _, err = c.Get("a")
+	require.NoError(t, err)
+	time.Sleep(5 * time.Millisecond)
+	_, err = c.Put("f", []byte("xx"))
+	require.NoError(t, err)
+	_, _, err = c.Get("e")
+	assert.ErrorIs(t, err, ErrCacheEntryNotFound)
+	_, _, err = c.Get("c")
+	assert.ErrorIs(t, err, ErrCacheEntryNotFound)
+	_, _, err = c.Get("d")
+	assert.NoError(t, err)
+
+	_, err = c.Put("large", make([]byte, 11))
+	assert.ErrorContains(t, err, "exceeds the cache size of plugin test-plugin of 10 bytes")
+}
+
+func TestPluginCacheConcurrentAccess(t *testing.T) {
+	t.Setenv(EnvXDGCacheHomeKey, t.TempDir())
+	c, err := NewPluginCache("test-plugin", WithCacheMaxSize(50))
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := c.Put(fmt.Sprintf("key-%d", i), []byte("0123456789"))
+			assert.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+
+	entries, err := c.Entries()
+	require.NoError(t, err)
+	assert.Len(t, entries, 5)
+}
+
+func TestPluginCacheSweepsOrphanedFiles(t *testing.T) {
+	t.Setenv(EnvXDGCacheHomeKey, t.TempDir())
+	c, err := NewPluginCache("test-plugin")
+	require.NoError(t, err)
+	_, err = c.Put("a", []byte("a"))
+	require.NoError(t, err)
+
+	// Simulate the files left by interrupted writes
+	dir, err := c.Dir()
+	require.NoError(t, err)
+	entriesDir := filepath.Join(dir, pluginCacheEntriesDir)
+	orphanedData := cacheEntryPath(entriesDir, "b", pluginCacheDataSuffix)
+	require.NoError(t, os.WriteFile(orphanedData, []byte("b"), 0600))
+	orphanedTemp := filepath.Join(entriesDir, "."+filepath.Base(orphanedData)+".tmp-123")
+	require.NoError(t, os.WriteFile(orphanedTemp, []byte("b"), 0600))
+
+	_, _, err = c.Get("b")
+	assert.ErrorIs(t, err, ErrCacheEntryNotFound)
+
+	_, err = c.Put("c", []byte("c"))
+	require.NoError(t, err)
+	for _, path := range []string{orphanedData, orphanedTemp} {
+		_, err = os.Stat(path)
+		assert.True(t, os.IsNotExist(err), path)
+	}
+	data, _, err := c.Get("a")
+	assert.NoError(t, err)
+	assert.Equal(t, "a", string(data))
+}
@@ -0,0 +1,412 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package pluginconfig provides a store of plugin owned settings kept in plugins/<name>/ of the tanzu config dir
+package pluginconfig
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+
+	"github.com/vmware-tanzu/tanzu-plugin-runtime/config"
+	"github.com/vmware-tanzu/tanzu-plugin-runtime/config/internal/atomicfile"
+)
+
+const (
+	// SettingsFileName is the name of the file storing the settings of a plugin
+	SettingsFileName = "settings.yaml"
+	// DefaultSchemaVersion is the schema version of the settings of a store created without WithSchemaVersion
+	DefaultSchemaVersion = 1
+
+	// settingsLockFileName is the name of the lock file protecting the settings file of a plugin
+	settingsLockFileName = ".settings.lock"
+)
+
+// ErrSettingNotFound is returned when a setting does not exist in the store
+var ErrSettingNotFound = errors.New("setting not found")
+
+// Defaulter is implemented by the settings setting their default values.
+// Default is called before the stored values are decoded on top of the defaults.
+type Defaulter interface {
+	Default()
+}
+
+// Validator is implemented by the settings validating their values.
+// Validate is called after the settings are decoded and before they are stored.
+type Validator interface {
+	Validate() error
+}
+
+// Migration upgrades the settings stored with the previous schema version to the schema Version
+type Migration struct {
+	// Version is the schema version of the settings after the migration
+	Version int
+	// Migrate updates the settings in place
+	Migrate func(values map[string]interface{}) error
+}
+
+type storeOptions struct {
+	schemaVersion int
+	migrations    []Migration
+	lockTimeout   time.Duration
+}
+
+// StoreOptions configures the settings store of a plugin, see WithSchemaVersion and WithLockTimeout
+type StoreOptions func(o *storeOptions)
+
+// WithSchemaVersion sets the schema version of the settings. The settings stored with an older schema
+// version are upgraded with the migrations of a greater version, in the order of their versions.
+// The settings stored with a newer schema version are rejected.
+func WithSchemaVersion(version int, migrations ...Migration) StoreOptions {
+	return func(o *storeOptions) {
+		o.schemaVersion = version
+		o.migrations = append(o.migrations, migrations...)
+	}
+}
+
+// WithLockTimeout sets the time waiting on the lock of the settings, config.DefaultLockTimeout by default
+func WithLockTimeout(timeout time.Duration) StoreOptions {
+	return func(o *storeOptions) {
+		o.lockTimeout = timeout
+	}
+}
+
+// Store reads and writes the settings of a plugin. The settings are stored in plugins/<name>/settings.yaml
+// of the tanzu config dir of the active profile and every access holds the lock of the settings file.
+type Store struct {
+	plugin string
+	opts   storeOptions
+}
+
+// settingsFile is the content of the settings file
+type settingsFile struct {
+	SchemaVersion int                    `yaml:"schemaVersion"`
+	Values        map[string]interface{} `yaml:"values,omitempty"`
+}
+
+// NewStore returns the settings store of the plugin
+func NewStore(plugin string, opts ...StoreOptions) (*Store, error) {
+	if err := config.ValidatePluginName(plugin); err != nil {
+		return nil, err
+	}
+	options := storeOptions{
+		schemaVersion: DefaultSchemaVersion,
+		lockTimeout:   config.DefaultLockTimeout,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.schemaVersion < 1 {
+		return nil, errors.Errorf("invalid schema version %d, the schema version must be greater than 0", options.schemaVersion)
+	}
+	sort.SliceStable(options.migrations, func(i, j int) bool {
+		return options.migrations[i].Version < options.migrations[j].Version
+	})
+	for _, m := range options.migrations {
+		if m.Version < 2 || m.Version > options.schemaVersion {
+			return nil, errors.Errorf("invalid migration to schema version %d, the version must be between 2 and %d", m.Version, options.schemaVersion)
+		}
+		if m.Migrate == nil {
+			return nil, errors.Errorf("missing migrate function of the migration to schema version %d", m.Version)
+		}
+	}
+	return &Store{plugin: plugin, opts: options}, nil
+}
+
+// Plugin returns the name of the plugin owning the settings
+func (s *Store) Plugin() string {
+	return s.plugin
+}
+
+// SchemaVersion returns the schema version of the settings
+func (s *Store) SchemaVersion() int {
+	return s.opts.schemaVersion
+}
+
+// Dir returns the directory of the plugin in the tanzu config dir. The directory is created by the first
+// write of the settings.
+func (s *Store) Dir() (string, error) {
+	tanzuDir, err := config.LocalDir()
+	if err != nil {
+		return "", errors.Wrap(err, "could not find local tanzu dir for OS")
+	}
+	return filepath.Join(tanzuDir, config.PluginsBaseDir, s.plugin), nil
+}
+
+// Get returns the setting of the key decoded into a T. If T implements Defaulter, the stored values are
+// decoded on top of the defaults and if T implements Validator, the setting is validated.
+// ErrSettingNotFound is returned, along with the defaults, if the setting does not exist.
+func Get[T any](s *Store, key string) (T, error) {
+	var value T
+	if t := reflect.TypeOf(&value).Elem(); t.Kind() == reflect.Ptr {
+		// The defaults of a pointer T are set on a new value
+		value = reflect.New(t.Elem()).Interface().(T)
+	}
+	setDefaults(settingOf(&value))
+	err := s.view(func(values map[string]interface{}) error {
+		stored, ok := values[key]
+		if !ok {
+			return errors.Wrapf(ErrSettingNotFound, "setting %s of plugin %s", key, s.plugin)
+		}
+		if err := decodeValue(stored, &value); err != nil {
+			return errors.Wrapf(err, "failed to decode setting %s of plugin %s", key, s.plugin)
+		}
+		return validate(settingOf(&value))
+	})
+	return value, err
+}
+
+// Set validates and stores the setting of the key
+func Set[T any](s *Store, key string, value T) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	if err := validate(settingOf(&value)); err != nil {
+		return err
+	}
+	encoded, err := encodeValue(value)
+	if err != nil {
+		return errors.Wrapf(err, "failed to encode setting %s of plugin %s", key, s.plugin)
+	}
+	return s.update(func(values map[string]interface{}) error {
+		values[key] = encoded
+		return nil
+	})
+}
+
+// Delete removes the setting of the key. Deleting a missing setting is a no-op.
+func (s *Store) Delete(key string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	return s.update(func(values map[string]interface{}) error {
+		delete(values, key)
+		return nil
+	})
+}
+
+// Keys returns the sorted keys of the stored settings
+func (s *Store) Keys() ([]string, error) {
+	var keys []string
+	err := s.view(func(values map[string]interface{}) error {
+		for key := range values {
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	sort.Strings(keys)
+	return keys, err
+}
+
+// Load decodes all the settings into v, a pointer to a struct or a map, each setting being a field of v.
+// If v implements Defaulter, the stored values are decoded on top of the defaults and if v implements
+// Validator, the settings are validated. The defaults are returned if no setting is stored.
+func (s *Store) Load(v interface{}) error {
+	setDefaults(v)
+	err := s.view(func(values map[string]interface{}) error {
+		if len(values) == 0 {
+			return nil
+		}
+		if err := decodeValue(values, v); err != nil {
+			return errors.Wrapf(err, "failed to decode settings of plugin %s", s.plugin)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return validate(v)
+}
+
+// Save validates v and replaces all the settings with the fields of v
+func (s *Store) Save(v interface{}) error {
+	if err := validate(v); err != nil {
+		return err
+	}
+	encoded, err := encodeValue(v)
+	if err != nil {
+		return errors.Wrapf(err, "failed to encode settings of plugin %s", s.plugin)
+	}
+	fields, ok := encoded.(map[string]interface{})
+	if !ok && encoded != nil {
+		return errors.Errorf("settings of plugin %s must be an object, got %T", s.plugin, v)
+	}
+	return s.update(func(values map[string]interface{}) error {
+		for key := range values {
+			delete(values, key)
+		}
+		for key, value := range fields {
+			values[key] = value
+		}
+		return nil
+	})
+}
+
+// view calls fn with the stored settings
+func (s *Store) view(fn func(values map[string]interface{}) error) error {
+	return s.withSettings(false, fn)
+}
+
+// update calls fn with the stored settings and stores the settings updated by fn
+func (s *Store) update(fn func(values map[string]interface{}) error) error {
+	return s.withSettings(true, fn)
+}
+
+// withSettings calls fn with the settings migrated to the schema version of the store while holding the
+// lock of the settings file. The settings are stored if they are updated or migrated.
+// Reading the settings of a plugin without a directory does not create it.
+func (s *Store) withSettings(update bool, fn func(values map[string]interface{}) error) error {
+	dir, err := s.Dir()
+	if err != nil {
+		return err
+	}
+	if !update {
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			// Nothing is stored yet
+			return fn(map[string]interface{}{})
+		}
+	} else if err := os.MkdirAll(dir, 0o755); err != nil {
+		return errors.Wrapf(err, "could not make the directory of plugin %s", s.plugin)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.opts.lockTimeout)
+	defer cancel()
+	unlocker, err := config.AcquireFileLockContext(ctx, filepath.Join(dir, settingsLockFileName))
+	if err != nil {
+		return errors.Wrapf(err, "cannot lock the settings of plugin %s", s.plugin)
+	}
+	defer func() { _ = unlocker.Unlock() }()
+
+	filename := filepath.Join(dir, SettingsFileName)
+	settings, err := readSettingsFile(filename, s.opts.schemaVersion)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read the settings of plugin %s", s.plugin)
+	}
+	migrated, err := s.migrate(settings)
+	if err != nil {
+		return err
+	}
+	if err := fn(settings.Values); err != nil {
+		return err
+	}
+	if !update && !migrated {
+		return nil
+	}
+	settings.SchemaVersion = s.opts.schemaVersion
+	if err := writeSettingsFile(filename, settings); err != nil {
+		return errors.Wrapf(err, "failed to write the settings of plugin %s", s.plugin)
+	}
+	return nil
+}
+
+// migrate upgrades the settings to the schema version of the store and returns true if the settings were upgraded
+func (s *Store) migrate(settings *settingsFile) (bool, error) {
+	if settings.SchemaVersion > s.opts.schemaVersion {
+		return false, errors.Errorf("the settings of plugin %s have schema version %d, newer than the supported schema version %d", s.plugin, settings.SchemaVersion, s.opts.schemaVersion)
+	}
+	if settings.SchemaVersion == s.opts.schemaVersion {
+		return false, nil
+	}
+	for _, m := range s.opts.migrations {
+		if m.Version <= settings.SchemaVersion {
+			continue
+		}
+		if err := m.Migrate(settings.Values); err != nil {
+			return false, errors.Wrapf(err, "failed to migrate the settings of plugin %s to schema version %d", s.plugin, m.Version)
+		}
+	}
+	settings.SchemaVersion = s.opts.schemaVersion
+	return true, nil
+}
+
+// readSettingsFile reads the settings file. A missing settings file has no settings of the given schema version.
+func readSettingsFile(filename string, schemaVersion int) (*settingsFile, error) {
+	settings := &settingsFile{}
+	data, err := os.ReadFile(filename)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		settings.SchemaVersion = schemaVersion
+	} else if err := yaml.Unmarshal(data, settings); err != nil {
+		return nil, err
+	}
+	if settings.SchemaVersion == 0 {
+		// The settings written without schema version have the initial schema version
+		settings.SchemaVersion = DefaultSchemaVersion
+	}
+	if settings.Values == nil {
+		settings.Values = map[string]interface{}{}
+	}
+	return settings, nil
+}
+
+// writeSettingsFile atomically replaces the settings file
+func writeSettingsFile(filename string, settings *settingsFile) error {
+	data, err := yaml.Marshal(settings)
+	if err != nil {
+		return err
+	}
+	return atomicfile.WriteFile(filename, data, 0o600)
+}
+
+// encodeValue converts the value to its generic yaml representation
+func encodeValue(value interface{}) (interface{}, error) {
+	data, err := yaml.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var encoded interface{}
+	if err := yaml.Unmarshal(data, &encoded); err != nil {
+		return nil, err
+	}
+	return encoded, nil
+}
+
+// decodeValue decodes the generic yaml representation into out, rejecting the unknown fields
+func decodeValue(value, out interface{}) error {
+	data, err := yaml.Marshal(value)
+	if err != nil {
+		return err
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	return decoder.Decode(out)
+}
+
+// settingOf returns the setting implementing Defaulter or Validator, the value itself if T is a pointer
+// type implementing them, else the pointer to the value
+func settingOf[T any](value *T) interface{} {
+	switch v := any(*value).(type) {
+	case Defaulter, Validator:
+		return v
+	}
+	return value
+}
+
+func setDefaults(v interface{}) {
+	if d, ok := v.(Defaulter); ok {
+		d.Default()
+	}
+}
+
+func validate(v interface{}) error {
+	if validator, ok := v.(Validator); ok {
+		return validator.Validate()
+	}
+	return nil
+}
+
+func validateKey(key string) error {
+	if key == "" {
+		return errors.New("setting key cannot be empty")
+	}
+	return nil
+}
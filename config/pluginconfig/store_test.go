@@ -0,0 +1,226 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package pluginconfig
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type testSettings struct {
+	Endpoint string   `yaml:"endpoint"`
+	Retries  int      `yaml:"retries"`
+	Regions  []string `yaml:"regions,omitempty"`
+}
+
+func (s *testSettings) Default() {
+	s.Endpoint = "https://default.example.com"
+	s.Retries = 3
+}
+
+func (s *testSettings) Validate() error {
+	if s.Retries < 0 {
+		return errors.Errorf("invalid retries %d", s.Retries)
+	}
+	return nil
+}
+
+func setupTestStore(t *testing.T, opts ...StoreOptions) *Store {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("TANZU_CONFIG_PROFILE", "")
+	s, err := NewStore("test-plugin", opts...)
+	assert.NoError(t, err)
+	return s
+}
+
+func TestStoreGetSetDelete(t *testing.T) {
+	s := setupTestStore(t)
+
+	_, err := Get[string](s, "name")
+	assert.ErrorIs(t, err, ErrSettingNotFound)
+
+	assert.NoError(t, Set(s, "name", "foo"))
+	assert.NoError(t, Set(s, "enabled", true))
+	assert.NoError(t, Set(s, "limits", map[string]int{"cpu": 2}))
+
+	name, err := Get[string](s, "name")
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", name)
+	enabled, err := Get[bool](s, "enabled")
+	assert.NoError(t, err)
+	assert.True(t, enabled)
+	limits, err := Get[map[string]int](s, "limits")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"cpu": 2}, limits)
+	_, err = Get[int](s, "name")
+	assert.ErrorContains(t, err, "failed to decode setting name of plugin test-plugin")
+
+	keys, err := s.Keys()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"enabled", "limits", "name"}, keys)
+
+	assert.NoError(t, s.Delete("name"))
+	assert.NoError(t, s.Delete("missing"))
+	_, err = Get[string](s, "name")
+	assert.ErrorIs(t, err, ErrSettingNotFound)
+
+	dir, err := s.Dir()
+	assert.NoError(t, err)
+	data, err := os.ReadFile(filepath.Join(dir, SettingsFileName))
+	assert.NoError(t, err)
+	assert.Equal(t, "schemaVersion: 1\nvalues:\n    enabled: true\n    limits:\n        cpu: 2\n", string(data))
+
+	assert.EqualError(t, Set(s, "", "foo"), "setting key cannot be empty")
+	_, err = NewStore("../test")
+	assert.ErrorContains(t, err, `invalid plugin name "../test"`)
+}
+
+func TestStoreDefaultsAndValidation(t *testing.T) {
+	s := setupTestStore(t)
+
+	// The defaults are returned for a missing setting
+	settings, err := Get[testSettings](s, "server")
+	assert.ErrorIs(t, err, ErrSettingNotFound)
+	assert.Equal(t, testSettings{Endpoint: "https://default.example.com", Retries: 3}, settings)
+
+	// The stored values are decoded on top of the defaults
+	assert.NoError(t, Set(s, "server", map[string]interface{}{"endpoint": "https://foo.example.com"}))
+	settings, err = Get[testSettings](s, "server")
+	assert.NoError(t, err)
+	assert.Equal(t, testSettings{Endpoint: "https://foo.example.com", Retries: 3}, settings)
+
+	assert.EqualError(t, Set(s, "server", testSettings{Retries: -1}), "invalid retries -1")
+	assert.NoError(t, Set(s, "server", map[string]interface{}{"retries": -1}))
+	_, err = Get[testSettings](s, "server")
+	assert.EqualError(t, err, "invalid retries -1")
+
+	// Unknown fields are rejected
+	assert.NoError(t, Set(s, "server", map[string]interface{}{"endpont": "typo"}))
+	_, err = Get[testSettings](s, "server")
+	assert.ErrorContains(t, err, "field endpont not found")
+}
+
+func TestStoreDefaultsAndValidationOfPointers(t *testing.T) {
+	s := setupTestStore(t)
+
+	settings, err := Get[*testSettings](s, "server")
+	assert.ErrorIs(t, err, ErrSettingNotFound)
+	assert.Equal(t, &testSettings{Endpoint: "https://default.example.com", Retries: 3}, settings)
+
+	assert.NoError(t, Set(s, "server", map[string]interface{}{"endpoint": "https://foo.example.com"}))
+	settings, err = Get[*testSettings](s, "server")
+	assert.NoError(t, err)
+	assert.Equal(t, &testSettings{Endpoint: "https://foo.example.com", Retries: 3}, settings)
+
+	assert.EqualError(t, Set(s, "server", &testSettings{Retries: -1}), "invalid retries -1")
+	assert.NoError(t, Set(s, "server", map[string]interface{}{"retries": -1}))
+	_, err = Get[*testSettings](s, "server")
+	assert.EqualError(t, err, "invalid retries -1")
+}
+
+func TestStoreLoadSave(t *testing.T) {
+	s := setupTestStore(t)
+
+	var settings testSettings
+	assert.NoError(t, s.Load(&settings))
+	assert.Equal(t, testSettings{Endpoint: "https://default.example.com", Retries: 3}, settings)
+
+	assert.NoError(t, Set(s, "stale", "value"))
+	assert.NoError(t, s.Save(&testSettings{Endpoint: "https://foo.example.com", Regions: []string{"us"}}))
+	keys, err := s.Keys()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"endpoint", "regions", "retries"}, keys)
+
+	settings = testSettings{}
+	assert.NoError(t, s.Load(&settings))
+	assert.Equal(t, testSettings{Endpoint: "https://foo.example.com", Retries: 0, Regions: []string{"us"}}, settings)
+
+	assert.EqualError(t, s.Save(&testSettings{Retries: -1}), "invalid retries -1")
+	assert.ErrorContains(t, s.Save([]string{"a"}), "settings of plugin test-plugin must be an object")
+}
+
+func TestStoreSchemaVersion(t *testing.T) {
+	s := setupTestStore(t)
+	assert.NoError(t, Set(s, "timeout", 30))
+
+	var calls []int
+	migrations := []Migration{
+		{Version: 3, Migrate: func(values map[string]interface{}) error {
+			calls = append(calls, 3)
+			values["timeout"] = values["timeoutSeconds"].(string) + "s"
+			delete(values, "timeoutSeconds")
+			return nil
+		}},
+		{Version: 2, Migrate: func(values map[string]interface{}) error {
+			calls = append(calls, 2)
+			values["timeoutSeconds"] = "30"
+			delete(values, "timeout")
+			return nil
+		}},
+	}
+	s3, err := NewStore("test-plugin", WithSchemaVersion(3, migrations...))
+	assert.NoError(t, err)
+	timeout, err := Get[string](s3, "timeout")
+	assert.NoError(t, err)
+	assert.Equal(t, "30s", timeout)
+	assert.Equal(t, []int{2, 3}, calls)
+
+	// The migrated settings are stored
+	timeout, err = Get[string](s3, "timeout")
+	assert.NoError(t, err)
+	assert.Equal(t, "30s", timeout)
+	assert.Equal(t, []int{2, 3}, calls)
+
+	// The settings of a newer schema version are rejected
+	_, err = Get[int](s, "timeout")
+	assert.EqualError(t, err, "the settings of plugin test-plugin have schema version 3, newer than the supported schema version 1")
+	assert.Error(t, Set(s, "timeout", 10))
+
+	_, err = NewStore("test-plugin", WithSchemaVersion(2, Migration{Version: 3, Migrate: migrations[0].Migrate}))
+	assert.EqualError(t, err, "invalid migration to schema version 3, the version must be between 2 and 2")
+	_, err = NewStore("test-plugin", WithSchemaVersion(0))
+	assert.Error(t, err)
+}
+
+func TestStoreConcurrentUpdates(t *testing.T) {
+	s := setupTestStore(t)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			assert.NoError(t, Set(s, "key-"+string(rune('a'+i)), i))
+		}(i)
+	}
+	wg.Wait()
+
+	keys, err := s.Keys()
+	assert.NoError(t, err)
+	assert.Len(t, keys, 10)
+}
+
+func TestStoreReadsDoNotCreateDir(t *testing.T) {
+	s := setupTestStore(t)
+
+	_, err := Get[string](s, "name")
+	assert.ErrorIs(t, err, ErrSettingNotFound)
+	keys, err := s.Keys()
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+
+	dir, err := s.Dir()
+	assert.NoError(t, err)
+	_, err = os.Stat(dir)
+	assert.True(t, os.IsNotExist(err))
+
+	assert.NoError(t, Set(s, "name", "foo"))
+	_, err = os.Stat(filepath.Join(dir, SettingsFileName))
+	assert.NoError(t, err)
+}
@@ -0,0 +1,236 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// EnvConfigProfileKey is the environment variable that selects the config profile
+	EnvConfigProfileKey = "TANZU_CONFIG_PROFILE"
+
+	// DefaultProfileName is the name of the profile stored directly in the local tanzu directory
+	DefaultProfileName = "default"
+
+	// ProfilesDirName is the name of the directory of the local tanzu directory in which the profiles
+	// other than the default profile are stored
+	ProfilesDirName = "profiles"
+)
+
+// ErrProfileNotFound is returned when a config profile does not exist
+var ErrProfileNotFound = errors.New("config profile not found")
+
+var (
+	profileMutex sync.RWMutex
+	// profileOverride is the profile selected with UseProfile, empty if not set
+	profileOverride string
+)
+
+// profileFiles are the files of a profile copied by CopyProfile
+var profileFiles = []string{ConfigName, CfgNextGenName, CfgMetadataName, SecretsFileName, SecretsKeyFileName}
+
+// ActiveProfile returns the name of the config profile in use: the profile selected with UseProfile,
+// else the profile set by the TANZU_CONFIG_PROFILE environment variable, else DefaultProfileName.
+func ActiveProfile() (string, error) {
+	profileMutex.RLock()
+	name := profileOverride
+	profileMutex.RUnlock()
+	if name != "" {
+		return name, nil
+	}
+	name = os.Getenv(EnvConfigProfileKey)
+	if name == "" {
+		return DefaultProfileName, nil
+	}
+	if err := validateProfileName(name); err != nil {
+		return "", errors.Wrapf(err, "invalid %s", EnvConfigProfileKey)
+	}
+	return name, nil
+}
+
+// UseProfile selects the config profile used by the process, taking precedence over the
+// TANZU_CONFIG_PROFILE environment variable. An empty name restores the selection from the environment.
+// ErrProfileNotFound is returned if the profile does not exist.
+func UseProfile(name string) error {
+	if name != "" {
+		exists, err := profileExists(name)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return errors.Wrapf(ErrProfileNotFound, "profile %s", name)
+		}
+	}
+	profileMutex.Lock()
+	defer profileMutex.Unlock()
+	profileOverride = name
+	return nil
+}
+
+// ListProfiles returns the names of the config profiles, the default profile first and the others in order
+func ListProfiles() ([]string, error) {
+	dir, err := profilesDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errors.Wrap(err, "failed to list the config profiles")
+	}
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() && validateProfileName(entry.Name()) == nil && entry.Name() != DefaultProfileName {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return append([]string{DefaultProfileName}, names...), nil
+}
+
+// CreateProfile creates an empty config profile
+func CreateProfile(name string) error {
+	dir, err := newProfileDir(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return errors.Wrapf(err, "failed to create profile %s", name)
+	}
+	return nil
+}
+
+// CopyProfile creates the config profile dst with a copy of the config files, the file secret store
+// and the plugin owned configurations of the profile src
+func CopyProfile(src, dst string) error {
+	srcDir, err := profileDir(src)
+	if err != nil {
+		return err
+	}
+	exists, err := profileExists(src)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errors.Wrapf(ErrProfileNotFound, "profile %s", src)
+	}
+	dstDir, err := newProfileDir(dst)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dstDir, 0755); err != nil {
+		return errors.Wrapf(err, "failed to create profile %s", dst)
+	}
+	for _, name := range profileFiles {
+		srcFile := filepath.Join(srcDir, name)
+		if ok, err := fileExists(srcFile); err != nil || !ok {
+			continue
+		}
+		if err := copyFile(srcFile, filepath.Join(dstDir, name)); err != nil {
+			return errors.Wrapf(err, "failed to copy %s of profile %s", name, src)
+		}
+	}
+	if ok, err := fileExists(filepath.Join(srcDir, PluginsBaseDir)); err == nil && ok {
+		if err := copyDir(filepath.Join(srcDir, PluginsBaseDir), filepath.Join(dstDir, PluginsBaseDir)); err != nil {
+			return errors.Wrapf(err, "failed to copy the plugin owned configurations of profile %s", src)
+		}
+	}
+	return nil
+}
+
+// DeleteProfile deletes the config profile and all its files. The default profile and the active
+// profile cannot be deleted.
+func DeleteProfile(name string) error {
+	if name == DefaultProfileName {
+		return errors.New("the default profile cannot be deleted")
+	}
+	active, err := ActiveProfile()
+	if err != nil {
+		return err
+	}
+	if name == active {
+		return errors.Errorf("profile %s is in use and cannot be deleted", name)
+	}
+	dir, err := profileDir(name)
+	if err != nil {
+		return err
+	}
+	exists, err := profileExists(name)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errors.Wrapf(ErrProfileNotFound, "profile %s", name)
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return errors.Wrapf(err, "failed to delete profile %s", name)
+	}
+	return nil
+}
+
+// baseLocalDir returns the local tanzu directory, which is the directory of the default profile
+func baseLocalDir() (string, error) {
+	return localDirPath(LocalDirName)
+}
+
+// profilesDir returns the directory of the profiles other than the default profile
+func profilesDir() (string, error) {
+	base, err := baseLocalDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, ProfilesDirName), nil
+}
+
+// profileDir returns the directory of the config files of the profile
+func profileDir(name string) (string, error) {
+	if err := validateProfileName(name); err != nil {
+		return "", err
+	}
+	if name == DefaultProfileName {
+		return baseLocalDir()
+	}
+	dir, err := profilesDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name), nil
+}
+
+// newProfileDir returns the directory of a profile to create, failing if the profile exists
+func newProfileDir(name string) (string, error) {
+	dir, err := profileDir(name)
+	if err != nil {
+		return "", err
+	}
+	exists, err := profileExists(name)
+	if err != nil {
+		return "", err
+	}
+	if exists {
+		return "", errors.Errorf("profile %s already exists", name)
+	}
+	return dir, nil
+}
+
+// profileExists checks whether the profile exists. The default profile always exists.
+func profileExists(name string) (bool, error) {
+	dir, err := profileDir(name)
+	if err != nil {
+		return false, err
+	}
+	if name == DefaultProfileName {
+		return true, nil
+	}
+	return fileExists(dir)
+}
+
+func validateProfileName(name string) error {
+	return validateName("profile", name)
+}
@@ -0,0 +1,116 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	configtypes "github.com/vmware-tanzu/tanzu-plugin-runtime/config/types"
+)
+
+// unsetConfigFileEnvs makes the config files follow the local directory of the active profile
+func unsetConfigFileEnvs() {
+	for _, key := range []string{EnvConfigKey, EnvConfigNextGenKey, EnvConfigMetadataKey} {
+		_ = os.Unsetenv(key)
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	unsetConfigFileEnvs()
+	LocalDirName = TestLocalDirName
+	defer cleanupTestingDir(t)
+	defer func() { assert.NoError(t, UseProfile("")) }()
+
+	base, err := localDirPath(TestLocalDirName)
+	assert.NoError(t, err)
+	assert.NoError(t, SetEnv("PROFILE", "default"))
+
+	profiles, err := ListProfiles()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{DefaultProfileName}, profiles)
+
+	// A new profile is empty and isolated from the default profile
+	assert.NoError(t, CreateProfile("staging"))
+	assert.EqualError(t, CreateProfile("staging"), "profile staging already exists")
+	assert.ErrorIs(t, UseProfile("production"), ErrProfileNotFound)
+	assert.NoError(t, UseProfile("staging"))
+
+	active, err := ActiveProfile()
+	assert.NoError(t, err)
+	assert.Equal(t, "staging", active)
+	dir, err := LocalDir()
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(base, ProfilesDirName, "staging"), dir)
+	path, err := ClientConfigNextGenPath()
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, CfgNextGenName), path)
+
+	_, err = GetEnv("PROFILE")
+	assert.Error(t, err)
+	assert.NoError(t, SetEnv("PROFILE", "staging"))
+
+	// A copy has the config of the source profile
+	assert.NoError(t, CopyProfile("staging", "demo"))
+	assert.NoError(t, UseProfile("demo"))
+	value, err := GetEnv("PROFILE")
+	assert.NoError(t, err)
+	assert.Equal(t, "staging", value)
+
+	profiles, err = ListProfiles()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{DefaultProfileName, "demo", "staging"}, profiles)
+
+	// The active profile cannot be deleted
+	assert.EqualError(t, DeleteProfile("demo"), "profile demo is in use and cannot be deleted")
+	assert.EqualError(t, DeleteProfile(DefaultProfileName), "the default profile cannot be deleted")
+
+	assert.NoError(t, UseProfile(DefaultProfileName))
+	value, err = GetEnv("PROFILE")
+	assert.NoError(t, err)
+	assert.Equal(t, "default", value)
+
+	assert.NoError(t, DeleteProfile("demo"))
+	assert.ErrorIs(t, DeleteProfile("demo"), ErrProfileNotFound)
+	profiles, err = ListProfiles()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{DefaultProfileName, "staging"}, profiles)
+}
+
+func TestProfileFromEnv(t *testing.T) {
+	unsetConfigFileEnvs()
+	LocalDirName = TestLocalDirName
+	defer cleanupTestingDir(t)
+	defer func() { assert.NoError(t, UseProfile("")) }()
+
+	t.Setenv(EnvConfigProfileKey, "production")
+	active, err := ActiveProfile()
+	assert.NoError(t, err)
+	assert.Equal(t, "production", active)
+
+	// The profile of the environment is created on the first write
+	assert.NoError(t, SetContext(&configtypes.Context{
+		Name:        "prod",
+		ContextType: configtypes.ContextTypeK8s,
+		ClusterOpts: &configtypes.ClusterServer{Path: "prod-path", Context: "prod-context"},
+	}, false))
+	base, err := localDirPath(TestLocalDirName)
+	assert.NoError(t, err)
+	_, err = os.Stat(filepath.Join(base, ProfilesDirName, "production", CfgNextGenName))
+	assert.NoError(t, err)
+
+	// UseProfile takes precedence over the environment
+	assert.NoError(t, UseProfile(DefaultProfileName))
+	_, err = GetContext("prod")
+	assert.Error(t, err)
+
+	t.Setenv(EnvConfigProfileKey, "../prod")
+	assert.NoError(t, UseProfile(""))
+	_, err = LocalDir()
+	assert.ErrorContains(t, err, "invalid TANZU_CONFIG_PROFILE")
+	t.Setenv(EnvConfigProfileKey, "")
+}
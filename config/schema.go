@@ -0,0 +1,145 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+
+	configtypes "github.com/vmware-tanzu/tanzu-plugin-runtime/config/types"
+)
+
+// JSONSchemaDialect is the JSON Schema dialect of the generated schemas
+const JSONSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// JSON Schema types
+const (
+	schemaTypeObject  = "object"
+	schemaTypeArray   = "array"
+	schemaTypeString  = "string"
+	schemaTypeBoolean = "boolean"
+	schemaTypeInteger = "integer"
+	schemaTypeNumber  = "number"
+
+	schemaFormatDateTime = "date-time"
+)
+
+// JSONSchema is the subset of JSON Schema used to describe the config files
+type JSONSchema struct {
+	Schema      string `json:"$schema,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Type        string `json:"type,omitempty"`
+	Format      string `json:"format,omitempty"`
+	Deprecated  bool   `json:"deprecated,omitempty"`
+	// Properties are the schemas of the known keys of an object
+	Properties map[string]*JSONSchema `json:"properties,omitempty"`
+	// AdditionalProperties is the schema of the keys of an object not in Properties. The keys are rejected if nil.
+	AdditionalProperties *JSONSchema `json:"-"`
+	// Items is the schema of the items of an array
+	Items *JSONSchema `json:"items,omitempty"`
+}
+
+// MarshalJSON marshals the schema, with additionalProperties false for the objects rejecting the unknown keys
+func (s *JSONSchema) MarshalJSON() ([]byte, error) {
+	type schema JSONSchema
+	out := struct {
+		*schema
+		AdditionalProperties interface{} `json:"additionalProperties,omitempty"`
+	}{schema: (*schema)(s)}
+	if s.AdditionalProperties != nil {
+		out.AdditionalProperties = s.AdditionalProperties
+	} else if s.Type == schemaTypeObject {
+		out.AdditionalProperties = false
+	}
+	return json.Marshal(out)
+}
+
+// ClientConfigJSONSchema returns the JSON Schema of the client config files, config.yaml and config-ng.yaml,
+// generated from types.ClientConfig
+func ClientConfigJSONSchema() *JSONSchema {
+	s := generateJSONSchema(reflect.TypeOf(configtypes.ClientConfig{}))
+	s.Schema = JSONSchemaDialect
+	s.Title = "Tanzu client configuration"
+	return s
+}
+
+// MetadataJSONSchema returns the JSON Schema of the config metadata file, .config-metadata.yaml,
+// generated from types.Metadata
+func MetadataJSONSchema() *JSONSchema {
+	s := generateJSONSchema(reflect.TypeOf(configtypes.Metadata{}))
+	s.Schema = JSONSchemaDialect
+	s.Title = "Tanzu config metadata"
+	return s
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// legacyProperties are the keys written by the former versions of the CLI that are not part of the types anymore
+var legacyProperties = map[reflect.Type]map[string]*JSONSchema{
+	reflect.TypeOf(configtypes.ClientConfig{}): {
+		"apiVersion": {Type: schemaTypeString},
+		"kind":       {Type: schemaTypeString, Deprecated: true},
+		"metadata":   {Type: schemaTypeObject, Deprecated: true, AdditionalProperties: &JSONSchema{}},
+	},
+	reflect.TypeOf(configtypes.PluginDiscovery{}): {
+		"contextType": {Type: schemaTypeString, Deprecated: true},
+	},
+}
+
+// generateJSONSchema returns the schema of the YAML representation of the type
+func generateJSONSchema(t reflect.Type) *JSONSchema {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return generateJSONSchema(t.Elem())
+	case reflect.Struct:
+		if t == timeType {
+			return &JSONSchema{Type: schemaTypeString, Format: schemaFormatDateTime}
+		}
+		s := &JSONSchema{Type: schemaTypeObject, Properties: map[string]*JSONSchema{}}
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name := yamlFieldName(f)
+			if f.PkgPath != "" || name == "-" {
+				continue
+			}
+			s.Properties[name] = generateJSONSchema(f.Type)
+		}
+		for name, property := range legacyProperties[t] {
+			p := *property
+			s.Properties[name] = &p
+		}
+		return s
+	case reflect.Map:
+		return &JSONSchema{Type: schemaTypeObject, AdditionalProperties: generateJSONSchema(t.Elem())}
+	case reflect.Slice, reflect.Array:
+		return &JSONSchema{Type: schemaTypeArray, Items: generateJSONSchema(t.Elem())}
+	case reflect.String:
+		return &JSONSchema{Type: schemaTypeString}
+	case reflect.Bool:
+		return &JSONSchema{Type: schemaTypeBoolean}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &JSONSchema{Type: schemaTypeInteger}
+	case reflect.Float32, reflect.Float64:
+		return &JSONSchema{Type: schemaTypeNumber}
+	default:
+		// Any value
+		return &JSONSchema{}
+	}
+}
+
+// yamlFieldName returns the key of the struct field in the YAML representation
+func yamlFieldName(f reflect.StructField) string {
+	name := strings.Split(f.Tag.Get("yaml"), ",")[0]
+	if name == "" {
+		return strings.ToLower(f.Name)
+	}
+	return name
+}
+
+// pluginDiscoveryBackends are the keys of a discovery source of which exactly one must be set
+var pluginDiscoveryBackends = []string{"gcp", "oci", "rest", "k8s", "local"}
@@ -0,0 +1,233 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	configtypes "github.com/vmware-tanzu/tanzu-plugin-runtime/config/types"
+)
+
+const (
+	// SecretRefPrefix is the prefix of the values stored in the config that reference a secret of the SecretStore
+	SecretRefPrefix = "secretref:"
+
+	// SettingSecretStore is the config metadata setting selecting the secret store, either "file" or "exec"
+	SettingSecretStore = "secretStore"
+	// SettingSecretStoreHelper is the config metadata setting specifying the credential helper command of the "exec" secret store
+	SettingSecretStoreHelper = "secretStoreHelper"
+
+	SecretStoreTypeFile = "file"
+	SecretStoreTypeExec = "exec"
+)
+
+// ErrSecretNotFound is returned by a SecretStore when there is no secret for the key
+var ErrSecretNotFound = errors.New("secret not found")
+
+// SecretStore stores the secrets, like the GlobalServerAuth tokens, outside the config files
+type SecretStore interface {
+	// Get returns the secret stored for the key or ErrSecretNotFound
+	Get(key string) (string, error)
+	// Set adds or updates the secret for the key
+	Set(key, secret string) error
+	// Delete removes the secret for the key. Deleting a missing secret is not an error.
+	Delete(key string) error
+}
+
+var (
+	secretStoreMutex sync.Mutex
+	secretStore      SecretStore
+)
+
+// SetSecretStore sets the secret store used by SetContext to move the GlobalServerAuth tokens out of
+// the config and by GetContext to rehydrate them. It takes precedence over the SettingSecretStore
+// config metadata setting. Pass nil to fall back to the config metadata setting.
+func SetSecretStore(store SecretStore) {
+	secretStoreMutex.Lock()
+	defer secretStoreMutex.Unlock()
+	secretStore = store
+}
+
+// GetSecretStore returns the configured secret store, nil if the tokens are stored in the config
+func GetSecretStore() (SecretStore, error) {
+	return resolveSecretStore(GetConfigMetadataSettings)
+}
+
+// resolveSecretStore returns the secret store set by SetSecretStore, or the one selected by the
+// config metadata settings returned by settingsGetter
+func resolveSecretStore(settingsGetter func() (map[string]string, error)) (SecretStore, error) {
+	secretStoreMutex.Lock()
+	store := secretStore
+	secretStoreMutex.Unlock()
+	if store != nil {
+		return store, nil
+	}
+
+	// A failure to read the setting must not fall back to storing the tokens in the config
+	settings, err := settingsGetter()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read the config metadata setting %s", SettingSecretStore)
+	}
+	switch storeType := settings[SettingSecretStore]; storeType {
+	case "":
+		return nil, nil
+	case SecretStoreTypeFile:
+		return NewFileSecretStore()
+	case SecretStoreTypeExec:
+		helper := settings[SettingSecretStoreHelper]
+		if helper == "" {
+			return nil, errors.Errorf("config metadata setting %s is required for the %q secret store", SettingSecretStoreHelper, SecretStoreTypeExec)
+		}
+		return NewExecSecretStore(helper), nil
+	default:
+		return nil, errors.Errorf("unknown secret store %q", storeType)
+	}
+}
+
+// IsSecretRef checks whether the value is a reference to a secret of the SecretStore
+func IsSecretRef(value string) bool {
+	return strings.HasPrefix(value, SecretRefPrefix)
+}
+
+// contextSecretKey returns a new key of a GlobalServerAuth token of a context in the SecretStore.
+// Every write uses a new key so that the secrets referenced by the persisted config are never overwritten.
+func contextSecretKey(contextName, field string) (string, error) {
+	version := make([]byte, 8)
+	if _, err := rand.Read(version); err != nil {
+		return "", errors.Wrap(err, "failed to generate the secret key")
+	}
+	return fmt.Sprintf("contexts/%s/%s/%s", contextName, field, hex.EncodeToString(version)), nil
+}
+
+// contextAuthSecrets returns the token fields of the context auth keyed by field name
+func contextAuthSecrets(c *configtypes.Context) map[string]*string {
+	if c == nil || c.GlobalOpts == nil {
+		return nil
+	}
+	return map[string]*string{
+		"accessToken":   &c.GlobalOpts.Auth.AccessToken,
+		"IDToken":       &c.GlobalOpts.Auth.IDToken,
+		"refresh_token": &c.GlobalOpts.Auth.RefreshToken,
+	}
+}
+
+// contextSecretRefs returns the keys of the secrets referenced by the context
+func contextSecretRefs(c *configtypes.Context) []string {
+	var keys []string
+	for _, value := range contextAuthSecrets(c) {
+		if IsSecretRef(*value) {
+			keys = append(keys, strings.TrimPrefix(*value, SecretRefPrefix))
+		}
+	}
+	return keys
+}
+
+// rehydrateContextSecrets replaces the secret references of the context with the secrets from the secret store
+func rehydrateContextSecrets(c *configtypes.Context) (*configtypes.Context, error) {
+	var store SecretStore
+	for field, value := range contextAuthSecrets(c) {
+		if !IsSecretRef(*value) {
+			continue
+		}
+		if store == nil {
+			var err error
+			store, err = GetSecretStore()
+			if err != nil {
+				return nil, err
+			}
+			if store == nil {
+				return nil, errors.Errorf("%s of context %s is stored in a secret store but no secret store is configured", field, c.Name)
+			}
+		}
+		secret, err := store.Get(strings.TrimPrefix(*value, SecretRefPrefix))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to retrieve %s of context %s", field, c.Name)
+		}
+		*value = secret
+	}
+	return c, nil
+}
+
+// secretStoreTx records the secret store mutations of a ConfigTx. The secrets are written under new keys
+// before the config is persisted and the secrets no longer referenced are deleted only once the config is
+// persisted, so that the persisted config always references existing secrets.
+type secretStoreTx struct {
+	store SecretStore
+	// written are the keys of the secrets written by the transaction, deleted on rollback
+	written []string
+	// released are the keys of the secrets no longer referenced, deleted on commit
+	released []string
+}
+
+// externalize stores the tokens of the context in the secret store and returns a copy of the context
+// referencing them. The context is returned as is if no secret store is configured.
+func (stx *secretStoreTx) externalize(c *configtypes.Context) (*configtypes.Context, error) {
+	if c == nil || c.GlobalOpts == nil {
+		return c, nil
+	}
+	if stx.store == nil {
+		store, err := GetSecretStore()
+		if err != nil || store == nil {
+			return c, err
+		}
+		stx.store = store
+	}
+
+	copied := *c
+	globalOpts := *c.GlobalOpts
+	copied.GlobalOpts = &globalOpts
+	for field, value := range contextAuthSecrets(&copied) {
+		if *value == "" || IsSecretRef(*value) {
+			continue
+		}
+		key, err := contextSecretKey(c.Name, field)
+		if err != nil {
+			return nil, err
+		}
+		if err := stx.store.Set(key, *value); err != nil {
+			return nil, errors.Wrapf(err, "failed to store %s of context %s", field, c.Name)
+		}
+		stx.written = append(stx.written, key)
+		*value = SecretRefPrefix + key
+	}
+	return &copied, nil
+}
+
+// release schedules the deletion of the secrets once the config is persisted
+func (stx *secretStoreTx) release(keys ...string) {
+	stx.released = append(stx.released, keys...)
+}
+
+// commit deletes the released secrets. The config is already persisted and does not reference them
+// anymore, a failure only leaves unreferenced secrets in the store.
+func (stx *secretStoreTx) commit() {
+	stx.deleteAll(stx.released)
+}
+
+// rollback deletes the secrets written by the transaction as the config referencing them was not persisted
+func (stx *secretStoreTx) rollback() {
+	stx.deleteAll(stx.written)
+}
+
+func (stx *secretStoreTx) deleteAll(keys []string) {
+	if len(keys) == 0 {
+		return
+	}
+	if stx.store == nil {
+		store, err := GetSecretStore()
+		if err != nil || store == nil {
+			return
+		}
+		stx.store = store
+	}
+	for _, key := range keys {
+		_ = stx.store.Delete(key)
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"os/exec"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// execSecretStoreUsername is the username recorded with the secrets stored by a credential helper
+	execSecretStoreUsername = "tanzu"
+	// credentialsNotFoundMessage is the message of the credential helpers when there is no secret for a key
+	credentialsNotFoundMessage = "credentials not found"
+)
+
+// ExecSecretStore is a SecretStore delegating to an external credential helper, e.g. a
+// docker-credential-* binary backed by the OS keychain. The helper implements the docker
+// credential helper protocol: it is invoked with the "get", "store" or "erase" action and
+// reads the request from stdin.
+type ExecSecretStore struct {
+	helper string
+}
+
+// credentialHelperRequest is the credentials exchanged with the credential helper
+type credentialHelperRequest struct {
+	ServerURL string `json:"ServerURL"`
+	Username  string `json:"Username"`
+	Secret    string `json:"Secret"`
+}
+
+// NewExecSecretStore returns an ExecSecretStore invoking the helper command
+func NewExecSecretStore(helper string) *ExecSecretStore {
+	return &ExecSecretStore{helper: helper}
+}
+
+// Get returns the secret stored for the key or ErrSecretNotFound
+func (s *ExecSecretStore) Get(key string) (string, error) {
+	out, err := s.run("get", []byte(key))
+	if err != nil {
+		return "", err
+	}
+	resp := &credentialHelperRequest{}
+	if err := json.Unmarshal(out, resp); err != nil {
+		return "", errors.Wrapf(err, "failed to parse the output of credential helper %s", s.helper)
+	}
+	return resp.Secret, nil
+}
+
+// Set adds or updates the secret for the key
+func (s *ExecSecretStore) Set(key, secret string) error {
+	req, err := json.Marshal(&credentialHelperRequest{ServerURL: key, Username: execSecretStoreUsername, Secret: secret})
+	if err != nil {
+		return err
+	}
+	_, err = s.run("store", req)
+	return err
+}
+
+// Delete removes the secret for the key
+func (s *ExecSecretStore) Delete(key string) error {
+	_, err := s.run("erase", []byte(key))
+	if errors.Is(err, ErrSecretNotFound) {
+		return nil
+	}
+	return err
+}
+
+func (s *ExecSecretStore) run(action string, input []byte) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(s.helper, action) //nolint:gosec
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stdout.String() + " " + stderr.String())
+		if strings.Contains(strings.ToLower(msg), credentialsNotFoundMessage) {
+			return nil, ErrSecretNotFound
+		}
+		return nil, errors.Wrapf(err, "credential helper %s %s failed: %s", s.helper, action, msg)
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,193 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+
+	"github.com/vmware-tanzu/tanzu-plugin-runtime/config/internal/atomicfile"
+)
+
+const (
+	// SecretsFileName is the name of the file storing the secrets of the file secret store
+	SecretsFileName = ".secrets.yaml"
+	// SecretsKeyFileName is the name of the file storing the key used to encrypt the secrets of the file secret store
+	SecretsKeyFileName = ".secrets.key"
+
+	secretsKeySize = 32
+	// secretsLockFileSuffix is the suffix of the lock file, next to the secrets file, protecting the secrets
+	secretsLockFileSuffix = ".lock"
+)
+
+// FileSecretStore is a SecretStore encrypting the secrets with AES-GCM in a local file.
+// The encryption key is derived from the content of a local key file that is created on first use.
+// The updates of the secrets file hold an interprocess lock on the file.
+type FileSecretStore struct {
+	secretsFile string
+	keyFile     string
+}
+
+// secretsFileContent is the content of the secrets file, the encrypted secrets keyed by name
+type secretsFileContent struct {
+	Secrets map[string]string `yaml:"secrets,omitempty"`
+}
+
+// NewFileSecretStore returns a FileSecretStore storing the secrets next to the client config
+func NewFileSecretStore() (*FileSecretStore, error) {
+	cfgPath, err := ClientConfigPath()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the client config path")
+	}
+	dir := filepath.Dir(cfgPath)
+	return NewFileSecretStoreAt(filepath.Join(dir, SecretsFileName), filepath.Join(dir, SecretsKeyFileName)), nil
+}
+
+// NewFileSecretStoreAt returns a FileSecretStore storing the secrets in secretsFile encrypted with a key derived from keyFile
+func NewFileSecretStoreAt(secretsFile, keyFile string) *FileSecretStore {
+	return &FileSecretStore{secretsFile: secretsFile, keyFile: keyFile}
+}
+
+// Get returns the secret stored for the key or ErrSecretNotFound
+func (s *FileSecretStore) Get(key string) (string, error) {
+	content, err := s.read()
+	if err != nil {
+		return "", err
+	}
+	encrypted, ok := content.Secrets[key]
+	if !ok {
+		return "", ErrSecretNotFound
+	}
+	gcm, err := s.cipher(false)
+	if err != nil {
+		return "", err
+	}
+	data, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil || len(data) < gcm.NonceSize() {
+		return "", errors.Errorf("malformed secret %s", key)
+	}
+	secret, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], []byte(key))
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to decrypt secret %s", key)
+	}
+	return string(secret), nil
+}
+
+// Set adds or updates the secret for the key
+func (s *FileSecretStore) Set(key, secret string) error {
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock.Unlock()
+
+	content, err := s.read()
+	if err != nil {
+		return err
+	}
+	gcm, err := s.cipher(true)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return errors.Wrap(err, "failed to generate nonce")
+	}
+	if content.Secrets == nil {
+		content.Secrets = make(map[string]string)
+	}
+	content.Secrets[key] = base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(secret), []byte(key)))
+	return s.write(content)
+}
+
+// Delete removes the secret for the key
+func (s *FileSecretStore) Delete(key string) error {
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock.Unlock()
+
+	content, err := s.read()
+	if err != nil {
+		return err
+	}
+	if _, ok := content.Secrets[key]; !ok {
+		return nil
+	}
+	delete(content.Secrets, key)
+	return s.write(content)
+}
+
+// lock acquires the lock of the secrets file, shared by the stores of the same file in every process
+func (s *FileSecretStore) lock() (Unlocker, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultLockTimeout)
+	defer cancel()
+	return AcquireFileLockContext(ctx, s.secretsFile+secretsLockFileSuffix)
+}
+
+func (s *FileSecretStore) read() (*secretsFileContent, error) {
+	content := &secretsFileContent{}
+	data, err := os.ReadFile(s.secretsFile)
+	if os.IsNotExist(err) {
+		return content, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read the secrets file")
+	}
+	if err := yaml.Unmarshal(data, content); err != nil {
+		return nil, errors.Wrap(err, "failed to parse the secrets file")
+	}
+	return content, nil
+}
+
+func (s *FileSecretStore) write(content *secretsFileContent) error {
+	data, err := yaml.Marshal(content)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal the secrets")
+	}
+	if err := os.MkdirAll(filepath.Dir(s.secretsFile), 0o700); err != nil {
+		return err
+	}
+	return atomicfile.WriteFile(s.secretsFile, data, 0o600)
+}
+
+// cipher returns the AES-GCM cipher keyed from the key file, creating the key file if requested
+func (s *FileSecretStore) cipher(create bool) (cipher.AEAD, error) {
+	keyData, err := os.ReadFile(s.keyFile)
+	if os.IsNotExist(err) && create {
+		keyData = make([]byte, secretsKeySize)
+		if _, err := io.ReadFull(rand.Reader, keyData); err != nil {
+			return nil, errors.Wrap(err, "failed to generate the secrets key")
+		}
+		if err := os.MkdirAll(filepath.Dir(s.keyFile), 0o700); err != nil {
+			return nil, err
+		}
+		if err := atomicfile.WriteFile(s.keyFile, keyData, 0o600); err != nil {
+			return nil, errors.Wrap(err, "failed to write the secrets key file")
+		}
+	} else if err != nil {
+		return nil, errors.Wrap(err, "failed to read the secrets key file")
+	}
+	if len(keyData) == 0 {
+		return nil, errors.Errorf("secrets key file %s is empty", s.keyFile)
+	}
+
+	key := sha256.Sum256(keyData)
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,276 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	configtypes "github.com/vmware-tanzu/tanzu-plugin-runtime/config/types"
+)
+
+func TestFileSecretStore(t *testing.T) {
+	dir, err := os.MkdirTemp("", "tanzu_secrets")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	store := NewFileSecretStoreAt(filepath.Join(dir, SecretsFileName), filepath.Join(dir, SecretsKeyFileName))
+
+	_, err = store.Get("key1")
+	assert.True(t, errors.Is(err, ErrSecretNotFound))
+
+	assert.NoError(t, store.Set("key1", "secret1"))
+	assert.NoError(t, store.Set("key2", "secret2"))
+
+	secret, err := store.Get("key1")
+	assert.NoError(t, err)
+	assert.Equal(t, "secret1", secret)
+
+	// The secrets are not stored in clear text
+	data, err := os.ReadFile(filepath.Join(dir, SecretsFileName))
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), "secret1")
+
+	// The secrets cannot be decrypted with another key
+	other := NewFileSecretStoreAt(filepath.Join(dir, SecretsFileName), filepath.Join(dir, "other.key"))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "other.key"), []byte("other"), 0600))
+	_, err = other.Get("key1")
+	assert.Error(t, err)
+
+	assert.NoError(t, store.Delete("key1"))
+	assert.NoError(t, store.Delete("key1"))
+	_, err = store.Get("key1")
+	assert.True(t, errors.Is(err, ErrSecretNotFound))
+	secret, err = store.Get("key2")
+	assert.NoError(t, err)
+	assert.Equal(t, "secret2", secret)
+}
+
+func TestFileSecretStoreConcurrentInstances(t *testing.T) {
+	dir, err := os.MkdirTemp("", "tanzu_secrets")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// Every store of the same file shares the lock of the file
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			store := NewFileSecretStoreAt(filepath.Join(dir, SecretsFileName), filepath.Join(dir, SecretsKeyFileName))
+			assert.NoError(t, store.Set(fmt.Sprintf("key%d", i), "secret"))
+		}(i)
+	}
+	wg.Wait()
+
+	store := NewFileSecretStoreAt(filepath.Join(dir, SecretsFileName), filepath.Join(dir, SecretsKeyFileName))
+	for i := 0; i < 10; i++ {
+		secret, err := store.Get(fmt.Sprintf("key%d", i))
+		assert.NoError(t, err)
+		assert.Equal(t, "secret", secret)
+	}
+}
+
+func TestSetContextWithSecretStore(t *testing.T) {
+	// Setup config data
+	files, cleanUp := setupTestConfig(t, &CfgTestData{})
+	dir, err := os.MkdirTemp("", "tanzu_secrets")
+	assert.NoError(t, err)
+
+	defer func() {
+		cleanUp()
+		_ = os.RemoveAll(dir)
+		SetSecretStore(nil)
+	}()
+
+	store := NewFileSecretStoreAt(filepath.Join(dir, SecretsFileName), filepath.Join(dir, SecretsKeyFileName))
+	SetSecretStore(store)
+
+	ctx := &configtypes.Context{
+		Name:        "test-tmc",
+		ContextType: configtypes.ContextTypeTMC,
+		GlobalOpts: &configtypes.GlobalServer{
+			Endpoint: "test-endpoint",
+			Auth: configtypes.GlobalServerAuth{
+				Issuer:       "https://issuer",
+				AccessToken:  "access-token",
+				IDToken:      "id-token",
+				RefreshToken: "refresh-token",
+			},
+		},
+	}
+	err = SetContext(ctx, true)
+	assert.NoError(t, err)
+
+	// The tokens are replaced by references in the config files
+	for _, f := range files[:2] {
+		data, err := os.ReadFile(f.Name())
+		assert.NoError(t, err)
+		assert.NotContains(t, string(data), "access-token")
+		assert.NotContains(t, string(data), "refresh-token")
+	}
+	cfg, err := GetClientConfig()
+	assert.NoError(t, err)
+	c, err := cfg.GetContext("test-tmc")
+	assert.NoError(t, err)
+	accessTokenRef := c.GlobalOpts.Auth.AccessToken
+	assert.True(t, strings.HasPrefix(accessTokenRef, SecretRefPrefix+"contexts/test-tmc/accessToken/"))
+	assert.Equal(t, "https://issuer", c.GlobalOpts.Auth.Issuer)
+
+	// The caller's context is left untouched
+	assert.Equal(t, "access-token", ctx.GlobalOpts.Auth.AccessToken)
+
+	c, err = GetContext("test-tmc")
+	assert.NoError(t, err)
+	assert.Equal(t, "access-token", c.GlobalOpts.Auth.AccessToken)
+	assert.Equal(t, "id-token", c.GlobalOpts.Auth.IDToken)
+	assert.Equal(t, "refresh-token", c.GlobalOpts.Auth.RefreshToken)
+
+	c, err = GetActiveContext(configtypes.ContextTypeTMC)
+	assert.NoError(t, err)
+	assert.Equal(t, "access-token", c.GlobalOpts.Auth.AccessToken)
+
+	// Updating a token stores it under a new key and deletes the replaced secret once persisted
+	updated := *ctx
+	updated.GlobalOpts = &configtypes.GlobalServer{Endpoint: "test-endpoint", Auth: configtypes.GlobalServerAuth{AccessToken: "new-access-token"}}
+	err = SetContext(&updated, false)
+	assert.NoError(t, err)
+	_, err = store.Get(strings.TrimPrefix(accessTokenRef, SecretRefPrefix))
+	assert.True(t, errors.Is(err, ErrSecretNotFound))
+	c, err = GetContext("test-tmc")
+	assert.NoError(t, err)
+	assert.Equal(t, "new-access-token", c.GlobalOpts.Auth.AccessToken)
+	assert.Equal(t, "refresh-token", c.GlobalOpts.Auth.RefreshToken)
+
+	// A failed update leaves the secrets referenced by the config untouched and rolls back the new secrets
+	cfg, err = GetClientConfig()
+	assert.NoError(t, err)
+	c, err = cfg.GetContext("test-tmc")
+	assert.NoError(t, err)
+	refs := contextSecretRefs(c)
+	assert.Len(t, refs, 3)
+	var written []string
+	err = Update(func(tx *ConfigTx) error {
+		updated.GlobalOpts = &configtypes.GlobalServer{Endpoint: "test-endpoint", Auth: configtypes.GlobalServerAuth{AccessToken: "failed-access-token"}}
+		if err := tx.SetContext(&updated, false); err != nil {
+			return err
+		}
+		if err := tx.DeleteContext("test-tmc"); err != nil {
+			return err
+		}
+		written = tx.secrets.written
+		return errors.New("update failed")
+	})
+	assert.EqualError(t, err, "update failed")
+	assert.Len(t, written, 1)
+	_, err = store.Get(written[0])
+	assert.True(t, errors.Is(err, ErrSecretNotFound))
+	for _, ref := range refs {
+		_, err = store.Get(ref)
+		assert.NoError(t, err)
+	}
+
+	// Deleting the context deletes the secrets
+	err = DeleteContext("test-tmc")
+	assert.NoError(t, err)
+	for _, ref := range refs {
+		_, err = store.Get(ref)
+		assert.True(t, errors.Is(err, ErrSecretNotFound))
+	}
+
+	// A secret reference cannot be resolved without a secret store
+	err = SetContext(ctx, false)
+	assert.NoError(t, err)
+	SetSecretStore(nil)
+	_, err = GetContext("test-tmc")
+	assert.ErrorContains(t, err, "no secret store is configured")
+}
+
+func TestExecSecretStoreFromMetadataSetting(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake credential helper is a shell script")
+	}
+	dir, err := os.MkdirTemp("", "tanzu_secrets")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// Fake credential helper storing the secrets in files
+	helper := filepath.Join(dir, "credential-helper")
+	script := `#!/bin/sh
+store="` + dir + `/store"
+mkdir -p "$store"
+case "$1" in
+  store) cat > "$store/last" ; key=$(sed 's/.*"ServerURL":"\([^"]*\)".*/\1/' "$store/last" | tr '/' '_') ; mv "$store/last" "$store/$key" ;;
+  get) key=$(cat | tr '/' '_') ; [ -f "$store/$key" ] || { echo "credentials not found in native keychain" ; exit 1 ; } ; cat "$store/$key" ;;
+  erase) key=$(cat | tr '/' '_') ; rm -f "$store/$key" ;;
+esac
+`
+	assert.NoError(t, os.WriteFile(helper, []byte(script), 0700))
+
+	cfgMetadata := `configMetadata:
+  settings:
+    secretStore: exec
+    secretStoreHelper: ` + helper + `
+`
+	_, cleanUp := setupTestConfig(t, &CfgTestData{cfgMetadata: cfgMetadata})
+	defer cleanUp()
+
+	store, err := GetSecretStore()
+	assert.NoError(t, err)
+	assert.IsType(t, &ExecSecretStore{}, store)
+
+	ctx := &configtypes.Context{
+		Name:        "test-tmc",
+		ContextType: configtypes.ContextTypeTMC,
+		GlobalOpts: &configtypes.GlobalServer{
+			Endpoint: "test-endpoint",
+			Auth:     configtypes.GlobalServerAuth{AccessToken: "access-token"},
+		},
+	}
+	assert.NoError(t, SetContext(ctx, true))
+
+	c, err := GetContext("test-tmc")
+	assert.NoError(t, err)
+	assert.Equal(t, "access-token", c.GlobalOpts.Auth.AccessToken)
+	cfg, err := GetClientConfig()
+	assert.NoError(t, err)
+	c, err = cfg.GetContext("test-tmc")
+	assert.NoError(t, err)
+	accessTokenRef := strings.TrimPrefix(c.GlobalOpts.Auth.AccessToken, SecretRefPrefix)
+
+	assert.NoError(t, DeleteContext("test-tmc"))
+	_, err = store.Get(accessTokenRef)
+	assert.True(t, errors.Is(err, ErrSecretNotFound))
+	entries, err := os.ReadDir(filepath.Join(dir, "store"))
+	assert.NoError(t, err)
+	for _, e := range entries {
+		assert.False(t, strings.Contains(e.Name(), "accessToken"))
+	}
+}
+
+func TestGetSecretStoreMetadataReadFailure(t *testing.T) {
+	_, cleanUp := setupTestConfig(t, &CfgTestData{cfgMetadata: "configMetadata: ["})
+	defer cleanUp()
+
+	// The tokens must not be stored in clear text when the secret store setting cannot be read
+	_, err := GetSecretStore()
+	assert.ErrorContains(t, err, "failed to read the config metadata setting secretStore")
+	err = SetContext(&configtypes.Context{
+		Name:        "test-tmc",
+		ContextType: configtypes.ContextTypeTMC,
+		GlobalOpts: &configtypes.GlobalServer{
+			Endpoint: "test-endpoint",
+			Auth:     configtypes.GlobalServerAuth{AccessToken: "access-token"},
+		},
+	}, false)
+	assert.Error(t, err)
+}
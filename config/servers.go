@@ -50,8 +50,11 @@ func GetCurrentServer() (*configtypes.Server, error) {
 // Deprecated: This API is deprecated. Use SetCurrentContext instead.
 func SetCurrentServer(name string) error {
 	// Retrieve client config node
-	AcquireTanzuConfigLock()
-	defer ReleaseTanzuConfigLock()
+	unlock, err := acquireTanzuConfigLock()
+	if err != nil {
+		return err
+	}
+	defer unlock.Unlock()
 	node, err := getClientConfigNodeNoLock()
 	if err != nil {
 		return err
@@ -90,8 +93,11 @@ func SetCurrentServer(name string) error {
 // Deprecated: This API is deprecated. Use RemoveCurrentContext instead.
 func RemoveCurrentServer(name string) error {
 	// Retrieve client config node
-	AcquireTanzuConfigLock()
-	defer ReleaseTanzuConfigLock()
+	unlock, err := acquireTanzuConfigLock()
+	if err != nil {
+		return err
+	}
+	defer unlock.Unlock()
 	node, err := getClientConfigNodeNoLock()
 	if err != nil {
 		return err
@@ -136,8 +142,11 @@ func AddServer(s *configtypes.Server, setCurrent bool) error {
 // Deprecated: This API is deprecated. Use AddContext or SetContext instead.
 func SetServer(s *configtypes.Server, setCurrent bool) error {
 	// Acquire tanzu config lock
-	AcquireTanzuConfigLock()
-	defer ReleaseTanzuConfigLock()
+	unlock, err := acquireTanzuConfigLock()
+	if err != nil {
+		return err
+	}
+	defer unlock.Unlock()
 	node, err := getClientConfigNodeNoLock()
 	if err != nil {
 		return err
@@ -212,8 +221,11 @@ func DeleteServer(name string) error {
 //
 // Deprecated: This API is deprecated. Use DeleteContext instead.
 func RemoveServer(name string) error {
-	AcquireTanzuConfigLock()
-	defer ReleaseTanzuConfigLock()
+	unlock, err := acquireTanzuConfigLock()
+	if err != nil {
+		return err
+	}
+	defer unlock.Unlock()
 	node, err := getClientConfigNodeNoLock()
 	if err != nil {
 		return err
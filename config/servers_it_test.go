@@ -114,7 +114,8 @@ current: test-mc2
 currentContext:
     kubernetes: test-mc
 `
-	expectedCfg2 := `contexts:
+	expectedCfg2 := `apiVersion: config.tanzu.vmware.com/v1alpha3
+contexts:
     - name: test-mc
       target: kubernetes
       group: one
@@ -135,6 +136,7 @@ currentContext:
             annotation: one
             required: true
           contextType: tmc
+      contextType: kubernetes
     - name: test-mc2
       target: kubernetes
       contextType: kubernetes
@@ -253,7 +255,8 @@ func TestServersIntegration(t *testing.T) {
 func TestServersIntegrationAndMigratedToNewConfig(t *testing.T) {
 	// Setup config data
 	cfg, _, cfg2, _ := setupServersTestData()
-	expectedCfg2 := `contexts:
+	expectedCfg2 := `apiVersion: config.tanzu.vmware.com/v1alpha3
+contexts:
     - name: test-mc
       target: kubernetes
       group: one
@@ -274,6 +277,7 @@ func TestServersIntegrationAndMigratedToNewConfig(t *testing.T) {
             annotation: one
             required: true
           contextType: tmc
+      contextType: kubernetes
     - name: test-mc2
       target: kubernetes
       contextType: kubernetes
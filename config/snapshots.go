@@ -0,0 +1,446 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+
+	"github.com/vmware-tanzu/tanzu-plugin-runtime/config/nodeutils"
+	configtypes "github.com/vmware-tanzu/tanzu-plugin-runtime/config/types"
+	"github.com/vmware-tanzu/tanzu-plugin-runtime/log"
+)
+
+const (
+	// CfgSnapshotsDirName is the name of the directory, next to the config.yaml, in which config snapshots are stored
+	CfgSnapshotsDirName = ".config-snapshots"
+
+	// cfgSnapshotInfoName is the name of the file describing a snapshot
+	cfgSnapshotInfoName = "snapshot.yaml"
+
+	// DefaultMaxConfigSnapshots is the default number of config snapshots to retain
+	DefaultMaxConfigSnapshots = 10
+)
+
+// MaxConfigSnapshots is the number of config snapshots to retain. Setting it to 0 disables snapshots.
+var MaxConfigSnapshots = DefaultMaxConfigSnapshots
+
+// ConfigSnapshot describes a version of config.yaml, config-ng.yaml and the config metadata file
+type ConfigSnapshot struct {
+	// ID of the snapshot
+	ID string `json:"id" yaml:"id"`
+	// CreatedAt is the time at which the snapshot was taken
+	CreatedAt time.Time `json:"createdAt" yaml:"createdAt"`
+	// Plugin is the name of the binary that was updating the config when the snapshot was taken
+	Plugin string `json:"plugin,omitempty" yaml:"plugin,omitempty"`
+}
+
+// SnapshotChange describes a value of a config file that differs between a snapshot and the current config
+type SnapshotChange struct {
+	// File is the name of the config file, e.g. config.yaml
+	File string `json:"file" yaml:"file"`
+	// Change from the snapshot to the current config, e.g. contexts[test-mc].clusterOpts.path
+	nodeutils.Change `json:",inline" yaml:",inline"`
+}
+
+// snapshotFile is a config file captured by a snapshot
+type snapshotFile struct {
+	name       string
+	pathGetter func() (string, error)
+}
+
+// snapshotFiles returns the config files captured by a snapshot
+func snapshotFiles() []snapshotFile {
+	return []snapshotFile{
+		{name: ConfigName, pathGetter: ClientConfigPath},
+		{name: CfgNextGenName, pathGetter: ClientConfigNextGenPath},
+		{name: CfgMetadataName, pathGetter: CfgMetadataFilePath},
+	}
+}
+
+// snapshotsDir returns the directory in which the config snapshots are stored
+func snapshotsDir() (string, error) {
+	cfgPath, err := ClientConfigPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(cfgPath), CfgSnapshotsDirName), nil
+}
+
+// ListSnapshots returns the config snapshots ordered from newest to oldest
+func ListSnapshots() ([]*ConfigSnapshot, error) {
+	// Acquire tanzu config lock
+	unlock, err := acquireTanzuConfigLock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock.Unlock()
+	return listSnapshots()
+}
+
+// RestoreSnapshot restores config.yaml, config-ng.yaml and the config metadata file to the
+// versions captured by the snapshot. The current config is snapshotted before it is replaced,
+// so a restore can itself be rolled back. The secret store is not captured by the snapshots, so
+// a snapshot referencing secrets that are no longer in the secret store is not restored.
+func RestoreSnapshot(id string) error {
+	// Acquire tanzu config lock and tanzu metadata lock
+	unlock, err := acquireTanzuConfigLock()
+	if err != nil {
+		return err
+	}
+	defer unlock.Unlock()
+	unlockMetadata, err := acquireTanzuMetadataLock()
+	if err != nil {
+		return err
+	}
+	defer unlockMetadata.Unlock()
+	if err := recoverConfigCommit(); err != nil {
+		return err
+	}
+
+	dir, err := snapshotDir(id)
+	if err != nil {
+		return err
+	}
+
+	var writes []fileWrite
+	contents := make(map[string][]byte)
+	for _, f := range snapshotFiles() {
+		path, err := f.pathGetter()
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(filepath.Join(dir, f.name))
+		if err != nil && !os.IsNotExist(err) {
+			return errors.Wrapf(err, "failed to read %s of snapshot %s", f.name, id)
+		}
+		// A file missing from the snapshot did not exist, which is equivalent to an empty file
+		writes = append(writes, fileWrite{path: path, data: data})
+		contents[f.name] = data
+	}
+	if err := checkSnapshotSecrets(id, contents); err != nil {
+		return err
+	}
+
+	if err := takeSnapshot(); err != nil {
+		return errors.Wrap(err, "failed to snapshot the current config")
+	}
+	return commitFiles(writes)
+}
+
+// DiffSnapshot compares the config captured by the snapshot with the current config and
+// returns the values that were added, removed or modified since the snapshot. The items of
+// the sequences are matched as by Diff, e.g. the contexts by name.
+func DiffSnapshot(id string) ([]SnapshotChange, error) {
+	// Acquire tanzu config lock
+	unlock, err := acquireTanzuConfigLock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock.Unlock()
+
+	dir, err := snapshotDir(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []SnapshotChange
+	for _, f := range snapshotFiles() {
+		path, err := f.pathGetter()
+		if err != nil {
+			return nil, err
+		}
+		snapshotNode, err := readSnapshotNode(filepath.Join(dir, f.name))
+		if err != nil {
+			return nil, err
+		}
+		currentNode, err := readSnapshotNode(path)
+		if err != nil {
+			return nil, err
+		}
+		fileChanges, err := nodeutils.Diff(snapshotNode, currentNode, clientConfigDiffOptions()...)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to compare %s", f.name)
+		}
+		for _, c := range fileChanges {
+			changes = append(changes, SnapshotChange{File: f.name, Change: c})
+		}
+	}
+	return changes, nil
+}
+
+// takeSnapshot captures the current config files as a new snapshot and prunes the oldest
+// snapshots exceeding MaxConfigSnapshots. No snapshot is taken if the config did not change
+// since the latest snapshot.
+// Pre-reqs: tanzu config lock is acquired
+func takeSnapshot() error {
+	if MaxConfigSnapshots <= 0 {
+		return nil
+	}
+	baseDir, err := snapshotsDir()
+	if err != nil {
+		return err
+	}
+
+	contents := make(map[string][]byte)
+	for _, f := range snapshotFiles() {
+		path, err := f.pathGetter()
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		contents[f.name] = data
+	}
+
+	snapshots, err := listSnapshots()
+	if err != nil {
+		return err
+	}
+	if len(snapshots) > 0 && snapshotContentEqual(filepath.Join(baseDir, snapshots[0].ID), contents) {
+		return nil
+	}
+
+	snapshot := &ConfigSnapshot{
+		CreatedAt: time.Now().UTC(),
+		Plugin:    filepath.Base(os.Args[0]),
+	}
+	snapshot.ID = snapshot.CreatedAt.Format("20060102T150405.000000000Z")
+	dir := filepath.Join(baseDir, snapshot.ID)
+	for i := 1; ; i++ {
+		exists, err := fileExists(dir)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			break
+		}
+		snapshot.ID = fmt.Sprintf("%s-%d", snapshot.CreatedAt.Format("20060102T150405.000000000Z"), i)
+		dir = filepath.Join(baseDir, snapshot.ID)
+	}
+
+	// Populate the snapshot in a staging directory so that a partial snapshot is never listed
+	stagingDir, err := os.MkdirTemp(baseDir, ".tmp-")
+	if os.IsNotExist(err) {
+		if err = os.MkdirAll(baseDir, 0o700); err == nil {
+			stagingDir, err = os.MkdirTemp(baseDir, ".tmp-")
+		}
+	}
+	if err != nil {
+		return err
+	}
+	for name, data := range contents {
+		if data == nil {
+			continue
+		}
+		if err := os.WriteFile(filepath.Join(stagingDir, name), data, 0600); err != nil {
+			_ = os.RemoveAll(stagingDir)
+			return err
+		}
+	}
+	info, err := yaml.Marshal(snapshot)
+	if err != nil {
+		_ = os.RemoveAll(stagingDir)
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(stagingDir, cfgSnapshotInfoName), info, 0600); err != nil {
+		_ = os.RemoveAll(stagingDir)
+		return err
+	}
+	if err := os.Rename(stagingDir, dir); err != nil {
+		_ = os.RemoveAll(stagingDir)
+		return err
+	}
+
+	return pruneSnapshots(baseDir, append([]*ConfigSnapshot{snapshot}, snapshots...))
+}
+
+// snapshotBeforeWrite takes a snapshot of the config before it is overwritten.
+// Failing to take a snapshot does not prevent the config from being updated.
+// Pre-reqs: tanzu config lock is acquired
+func snapshotBeforeWrite() {
+	if err := takeSnapshot(); err != nil {
+		log.Warningf("Failed to take a snapshot of the tanzu config: %v", err)
+	}
+}
+
+// pruneSnapshots removes the oldest snapshots exceeding MaxConfigSnapshots.
+// The snapshots have to be ordered from newest to oldest.
+func pruneSnapshots(baseDir string, snapshots []*ConfigSnapshot) error {
+	if len(snapshots) <= MaxConfigSnapshots {
+		return nil
+	}
+	for _, s := range snapshots[MaxConfigSnapshots:] {
+		if err := os.RemoveAll(filepath.Join(baseDir, s.ID)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// listSnapshots returns the config snapshots ordered from newest to oldest
+// Pre-reqs: tanzu config lock is acquired
+func listSnapshots() ([]*ConfigSnapshot, error) {
+	baseDir, err := snapshotsDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(baseDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read config snapshots")
+	}
+
+	var snapshots []*ConfigSnapshot
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		snapshot, err := readSnapshotInfo(filepath.Join(baseDir, entry.Name()))
+		if err != nil {
+			// Skip staging directories and unreadable snapshots
+			continue
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	sort.SliceStable(snapshots, func(i, j int) bool {
+		if snapshots[i].CreatedAt.Equal(snapshots[j].CreatedAt) {
+			return snapshots[i].ID > snapshots[j].ID
+		}
+		return snapshots[i].CreatedAt.After(snapshots[j].CreatedAt)
+	})
+	return snapshots, nil
+}
+
+// snapshotDir returns the directory of an existing snapshot
+func snapshotDir(id string) (string, error) {
+	if id == "" {
+		return "", errors.New("snapshot id cannot be empty")
+	}
+	baseDir, err := snapshotsDir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(baseDir, filepath.Base(id))
+	if _, err := readSnapshotInfo(dir); err != nil {
+		return "", fmt.Errorf("snapshot %v not found", id)
+	}
+	return dir, nil
+}
+
+func readSnapshotInfo(dir string) (*ConfigSnapshot, error) {
+	data, err := os.ReadFile(filepath.Join(dir, cfgSnapshotInfoName))
+	if err != nil {
+		return nil, err
+	}
+	snapshot := &ConfigSnapshot{}
+	if err := yaml.Unmarshal(data, snapshot); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+func snapshotContentEqual(dir string, contents map[string][]byte) bool {
+	for name, data := range contents {
+		snapshotData, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil && !os.IsNotExist(err) {
+			return false
+		}
+		if !bytes.Equal(snapshotData, data) {
+			return false
+		}
+	}
+	return true
+}
+
+// readSnapshotNode reads a config file as a yaml document node. A missing or empty file is an empty document.
+func readSnapshotNode(path string) (*yaml.Node, error) {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	node := &yaml.Node{}
+	if len(data) != 0 {
+		if err := yaml.Unmarshal(data, node); err != nil {
+			return nil, errors.Wrapf(err, "failed to parse %s", path)
+		}
+	}
+	if len(node.Content) == 0 {
+		node = &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	return node, nil
+}
+
+// checkSnapshotSecrets refuses to restore a snapshot whose contexts reference secrets that are no longer
+// in the secret store, e.g. the tokens of a context updated or deleted since the snapshot was taken.
+// The secret store is not captured by the snapshots as it can be external to the tanzu config dir.
+func checkSnapshotSecrets(id string, contents map[string][]byte) error {
+	refs := make(map[string][]string)
+	for _, name := range []string{ConfigName, CfgNextGenName} {
+		if len(bytes.TrimSpace(contents[name])) == 0 {
+			continue
+		}
+		cfg := &configtypes.ClientConfig{}
+		if err := yaml.Unmarshal(contents[name], cfg); err != nil {
+			return errors.Wrapf(err, "failed to parse %s of snapshot %s", name, id)
+		}
+		for _, c := range cfg.KnownContexts {
+			refs[c.Name] = append(refs[c.Name], contextSecretRefs(c)...)
+		}
+	}
+
+	var store SecretStore
+	var missing []string
+	for _, name := range sortedKeys(refs) {
+		for _, ref := range refs[name] {
+			if store == nil {
+				var err error
+				if store, err = resolveSecretStore(snapshotMetadataSettings(contents[CfgMetadataName])); err != nil {
+					return err
+				}
+				if store == nil {
+					return errors.Errorf("cannot restore snapshot %s, its contexts reference secrets but no secret store is configured", id)
+				}
+			}
+			_, err := store.Get(ref)
+			if errors.Is(err, ErrSecretNotFound) {
+				missing = append(missing, name)
+				break
+			}
+			if err != nil {
+				return errors.Wrapf(err, "failed to check the secrets of context %s of snapshot %s", name, id)
+			}
+		}
+	}
+	if len(missing) != 0 {
+		return errors.Errorf("cannot restore snapshot %s, the secrets of contexts %s are no longer in the secret store", id, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// snapshotMetadataSettings returns a getter of the config metadata settings of the snapshot
+func snapshotMetadataSettings(data []byte) func() (map[string]string, error) {
+	return func() (map[string]string, error) {
+		if len(bytes.TrimSpace(data)) == 0 {
+			return nil, nil
+		}
+		node := &yaml.Node{}
+		if err := yaml.Unmarshal(data, node); err != nil {
+			return nil, errors.Wrapf(err, "failed to parse %s", CfgMetadataName)
+		}
+		return getSettings(node)
+	}
+}
@@ -0,0 +1,197 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vmware-tanzu/tanzu-plugin-runtime/config/nodeutils"
+	configtypes "github.com/vmware-tanzu/tanzu-plugin-runtime/config/types"
+)
+
+// setupSnapshotTestConfig points the config files to an empty directory so that the
+// snapshots taken by the test are isolated
+func setupSnapshotTestConfig(t *testing.T) (cleanup func()) {
+	dir, err := os.MkdirTemp("", "tanzu_snapshots")
+	assert.NoError(t, err)
+
+	for key, name := range map[string]string{
+		EnvConfigKey:         ConfigName,
+		EnvConfigNextGenKey:  CfgNextGenName,
+		EnvConfigMetadataKey: CfgMetadataName,
+	} {
+		err = os.Setenv(key, filepath.Join(dir, name))
+		assert.NoError(t, err)
+	}
+
+	return func() {
+		for _, key := range []string{EnvConfigKey, EnvConfigNextGenKey, EnvConfigMetadataKey} {
+			_ = os.Unsetenv(key)
+		}
+		_ = os.RemoveAll(dir)
+	}
+}
+
+func TestSnapshotsRestore(t *testing.T) {
+	cleanup := setupSnapshotTestConfig(t)
+	defer cleanup()
+
+	snapshots, err := ListSnapshots()
+	assert.NoError(t, err)
+	assert.Empty(t, snapshots)
+
+	ctx := &configtypes.Context{
+		Name:        "test-mc",
+		ContextType: configtypes.ContextTypeK8s,
+		ClusterOpts: &configtypes.ClusterServer{
+			Endpoint: "test-endpoint",
+			Path:     "test-path",
+			Context:  "test-context",
+		},
+	}
+	err = SetContext(ctx, true)
+	assert.NoError(t, err)
+
+	// Snapshot of the config without any file
+	snapshots, err = ListSnapshots()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(snapshots))
+	assert.NotEmpty(t, snapshots[0].Plugin)
+
+	err = SetEnv("env1", "val1")
+	assert.NoError(t, err)
+
+	// Snapshot of the config with the context
+	snapshots, err = ListSnapshots()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(snapshots))
+	withContext := snapshots[0].ID
+	initial := snapshots[1].ID
+
+	changes, err := DiffSnapshot(withContext)
+	assert.NoError(t, err)
+	assert.Equal(t, []SnapshotChange{{File: ConfigName, Change: nodeutils.Change{
+		Type: nodeutils.ChangeAdded,
+		Path: "clientOptions",
+		New:  map[string]interface{}{"env": map[string]interface{}{"env1": "val1"}},
+	}}}, changes)
+
+	changes, err = DiffSnapshot(initial)
+	assert.NoError(t, err)
+	var paths []string
+	for _, c := range changes {
+		paths = append(paths, fmt.Sprintf("%s %s %s", c.File, c.Type, c.Path))
+	}
+	assert.Contains(t, paths, "config-ng.yaml added contexts")
+	assert.Contains(t, paths, "config-ng.yaml added currentContext")
+
+	err = RestoreSnapshot(withContext)
+	assert.NoError(t, err)
+
+	_, err = GetEnv("env1")
+	assert.Equal(t, "not found", err.Error())
+	c, err := GetContext("test-mc")
+	assert.NoError(t, err)
+	assert.Equal(t, ctx, c)
+
+	// The restore itself can be rolled back
+	snapshots, err = ListSnapshots()
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(snapshots))
+	err = RestoreSnapshot(snapshots[0].ID)
+	assert.NoError(t, err)
+	val, err := GetEnv("env1")
+	assert.NoError(t, err)
+	assert.Equal(t, "val1", val)
+
+	err = RestoreSnapshot(initial)
+	assert.NoError(t, err)
+	ok, err := ContextExists("test-mc")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	err = RestoreSnapshot("unknown")
+	assert.Equal(t, "snapshot unknown not found", err.Error())
+	_, err = DiffSnapshot("")
+	assert.Equal(t, "snapshot id cannot be empty", err.Error())
+}
+
+func TestSnapshotsRestoreWithReleasedSecrets(t *testing.T) {
+	cleanup := setupSnapshotTestConfig(t)
+	dir, err := os.MkdirTemp("", "tanzu_secrets")
+	assert.NoError(t, err)
+
+	defer func() {
+		cleanup()
+		_ = os.RemoveAll(dir)
+		SetSecretStore(nil)
+	}()
+
+	SetSecretStore(NewFileSecretStoreAt(filepath.Join(dir, SecretsFileName), filepath.Join(dir, SecretsKeyFileName)))
+
+	ctx := &configtypes.Context{
+		Name:        "test-tmc",
+		ContextType: configtypes.ContextTypeTMC,
+		GlobalOpts: &configtypes.GlobalServer{
+			Endpoint: "test-endpoint",
+			Auth:     configtypes.GlobalServerAuth{AccessToken: "access-token-1"},
+		},
+	}
+	err = SetContext(ctx, true)
+	assert.NoError(t, err)
+	err = SetEnv("env1", "val1")
+	assert.NoError(t, err)
+
+	// The snapshot of the first token can be restored while the token is in the secret store
+	snapshots, err := ListSnapshots()
+	assert.NoError(t, err)
+	withFirstToken := snapshots[0].ID
+	err = RestoreSnapshot(withFirstToken)
+	assert.NoError(t, err)
+
+	// Updating the token releases the secret of the first token
+	ctx.GlobalOpts.Auth.AccessToken = "access-token-2"
+	err = SetContext(ctx, true)
+	assert.NoError(t, err)
+
+	err = RestoreSnapshot(withFirstToken)
+	assert.ErrorContains(t, err, "the secrets of contexts test-tmc are no longer in the secret store")
+	c, err := GetContext("test-tmc")
+	assert.NoError(t, err)
+	assert.Equal(t, "access-token-2", c.GlobalOpts.Auth.AccessToken)
+}
+
+func TestSnapshotsPrune(t *testing.T) {
+	cleanup := setupSnapshotTestConfig(t)
+	defer cleanup()
+
+	maxSnapshots := MaxConfigSnapshots
+	MaxConfigSnapshots = 2
+	defer func() {
+		MaxConfigSnapshots = maxSnapshots
+	}()
+
+	for _, val := range []string{"val1", "val2", "val3", "val4"} {
+		err := SetEnv("env1", val)
+		assert.NoError(t, err)
+	}
+	// Setting an unchanged value does not take a new snapshot
+	err := SetEnv("env1", "val4")
+	assert.NoError(t, err)
+
+	snapshots, err := ListSnapshots()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(snapshots))
+
+	err = RestoreSnapshot(snapshots[0].ID)
+	assert.NoError(t, err)
+	val, err := GetEnv("env1")
+	assert.NoError(t, err)
+	assert.Equal(t, "val3", val)
+}
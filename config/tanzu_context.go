@@ -13,7 +13,8 @@ import (
 	"github.com/pkg/errors"
 	"gopkg.in/yaml.v3"
 
-	"github.com/vmware-tanzu/tanzu-plugin-runtime/config/internal/kubeconfig"
+	"github.com/vmware-tanzu/tanzu-plugin-runtime/config/internal/atomicfile"
+	"github.com/vmware-tanzu/tanzu-plugin-runtime/config/kubeconfig"
 	configtypes "github.com/vmware-tanzu/tanzu-plugin-runtime/config/types"
 )
 
@@ -45,6 +46,8 @@ type ResourceInfo struct {
 type cmdOptions struct {
 	outWriter io.Writer
 	errWriter io.Writer
+	// useCLI delegates the operation to the CLI binary referenced by TANZU_BIN
+	useCLI bool
 }
 
 type CommandOptions func(o *cmdOptions)
@@ -70,6 +73,14 @@ func WithNoStdout() CommandOptions {
 	}
 }
 
+// WithCLIStrategy specifies to delegate the operation to the CLI binary referenced by
+// the TANZU_BIN environment variable instead of performing it in process
+func WithCLIStrategy() CommandOptions {
+	return func(o *cmdOptions) {
+		o.useCLI = true
+	}
+}
+
 // WithNoStderr specifies to ignore stderr
 func WithNoStderr() CommandOptions {
 	return func(o *cmdOptions) {
@@ -99,8 +110,16 @@ func runCommand(commandPath string, args []string, opts *cmdOptions) (bytes.Buff
 	return stdout, stderr, command.Run()
 }
 
-// GetKubeconfigForContext returns the kubeconfig for any arbitrary Tanzu resource in the Tanzu object hierarchy
-// referred by the Tanzu context
+// GetKubeconfigForContext returns the kubeconfig of the context.
+//
+// For a kubernetes context, the kubeconfig of ClusterOpts.Path is minified to ClusterOpts.Context and flattened,
+// inlining the referenced certificate and token files.
+//
+// For a mission-control context, the kubeconfig points to the endpoint of the context and authenticates with an
+// exec credential plugin entry calling back into the CLI to get a token from the global server auth of the context.
+//
+// For a Tanzu context, the kubeconfig is for any arbitrary Tanzu resource in the Tanzu object hierarchy
+// referred by the Tanzu context. Project and space names are only supported for Tanzu contexts.
 // Pre-reqs: project and space names should be valid
 //
 // Notes:
@@ -120,20 +139,29 @@ func GetKubeconfigForContext(contextName, projectName, spaceName string) ([]byte
 	if err != nil {
 		return nil, err
 	}
-	if ctx.ContextType != configtypes.ContextTypeTanzu {
-		return nil, errors.Errorf("context must be of type: %s", configtypes.ContextTypeTanzu)
+	if ctx.ContextType != configtypes.ContextTypeTanzu && (projectName != "" || spaceName != "") {
+		return nil, errors.Errorf("context must be of type: %s to get the kubeconfig of a project or space", configtypes.ContextTypeTanzu)
 	}
 
-	kc, err := kubeconfig.ReadKubeConfig(ctx.ClusterOpts.Path)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to read the Tanzu context kubeconfig")
+	contextType := ctx.ContextType
+	if contextType == "" {
+		contextType = configtypes.ConvertTargetToContextType(ctx.Target)
 	}
 
-	kc, err = kubeconfig.MinifyKubeConfig(kc, ctx.ClusterOpts.Context)
+	var kc *kubeconfig.Config
+	switch contextType {
+	case configtypes.ContextTypeTanzu:
+		kc, err = getKubeconfigForTanzuContext(ctx, projectName, spaceName)
+	case configtypes.ContextTypeK8s:
+		kc, err = getKubeconfigForKubernetesContext(ctx)
+	case configtypes.ContextTypeTMC:
+		kc, err = getKubeconfigForMissionControlContext(ctx)
+	default:
+		err = errors.Errorf("unsupported context type: %s", ctx.ContextType)
+	}
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to minify the kubeconfig")
+		return nil, err
 	}
-	updateKubeconfigServerURL(kc, ctx, projectName, spaceName)
 
 	kubeconfigBytes, err := yaml.Marshal(kc)
 	if err != nil {
@@ -142,6 +170,21 @@ func GetKubeconfigForContext(contextName, projectName, spaceName string) ([]byte
 	return kubeconfigBytes, nil
 }
 
+// getKubeconfigForTanzuContext returns the kubeconfig of the Tanzu context pointing to the project or space
+func getKubeconfigForTanzuContext(ctx *configtypes.Context, projectName, spaceName string) (*kubeconfig.Config, error) {
+	kc, err := kubeconfig.ReadKubeConfig(ctx.ClusterOpts.Path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read the Tanzu context kubeconfig")
+	}
+
+	kc, err = kubeconfig.MinifyKubeConfig(kc, ctx.ClusterOpts.Context)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to minify the kubeconfig")
+	}
+	updateKubeconfigServerURL(kc, ctx, projectName, spaceName)
+	return kc, nil
+}
+
 func prepareClusterServerURL(context *configtypes.Context, projectName, spaceName string) string {
 	serverURL := context.ClusterOpts.Endpoint
 	if projectName == "" {
@@ -171,15 +214,86 @@ func updateKubeconfigServerURL(kc *kubeconfig.Config, cliContext *configtypes.Co
 //   - a space as active resource, both project and space names are required
 //   - a project as active resource, only project name is required (space should be empty string)
 //   - org as active resource, both project and space names should be empty strings
+//
+// The context metadata and the server URL of the kubeconfig are updated in process. Use WithCLIStrategy
+// to delegate the update to the CLI binary referenced by the TANZU_BIN environment variable instead.
 func SetTanzuContextActiveResource(contextName, projectName, spaceName string, opts ...CommandOptions) error {
-	// For now, the implementation expects env var TANZU_BIN to be set and
-	// pointing to the core CLI binary used to invoke setting the active Tanzu resource.
-
 	options := &cmdOptions{}
 	for _, opt := range opts {
 		opt(options)
 	}
+	if options.useCLI {
+		return setTanzuContextActiveResourceWithCLI(contextName, projectName, spaceName, options)
+	}
+	return setTanzuContextActiveResource(contextName, projectName, spaceName)
+}
+
+// setTanzuContextActiveResource updates the active resource in the context metadata and the server URL of the
+// kubeconfig referenced by the context. The kubeconfig is restored if the client config cannot be persisted.
+func setTanzuContextActiveResource(contextName, projectName, spaceName string) error {
+	if projectName == "" && spaceName != "" {
+		return errors.New("project name is required to set a space as active resource")
+	}
+
+	var restoreKubeconfig func()
+	err := Update(func(tx *ConfigTx) error {
+		ctx, err := getContext(tx.node, contextName)
+		if err != nil {
+			return err
+		}
+		if ctx.ContextType != configtypes.ContextTypeTanzu {
+			return errors.Errorf("context must be of type: %s", configtypes.ContextTypeTanzu)
+		}
+
+		for key, value := range map[string]string{ProjectNameKey: projectName, SpaceNameKey: spaceName} {
+			valueNode := &yaml.Node{}
+			if err := valueNode.Encode(value); err != nil {
+				return err
+			}
+			persist, err := setContextMetadataNode(tx.node, contextName, key, valueNode)
+			if err != nil {
+				return err
+			}
+			tx.markPersist(persist)
+		}
+
+		if ctx.ClusterOpts != nil && ctx.ClusterOpts.Path != "" && ctx.ClusterOpts.Context != "" {
+			restore, err := backupKubeconfigFile(ctx.ClusterOpts.Path)
+			if err != nil {
+				return errors.Wrap(err, "failed to read the Tanzu context kubeconfig")
+			}
+			serverURL := prepareClusterServerURL(ctx, projectName, spaceName)
+			if err := kubeconfig.SetClusterServer(ctx.ClusterOpts.Path, ctx.ClusterOpts.Context, serverURL); err != nil {
+				return errors.Wrap(err, "failed to update the Tanzu context kubeconfig")
+			}
+			restoreKubeconfig = restore
+		}
+		return nil
+	})
+	// The kubeconfig is updated last, so an error after its update comes from persisting the config
+	if err != nil && restoreKubeconfig != nil {
+		restoreKubeconfig()
+	}
+	return err
+}
+
+// backupKubeconfigFile reads the kubeconfig file and returns a function atomically restoring its content and permissions
+func backupKubeconfigFile(path string) (func(), error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return func() {
+		_ = atomicfile.WriteFile(path, previous, info.Mode().Perm())
+	}, nil
+}
 
+// setTanzuContextActiveResourceWithCLI delegates the update of the active resource to the CLI binary referenced by TANZU_BIN
+func setTanzuContextActiveResourceWithCLI(contextName, projectName, spaceName string, options *cmdOptions) error {
 	cliPath := os.Getenv("TANZU_BIN")
 	if cliPath == "" {
 		return errors.New("the environment variable TANZU_BIN is not set")
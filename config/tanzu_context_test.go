@@ -5,16 +5,19 @@ package config
 
 import (
 	"bytes"
+	"encoding/base64"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"gopkg.in/yaml.v3"
 
-	"github.com/vmware-tanzu/tanzu-plugin-runtime/config/internal/kubeconfig"
+	"github.com/vmware-tanzu/tanzu-plugin-runtime/config/kubeconfig"
+	configtypes "github.com/vmware-tanzu/tanzu-plugin-runtime/config/types"
 )
 
 const (
@@ -136,6 +139,123 @@ func TestGetKubeconfigForContext(t *testing.T) {
 	assert.ErrorContains(t, err, "context must be of type: tanzu")
 }
 
+func TestGetKubeconfigForKubernetesContext(t *testing.T) {
+	err := setupForGetContext()
+	assert.NoError(t, err)
+
+	dir, err := os.MkdirTemp("", "kubeconfig")
+	assert.NoError(t, err)
+	defer func() {
+		cleanupTestingDir(t)
+		_ = os.RemoveAll(dir)
+	}()
+
+	err = os.WriteFile(filepath.Join(dir, "ca.crt"), []byte("fake-ca"), 0o600)
+	assert.NoError(t, err)
+	kubeconfigFile := filepath.Join(dir, "config")
+	err = os.WriteFile(kubeconfigFile, []byte(`apiVersion: v1
+kind: Config
+current-context: foo-context
+clusters:
+  - cluster:
+      certificate-authority: ca.crt
+      server: https://foo.org:443
+    name: foo-cluster
+  - cluster:
+      server: https://bar.org:443
+    name: bar-cluster
+contexts:
+  - context:
+      cluster: foo-cluster
+      user: foo-user
+    name: foo-context
+  - context:
+      cluster: bar-cluster
+      user: bar-user
+    name: bar-context
+users:
+  - name: foo-user
+    user:
+      token: foo-token
+  - name: bar-user
+    user:
+      token: bar-token
+`), 0o600)
+	assert.NoError(t, err)
+
+	c, err := GetContext("test-mc")
+	assert.NoError(t, err)
+	c.ClusterOpts.Path = kubeconfigFile
+	c.ClusterOpts.Context = "foo-context"
+	err = SetContext(c, false)
+	assert.NoError(t, err)
+
+	kubeconfigBytes, err := GetKubeconfigForContext(c.Name, "", "")
+	assert.NoError(t, err)
+	var kc kubeconfig.Config
+	err = yaml.Unmarshal(kubeconfigBytes, &kc)
+	assert.NoError(t, err)
+	assert.Equal(t, "foo-context", kc.CurrentContext)
+	assert.Len(t, kc.Clusters, 1)
+	assert.Len(t, kc.Contexts, 1)
+	assert.Len(t, kc.AuthInfos, 1)
+	cluster := kubeconfig.GetCluster(&kc, "foo-cluster")
+	assert.Empty(t, cluster.Cluster.CertificateAuthority)
+	assert.Equal(t, base64.StdEncoding.EncodeToString([]byte("fake-ca")), cluster.Cluster.CertificateAuthorityData)
+}
+
+func TestGetKubeconfigForMissionControlContext(t *testing.T) {
+	err := setupForGetContext()
+	assert.NoError(t, err)
+	defer cleanupTestingDir(t)
+
+	os.Setenv("TANZU_BIN", "/usr/local/bin/tanzu")
+	defer os.Unsetenv("TANZU_BIN")
+
+	err = SetCert(&configtypes.Cert{Host: "test-endpoint", SkipCertVerify: "true"})
+	assert.NoError(t, err)
+
+	kubeconfigBytes, err := GetKubeconfigForContext("test-tmc", "", "")
+	assert.NoError(t, err)
+	var kc kubeconfig.Config
+	err = yaml.Unmarshal(kubeconfigBytes, &kc)
+	assert.NoError(t, err)
+	assert.Equal(t, "tanzu-cli-test-tmc", kc.CurrentContext)
+	cluster := kubeconfig.GetCluster(&kc, "tanzu-cli-test-tmc")
+	assert.Equal(t, "https://test-endpoint", cluster.Cluster.Server)
+	assert.True(t, cluster.Cluster.InsecureSkipTLSVerify)
+	context := kubeconfig.GetContext(&kc, "tanzu-cli-test-tmc")
+	assert.Equal(t, "tanzu-cli-test-tmc-user", context.Context.AuthInfo)
+
+	authInfo := kubeconfig.GetAuthInfo(&kc, "tanzu-cli-test-tmc-user")
+	user, ok := authInfo.AuthInfo.(map[string]interface{})
+	assert.True(t, ok)
+	exec, ok := user["exec"].(map[string]interface{})
+	assert.True(t, ok)
+	assert.Equal(t, "client.authentication.k8s.io/v1", exec["apiVersion"])
+	assert.Equal(t, "/usr/local/bin/tanzu", exec["command"])
+	assert.Equal(t, []interface{}{"context", "get-token", "test-tmc"}, exec["args"])
+	assert.Equal(t, "Never", exec["interactiveMode"])
+
+	// The CA certificates stored as is are base64 encoded and an insecure host is not verified
+	caCertData, err := base64.StdEncoding.DecodeString(newTestCACertData(t, "test-endpoint", time.Now().Add(time.Hour)))
+	assert.NoError(t, err)
+	err = SetCert(&configtypes.Cert{Host: "test-endpoint", CACertData: string(caCertData), Insecure: "true"})
+	assert.NoError(t, err)
+	kubeconfigBytes, err = GetKubeconfigForContext("test-tmc", "", "")
+	assert.NoError(t, err)
+	kc = kubeconfig.Config{}
+	err = yaml.Unmarshal(kubeconfigBytes, &kc)
+	assert.NoError(t, err)
+	cluster = kubeconfig.GetCluster(&kc, "tanzu-cli-test-tmc")
+	assert.Equal(t, base64.StdEncoding.EncodeToString(caCertData), cluster.Cluster.CertificateAuthorityData)
+	assert.True(t, cluster.Cluster.InsecureSkipTLSVerify)
+
+	// Project and space are only supported for Tanzu contexts
+	_, err = GetKubeconfigForContext("test-tmc", "project1", "")
+	assert.ErrorContains(t, err, "context must be of type: tanzu")
+}
+
 func TestGetTanzuContextActiveResource(t *testing.T) {
 	err := setupForGetContext()
 	assert.NoError(t, err)
@@ -269,7 +389,7 @@ func TestSetTanzuContextActiveResource(t *testing.T) {
 
 			// Test-1:
 			// - verify correct string gets printed to default stdout and stderr
-			err = SetTanzuContextActiveResource("test-context", "projectA", "spaceA")
+			err = SetTanzuContextActiveResource("test-context", "projectA", "spaceA", WithCLIStrategy())
 			w.Close()
 			stdoutRecieved := <-c
 
@@ -284,7 +404,7 @@ func TestSetTanzuContextActiveResource(t *testing.T) {
 			// Test-2: when external stdout and stderr are provided with WithStdout, WithStderr options,
 			// verify correct string gets printed to provided custom stdout/stderr
 			var combinedOutputBuff bytes.Buffer
-			err = SetTanzuContextActiveResource("test-context", "projectA", "spaceA", WithOutputWriter(&combinedOutputBuff), WithErrorWriter(&combinedOutputBuff))
+			err = SetTanzuContextActiveResource("test-context", "projectA", "spaceA", WithCLIStrategy(), WithOutputWriter(&combinedOutputBuff), WithErrorWriter(&combinedOutputBuff))
 			if spec.expectedFailure {
 				assert.NotNil(err)
 			} else {
@@ -296,3 +416,94 @@ func TestSetTanzuContextActiveResource(t *testing.T) {
 		})
 	}
 }
+
+func TestSetTanzuContextActiveResourceInProcess(t *testing.T) {
+	err := setupForGetContext()
+	assert.NoError(t, err)
+
+	kubeconfigFilePath, err := os.CreateTemp("", "config")
+	assert.NoError(t, err)
+	err = copyFile("../fakes/config/kubeconfig-1.yaml", kubeconfigFilePath.Name())
+	assert.NoError(t, err)
+
+	defer func() {
+		cleanupTestingDir(t)
+		_ = os.RemoveAll(kubeconfigFilePath.Name())
+	}()
+
+	c, err := GetContext("test-tanzu")
+	assert.NoError(t, err)
+	c.ClusterOpts.Path = kubeconfigFilePath.Name()
+	c.ClusterOpts.Context = "tanzu-cli-mytanzu"
+	err = SetContext(c, false)
+	assert.NoError(t, err)
+
+	readServerURL := func() string {
+		kc, err := kubeconfig.ReadKubeConfig(kubeconfigFilePath.Name())
+		assert.NoError(t, err)
+		return kubeconfig.GetCluster(kc, "tanzu-cli-mytanzu/current").Cluster.Server
+	}
+
+	// TANZU_BIN is not required
+	os.Unsetenv("TANZU_BIN")
+
+	err = SetTanzuContextActiveResource("test-tanzu", "project1", "space1")
+	assert.NoError(t, err)
+	activeResources, err := GetTanzuContextActiveResource("test-tanzu")
+	assert.NoError(t, err)
+	assert.Equal(t, "fake-org-id", activeResources.OrgID)
+	assert.Equal(t, "project1", activeResources.ProjectName)
+	assert.Equal(t, "space1", activeResources.SpaceName)
+	assert.Equal(t, c.ClusterOpts.Endpoint+"/project/project1/space/space1", readServerURL())
+
+	err = SetTanzuContextActiveResource("test-tanzu", "project2", "")
+	assert.NoError(t, err)
+	activeResources, err = GetTanzuContextActiveResource("test-tanzu")
+	assert.NoError(t, err)
+	assert.Equal(t, "project2", activeResources.ProjectName)
+	assert.Empty(t, activeResources.SpaceName)
+	assert.Equal(t, c.ClusterOpts.Endpoint+"/project/project2", readServerURL())
+
+	err = SetTanzuContextActiveResource("test-tanzu", "", "")
+	assert.NoError(t, err)
+	assert.Equal(t, c.ClusterOpts.Endpoint, readServerURL())
+
+	// Other kubeconfig entries are preserved
+	kc, err := kubeconfig.ReadKubeConfig(kubeconfigFilePath.Name())
+	assert.NoError(t, err)
+	original, err := kubeconfig.ReadKubeConfig("../fakes/config/kubeconfig-1.yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, len(original.Clusters), len(kc.Clusters))
+	assert.Equal(t, len(original.AuthInfos), len(kc.AuthInfos))
+	assert.Equal(t, original.CurrentContext, kc.CurrentContext)
+
+	err = SetTanzuContextActiveResource("test-tanzu", "", "space1")
+	assert.ErrorContains(t, err, "project name is required")
+	err = SetTanzuContextActiveResource("test-mc", "project1", "")
+	assert.ErrorContains(t, err, "context must be of type: tanzu")
+}
+
+func TestBackupKubeconfigFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config")
+	err := os.WriteFile(path, []byte("previous"), 0o640)
+	assert.NoError(t, err)
+
+	restore, err := backupKubeconfigFile(path)
+	assert.NoError(t, err)
+	err = os.WriteFile(path, []byte("updated"), 0o600)
+	assert.NoError(t, err)
+	assert.NoError(t, os.Chmod(path, 0o600))
+
+	// The content and the permissions of the file are restored
+	restore()
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "previous", string(data))
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o640), info.Mode().Perm())
+
+	_, err = backupKubeconfigFile(filepath.Join(dir, "missing"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,254 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	configtypes "github.com/vmware-tanzu/tanzu-plugin-runtime/config/types"
+)
+
+const (
+	// DefaultTokenExpirySkew is the default time before the expiration at which an access token is refreshed
+	DefaultTokenExpirySkew = 30 * time.Second
+
+	// oidcDiscoveryPath is the path of the OIDC provider metadata relative to the issuer
+	oidcDiscoveryPath = "/.well-known/openid-configuration"
+)
+
+type tokenOptions struct {
+	httpClient *http.Client
+	clientID   string
+	expirySkew time.Duration
+	// rejectedToken is an access token rejected by the server that must be refreshed even if not expired
+	rejectedToken string
+}
+
+type TokenOptions func(o *tokenOptions)
+
+// WithTokenHTTPClient sets the HTTP client used to discover the token endpoint and refresh the tokens
+func WithTokenHTTPClient(client *http.Client) TokenOptions {
+	return func(o *tokenOptions) {
+		o.httpClient = client
+	}
+}
+
+// WithTokenClientID sets the OAuth2 client ID sent with the refresh token grant
+func WithTokenClientID(clientID string) TokenOptions {
+	return func(o *tokenOptions) {
+		o.clientID = clientID
+	}
+}
+
+// WithTokenExpirySkew sets the time before the expiration at which the access token is refreshed
+func WithTokenExpirySkew(skew time.Duration) TokenOptions {
+	return func(o *tokenOptions) {
+		o.expirySkew = skew
+	}
+}
+
+// withRejectedToken forces the refresh of the access token if it is still the token rejected by the server
+func withRejectedToken(token string) TokenOptions {
+	return func(o *tokenOptions) {
+		o.rejectedToken = token
+	}
+}
+
+// oidcProviderMetadata is the subset of the OIDC provider metadata used to refresh tokens
+type oidcProviderMetadata struct {
+	TokenEndpoint string `json:"token_endpoint"`
+}
+
+// tokenResponse is the response of the OAuth2 token endpoint
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	IDToken          string `json:"id_token"`
+	RefreshToken     string `json:"refresh_token"`
+	TokenType        string `json:"token_type"`
+	ExpiresIn        int64  `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// GetValidAccessToken returns the access token of the context, refreshing it first if it expires
+// within the expiry skew. The refresh performs the OAuth2 refresh token grant against the token
+// endpoint discovered from the issuer of the context and persists the new tokens with SetContext.
+// Concurrent refreshes, including from other processes, are serialized with the tanzu config lock
+// so that a refresh token is only redeemed once.
+func GetValidAccessToken(ctx context.Context, contextName string, opts ...TokenOptions) (string, error) {
+	options := &tokenOptions{
+		httpClient: http.DefaultClient,
+		expirySkew: DefaultTokenExpirySkew,
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	c, err := GetContext(contextName)
+	if err != nil {
+		return "", err
+	}
+	if err := validateTokenContext(c); err != nil {
+		return "", err
+	}
+	if !needsTokenRefresh(&c.GlobalOpts.Auth, options) {
+		return c.GlobalOpts.Auth.AccessToken, nil
+	}
+
+	var accessToken string
+	err = UpdateContext(ctx, func(tx *ConfigTx) error {
+		// The token may have been refreshed while waiting for the lock
+		c, err := tx.GetContext(contextName)
+		if err != nil {
+			return err
+		}
+		if err := validateTokenContext(c); err != nil {
+			return err
+		}
+		if !needsTokenRefresh(&c.GlobalOpts.Auth, options) {
+			accessToken = c.GlobalOpts.Auth.AccessToken
+			return nil
+		}
+		if err := refreshToken(ctx, &c.GlobalOpts.Auth, options); err != nil {
+			return errors.Wrapf(err, "failed to refresh the access token of context %s", contextName)
+		}
+		accessToken = c.GlobalOpts.Auth.AccessToken
+		return tx.SetContext(c, false)
+	})
+	if err != nil {
+		return "", err
+	}
+	return accessToken, nil
+}
+
+func validateTokenContext(c *configtypes.Context) error {
+	if c.GlobalOpts == nil {
+		return errors.Errorf("context %s has no global server auth", c.Name)
+	}
+	return nil
+}
+
+// needsTokenRefresh checks whether the access token is expired or was rejected by the server
+func needsTokenRefresh(auth *configtypes.GlobalServerAuth, options *tokenOptions) bool {
+	if options.rejectedToken != "" && auth.AccessToken == options.rejectedToken {
+		return true
+	}
+	return isTokenExpired(auth, options.expirySkew)
+}
+
+// isTokenExpired checks whether the access token is missing or expires within the skew.
+// A token without expiration is considered valid.
+func isTokenExpired(auth *configtypes.GlobalServerAuth, skew time.Duration) bool {
+	if auth.AccessToken == "" {
+		return true
+	}
+	if auth.Expiration.IsZero() {
+		return false
+	}
+	return time.Now().Add(skew).After(auth.Expiration)
+}
+
+// refreshToken redeems the refresh token of the auth against the token endpoint of its issuer and updates the auth
+func refreshToken(ctx context.Context, auth *configtypes.GlobalServerAuth, options *tokenOptions) error {
+	if auth.RefreshToken == "" {
+		return errors.New("the access token is expired and there is no refresh token")
+	}
+	if auth.Issuer == "" {
+		return errors.New("the access token is expired and there is no issuer")
+	}
+	tokenEndpoint, err := discoverTokenEndpoint(ctx, auth.Issuer, options.httpClient)
+	if err != nil {
+		return err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "refresh_token")
+	form.Set("refresh_token", auth.RefreshToken)
+	if options.clientID != "" {
+		form.Set("client_id", options.clientID)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp := &tokenResponse{}
+	status, err := doJSONRequest(options.httpClient, req, resp)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK || resp.Error != "" {
+		if resp.Error != "" {
+			return errors.Errorf("token endpoint returned %s: %s", resp.Error, resp.ErrorDescription)
+		}
+		return errors.Errorf("token endpoint returned status %d", status)
+	}
+	if resp.AccessToken == "" {
+		return errors.New("token endpoint returned no access token")
+	}
+
+	auth.AccessToken = resp.AccessToken
+	if resp.IDToken != "" {
+		auth.IDToken = resp.IDToken
+	}
+	// The refresh token is only rotated if the token endpoint returns a new one
+	if resp.RefreshToken != "" {
+		auth.RefreshToken = resp.RefreshToken
+	}
+	if resp.ExpiresIn > 0 {
+		auth.Expiration = time.Now().Add(time.Duration(resp.ExpiresIn) * time.Second)
+	} else {
+		auth.Expiration = time.Time{}
+	}
+	return nil
+}
+
+// discoverTokenEndpoint returns the token endpoint from the OIDC provider metadata of the issuer
+func discoverTokenEndpoint(ctx context.Context, issuer string, client *http.Client) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(issuer, "/")+oidcDiscoveryPath, http.NoBody)
+	if err != nil {
+		return "", err
+	}
+	metadata := &oidcProviderMetadata{}
+	status, err := doJSONRequest(client, req, metadata)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to discover the token endpoint of issuer %s", issuer)
+	}
+	if status != http.StatusOK {
+		return "", errors.Errorf("failed to discover the token endpoint of issuer %s: status %d", issuer, status)
+	}
+	if metadata.TokenEndpoint == "" {
+		return "", errors.Errorf("issuer %s does not advertise a token endpoint", issuer)
+	}
+	return metadata.TokenEndpoint, nil
+}
+
+// doJSONRequest sends the request and decodes the JSON response body into v. It returns the status code of the response.
+func doJSONRequest(client *http.Client, req *http.Request, v interface{}) (int, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, err
+	}
+	if len(body) != 0 && strings.Contains(resp.Header.Get("Content-Type"), "json") {
+		if err := json.Unmarshal(body, v); err != nil {
+			return resp.StatusCode, errors.Wrapf(err, "failed to parse response of %s", req.URL)
+		}
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,128 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	configtypes "github.com/vmware-tanzu/tanzu-plugin-runtime/config/types"
+)
+
+// newTestOIDCServer returns an OIDC stand-in that issues a new access token for each refresh token grant
+func newTestOIDCServer(t *testing.T, refreshes *int32) *httptest.Server {
+	server := newUnstartedTestOIDCServer(t, refreshes)
+	server.Start()
+	return server
+}
+
+// newUnstartedTestOIDCServer returns the OIDC stand-in of newTestOIDCServer without starting it
+func newUnstartedTestOIDCServer(t *testing.T, refreshes *int32) *httptest.Server {
+	mux := http.NewServeMux()
+	var server *httptest.Server
+	mux.HandleFunc(oidcDiscoveryPath, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]string{"issuer": server.URL, "token_endpoint": server.URL + "/token"})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, r.ParseForm())
+		w.Header().Set("Content-Type", "application/json")
+		if r.Form.Get("grant_type") != "refresh_token" || r.Form.Get("refresh_token") != "refresh-token" {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant", "error_description": "unknown refresh token"})
+			return
+		}
+		atomic.AddInt32(refreshes, 1)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "new-access-token",
+			"id_token":     "new-id-token",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+		})
+	})
+	server = httptest.NewUnstartedServer(mux)
+	return server
+}
+
+func TestGetValidAccessToken(t *testing.T) {
+	// Setup config data
+	_, cleanUp := setupTestConfig(t, &CfgTestData{})
+
+	defer func() {
+		cleanUp()
+	}()
+
+	var refreshes int32
+	server := newTestOIDCServer(t, &refreshes)
+	defer server.Close()
+
+	c := &configtypes.Context{
+		Name:        "test-tmc",
+		ContextType: configtypes.ContextTypeTMC,
+		GlobalOpts: &configtypes.GlobalServer{
+			Endpoint: "test-endpoint",
+			Auth: configtypes.GlobalServerAuth{
+				Issuer:       server.URL,
+				AccessToken:  "access-token",
+				RefreshToken: "refresh-token",
+				Expiration:   time.Now().Add(time.Hour),
+			},
+		},
+	}
+	assert.NoError(t, SetContext(c, true))
+
+	// A valid token is returned as is
+	token, err := GetValidAccessToken(context.Background(), "test-tmc")
+	assert.NoError(t, err)
+	assert.Equal(t, "access-token", token)
+	assert.Equal(t, int32(0), refreshes)
+
+	// A token expiring within the skew is refreshed once across concurrent callers
+	c.GlobalOpts.Auth.Expiration = time.Now().Add(10 * time.Second)
+	assert.NoError(t, SetContext(c, true))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			token, err := GetValidAccessToken(context.Background(), "test-tmc")
+			assert.NoError(t, err)
+			assert.Equal(t, "new-access-token", token)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), refreshes)
+
+	updated, err := GetContext("test-tmc")
+	assert.NoError(t, err)
+	assert.Equal(t, "new-access-token", updated.GlobalOpts.Auth.AccessToken)
+	assert.Equal(t, "new-id-token", updated.GlobalOpts.Auth.IDToken)
+	assert.Equal(t, "refresh-token", updated.GlobalOpts.Auth.RefreshToken)
+	assert.True(t, updated.GlobalOpts.Auth.Expiration.After(time.Now().Add(time.Hour-time.Minute)))
+
+	// A rejected refresh token surfaces the error of the token endpoint
+	c.GlobalOpts.Auth.RefreshToken = "revoked"
+	c.GlobalOpts.Auth.Expiration = time.Now().Add(-time.Minute)
+	assert.NoError(t, SetContext(c, true))
+	_, err = GetValidAccessToken(context.Background(), "test-tmc")
+	assert.ErrorContains(t, err, "invalid_grant")
+
+	// A context without global server auth is rejected
+	assert.NoError(t, SetContext(&configtypes.Context{
+		Name:        "test-mc",
+		ContextType: configtypes.ContextTypeK8s,
+		ClusterOpts: &configtypes.ClusterServer{Endpoint: "test-endpoint"},
+	}, false))
+	_, err = GetValidAccessToken(context.Background(), "test-mc")
+	assert.ErrorContains(t, err, "has no global server auth")
+}
@@ -73,6 +73,9 @@ type Context struct {
 	// AdditionalMetadata to provide any additional data that is respective to each context
 	AdditionalMetadata map[string]interface{} `json:"additionalMetadata,omitempty" yaml:"additionalMetadata,omitempty"`
 
+	// Labels to organize and select the contexts, e.g. env=prod
+	Labels map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+
 	// DiscoverySources determines from where to discover plugins
 	// associated with this context.
 	// Deprecated: This field is deprecated.  It is currently no used.
@@ -324,7 +327,9 @@ type TelemetryOptions struct {
 
 // Cert provides a certificate configuration for an endpoint
 type Cert struct {
-	// Host is the host(or ipaddress) or host:port for which the certificate configuration is applicable
+	// Host is the host(or ipaddress) or host:port for which the certificate configuration is applicable.
+	// It may also be a wildcard domain (*.example.com) or a CIDR range (10.0.0.0/8), optionally followed by a port.
+	// Without a port, the certificate configuration is applicable to any port.
 	Host string `json:"host,omitempty" yaml:"host,omitempty"`
 	// CACertData is the CA certificate for the host
 	CACertData string `json:"caCertData,omitempty" yaml:"caCertData,omitempty"`
@@ -15,4 +15,6 @@ type ConfigMetadata struct {
 	PatchStrategy map[string]string `json:"patchStrategy,omitempty" yaml:"patchStrategy,omitempty" mapstructure:"patchStrategy,omitempty"`
 	// Settings related to config
 	Settings map[string]string `json:"settings,omitempty" yaml:"settings,omitempty" mapstructure:"settings,omitempty"`
+	// ConfigAPIVersion is the apiVersion of config-ng.yaml recorded once migrated
+	ConfigAPIVersion string `json:"configAPIVersion,omitempty" yaml:"configAPIVersion,omitempty" mapstructure:"configAPIVersion,omitempty"`
 }
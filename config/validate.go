@@ -0,0 +1,319 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+
+	"github.com/vmware-tanzu/tanzu-plugin-runtime/config/nodeutils"
+)
+
+// ValidationError is a problem of a config file
+type ValidationError struct {
+	// File is the path of the config file, empty if the content was validated directly
+	File string
+	// Line and Column locate the problem in the file, starting at 1. They are 0 if unknown.
+	Line   int
+	Column int
+	// Path is the dotted path of the offending node, e.g. contexts[1].name
+	Path string
+	// Message describes the problem
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	var location []string
+	if e.File != "" {
+		location = append(location, e.File)
+	}
+	if e.Line > 0 {
+		location = append(location, fmt.Sprintf("%d:%d", e.Line, e.Column))
+	}
+	msg := e.Message
+	if e.Path != "" {
+		msg = e.Path + ": " + msg
+	}
+	if len(location) == 0 {
+		return msg
+	}
+	return strings.Join(location, ":") + ": " + msg
+}
+
+// ValidationErrors are the problems of the config files
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Validate validates config.yaml, config-ng.yaml and the config metadata file against their JSON Schemas
+// and checks the consistency of the client config files. It returns ValidationErrors reporting every
+// problem with its line and column, or nil if the files are valid. Missing files are valid.
+func Validate() error {
+	var problems ValidationErrors
+	for _, f := range snapshotFiles() {
+		path, err := f.pathGetter()
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return errors.Wrapf(err, "failed to read %s", path)
+		}
+		var fileProblems ValidationErrors
+		if f.name == CfgMetadataName {
+			fileProblems = ValidateMetadata(data)
+		} else {
+			fileProblems = ValidateClientConfig(data)
+		}
+		for _, problem := range fileProblems {
+			problem.File = path
+		}
+		problems = append(problems, fileProblems...)
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return problems
+}
+
+// ValidateClientConfig validates the content of a client config file, config.yaml or config-ng.yaml
+func ValidateClientConfig(data []byte) ValidationErrors {
+	node, problems := parseForValidation(data)
+	if node == nil {
+		return problems
+	}
+	problems = append(problems, validateNode(node, ClientConfigJSONSchema(), "")...)
+	return append(problems, validateClientConfigNode(node)...)
+}
+
+// ValidateMetadata validates the content of the config metadata file
+func ValidateMetadata(data []byte) ValidationErrors {
+	node, problems := parseForValidation(data)
+	if node == nil {
+		return problems
+	}
+	return append(problems, validateNode(node, MetadataJSONSchema(), "")...)
+}
+
+// parseForValidation returns the root node of the document, nil if the document is empty or invalid
+func parseForValidation(data []byte) (*yaml.Node, ValidationErrors) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, ValidationErrors{{Message: err.Error()}}
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return nil, nil
+	}
+	return doc.Content[0], nil
+}
+
+func newValidationError(node *yaml.Node, path, format string, args ...interface{}) *ValidationError {
+	return &ValidationError{Line: node.Line, Column: node.Column, Path: path, Message: fmt.Sprintf(format, args...)}
+}
+
+// validateNode validates the node against the schema
+func validateNode(node *yaml.Node, schema *JSONSchema, path string) ValidationErrors {
+	if node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	// A null value is equivalent to a missing key
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+		return nil
+	}
+
+	switch schema.Type {
+	case schemaTypeObject:
+		if node.Kind != yaml.MappingNode {
+			return ValidationErrors{newValidationError(node, path, "expected an object, got %s", describeNode(node))}
+		}
+		var problems ValidationErrors
+		seen := map[string]bool{}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			keyNode, valueNode := node.Content[i], node.Content[i+1]
+			key := keyNode.Value
+			keyPath := joinValidationPath(path, key)
+			if seen[key] {
+				problems = append(problems, newValidationError(keyNode, keyPath, "duplicate key %q", key))
+				continue
+			}
+			seen[key] = true
+			propertySchema, ok := schema.Properties[key]
+			if !ok {
+				propertySchema = schema.AdditionalProperties
+			}
+			if propertySchema == nil {
+				problems = append(problems, newValidationError(keyNode, keyPath, "unknown key %q", key))
+				continue
+			}
+			problems = append(problems, validateNode(valueNode, propertySchema, keyPath)...)
+		}
+		return problems
+	case schemaTypeArray:
+		if node.Kind != yaml.SequenceNode {
+			return ValidationErrors{newValidationError(node, path, "expected an array, got %s", describeNode(node))}
+		}
+		var problems ValidationErrors
+		for i, item := range node.Content {
+			problems = append(problems, validateNode(item, schema.Items, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+		return problems
+	case schemaTypeString:
+		// Any scalar is decoded as a string
+		if node.Kind != yaml.ScalarNode {
+			return ValidationErrors{newValidationError(node, path, "expected a string, got %s", describeNode(node))}
+		}
+		if schema.Format == schemaFormatDateTime && node.Tag != "!!timestamp" {
+			if _, err := time.Parse(time.RFC3339, node.Value); err != nil {
+				return ValidationErrors{newValidationError(node, path, "expected a date-time, got %q", node.Value)}
+			}
+		}
+	case schemaTypeBoolean:
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!bool" {
+			return ValidationErrors{newValidationError(node, path, "expected a boolean, got %s", describeNode(node))}
+		}
+	case schemaTypeInteger:
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!int" {
+			return ValidationErrors{newValidationError(node, path, "expected an integer, got %s", describeNode(node))}
+		}
+	case schemaTypeNumber:
+		if node.Kind != yaml.ScalarNode || (node.Tag != "!!int" && node.Tag != "!!float") {
+			return ValidationErrors{newValidationError(node, path, "expected a number, got %s", describeNode(node))}
+		}
+	}
+	return nil
+}
+
+// describeNode describes the kind of the node for the validation messages
+func describeNode(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "an object"
+	case yaml.SequenceNode:
+		return "an array"
+	default:
+		return fmt.Sprintf("%s %q", strings.TrimPrefix(node.Tag, "!!"), node.Value)
+	}
+}
+
+func joinValidationPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// validateClientConfigNode checks the consistency of the client config beyond its schema
+func validateClientConfigNode(node *yaml.Node) ValidationErrors {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	var problems ValidationErrors
+
+	// Context names are unique and the current contexts exist
+	contextNames := map[string]bool{}
+	contexts := nodeutils.FindNode(node, nodeutils.WithKeys([]nodeutils.Key{{Name: KeyContexts}}))
+	for i, contextNode := range sequenceItems(contexts) {
+		if contextNode.Kind != yaml.MappingNode {
+			continue
+		}
+		nameNode := nodeutils.FindNode(contextNode, nodeutils.WithKeys([]nodeutils.Key{{Name: "name"}}))
+		if nameNode == nil || nameNode.Kind != yaml.ScalarNode {
+			continue
+		}
+		path := fmt.Sprintf("%s[%d].name", KeyContexts, i)
+		if contextNames[nameNode.Value] {
+			problems = append(problems, newValidationError(nameNode, path, "duplicate context name %q", nameNode.Value))
+		}
+		contextNames[nameNode.Value] = true
+	}
+	if currentContext := nodeutils.FindNode(node, nodeutils.WithKeys([]nodeutils.Key{{Name: KeyCurrentContext}})); currentContext != nil && currentContext.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(currentContext.Content); i += 2 {
+			contextType, nameNode := currentContext.Content[i].Value, currentContext.Content[i+1]
+			if nameNode.Kind == yaml.ScalarNode && nameNode.Value != "" && !contextNames[nameNode.Value] {
+				problems = append(problems, newValidationError(nameNode, joinValidationPath(KeyCurrentContext, contextType),
+					"current context %q does not exist", nameNode.Value))
+			}
+		}
+	}
+
+	// Every discovery source has exactly one backend
+	for path, sources := range discoverySourcesNodes(node) {
+		for i, sourceNode := range sequenceItems(sources) {
+			problems = append(problems, validateDiscoverySourceNode(sourceNode, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	}
+	sortValidationErrors(problems)
+	return problems
+}
+
+// discoverySourcesNodes returns the sequences of discovery sources of the client config by path
+func discoverySourcesNodes(node *yaml.Node) map[string]*yaml.Node {
+	nodes := map[string]*yaml.Node{
+		"cli.discoverySources": nodeutils.FindNode(node, nodeutils.WithKeys([]nodeutils.Key{{Name: KeyCLI}, {Name: KeyDiscoverySources}})),
+		"clientOptions.cli.discoverySources": nodeutils.FindNode(node, nodeutils.WithKeys([]nodeutils.Key{
+			{Name: KeyClientOptions}, {Name: KeyCLI}, {Name: KeyDiscoverySources}})),
+	}
+	for _, key := range []string{KeyContexts, KeyServers} {
+		for i, item := range sequenceItems(nodeutils.FindNode(node, nodeutils.WithKeys([]nodeutils.Key{{Name: key}}))) {
+			if item.Kind != yaml.MappingNode {
+				continue
+			}
+			nodes[fmt.Sprintf("%s[%d].discoverySources", key, i)] = nodeutils.FindNode(item, nodeutils.WithKeys([]nodeutils.Key{{Name: KeyDiscoverySources}}))
+		}
+	}
+	return nodes
+}
+
+func validateDiscoverySourceNode(node *yaml.Node, path string) ValidationErrors {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	var backends []string
+	for _, backend := range pluginDiscoveryBackends {
+		if value := nodeutils.FindNode(node, nodeutils.WithKeys([]nodeutils.Key{{Name: backend}})); value != nil && value.Tag != "!!null" {
+			backends = append(backends, backend)
+		}
+	}
+	switch len(backends) {
+	case 0:
+		return ValidationErrors{newValidationError(node, path, "discovery source has no backend, one of %s must be set", strings.Join(pluginDiscoveryBackends, ", "))}
+	case 1:
+		return nil
+	default:
+		return ValidationErrors{newValidationError(node, path, "discovery source has multiple backends %s, only one must be set", strings.Join(backends, ", "))}
+	}
+}
+
+// sequenceItems returns the items of the sequence node, nil if the node is not a sequence
+func sequenceItems(node *yaml.Node) []*yaml.Node {
+	if node == nil || node.Kind != yaml.SequenceNode {
+		return nil
+	}
+	return node.Content
+}
+
+// sortValidationErrors orders the problems by position in the file
+func sortValidationErrors(problems ValidationErrors) {
+	sort.SliceStable(problems, func(i, j int) bool {
+		if problems[i].Line != problems[j].Line {
+			return problems[i].Line < problems[j].Line
+		}
+		return problems[i].Column < problems[j].Column
+	})
+}
@@ -0,0 +1,183 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientConfigJSONSchema(t *testing.T) {
+	data, err := json.Marshal(ClientConfigJSONSchema())
+	assert.NoError(t, err)
+
+	var schema map[string]interface{}
+	err = json.Unmarshal(data, &schema)
+	assert.NoError(t, err)
+	assert.Equal(t, JSONSchemaDialect, schema["$schema"])
+	assert.Equal(t, "object", schema["type"])
+	assert.Equal(t, false, schema["additionalProperties"])
+
+	properties := schema["properties"].(map[string]interface{})
+	assert.Contains(t, properties, "contexts")
+	assert.Contains(t, properties, "apiVersion")
+	contexts := properties["contexts"].(map[string]interface{})
+	assert.Equal(t, "array", contexts["type"])
+	context := contexts["items"].(map[string]interface{})
+	contextProperties := context["properties"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"type": "object", "additionalProperties": map[string]interface{}{}}, contextProperties["additionalMetadata"])
+
+	expiration := contextProperties["globalOpts"].(map[string]interface{})["properties"].(map[string]interface{})["auth"].(map[string]interface{})["properties"].(map[string]interface{})["expiration"]
+	assert.Equal(t, map[string]interface{}{"type": "string", "format": "date-time"}, expiration)
+
+	data, err = json.Marshal(MetadataJSONSchema())
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"patchStrategy":{"type":"object","additionalProperties":{"type":"string"}}`)
+}
+
+func TestValidateClientConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected []string
+	}{
+		{
+			name: "valid config",
+			data: `apiVersion: config.tanzu.vmware.com/v1alpha1
+kind: ClientConfig
+metadata:
+  creationTimestamp: null
+contexts:
+  - name: test-mc
+    contextType: kubernetes
+    clusterOpts:
+      path: test-path
+      context: test-context
+      isManagementCluster: true
+    globalOpts:
+      auth:
+        expiration: 2023-06-01T10:00:00Z
+currentContext:
+  kubernetes: test-mc
+cli:
+  discoverySources:
+    - contextType: k8s
+      oci:
+        name: default
+        image: test-image:latest
+`,
+		},
+		{
+			name: "unknown keys and wrong types",
+			data: `contexts:
+  - name: test-mc
+    clusterOpts:
+      path: test-path
+      isManagementCluster: "yes"
+    unknown: value
+certs: host
+`,
+			expected: []string{
+				`5:28: contexts[0].clusterOpts.isManagementCluster: expected a boolean, got str "yes"`,
+				`6:5: contexts[0].unknown: unknown key "unknown"`,
+				`7:8: certs: expected an array, got str "host"`,
+			},
+		},
+		{
+			name: "duplicate context names and missing current context",
+			data: `contexts:
+  - name: test-mc
+  - name: test-mc
+currentContext:
+  kubernetes: test-mc
+  mission-control: test-tmc
+`,
+			expected: []string{
+				`3:11: contexts[1].name: duplicate context name "test-mc"`,
+				`6:20: currentContext.mission-control: current context "test-tmc" does not exist`,
+			},
+		},
+		{
+			name: "discovery sources without or with multiple backends",
+			data: `cli:
+  discoverySources:
+    - oci:
+        image: test-image
+      local:
+        path: test-path
+    - contextType: k8s
+`,
+			expected: []string{
+				`3:7: cli.discoverySources[0]: discovery source has multiple backends oci, local, only one must be set`,
+				`7:7: cli.discoverySources[1]: discovery source has no backend, one of gcp, oci, rest, k8s, local must be set`,
+			},
+		},
+		{
+			name: "duplicate keys and invalid date-time",
+			data: `contexts:
+  - name: test-tmc
+    globalOpts:
+      auth:
+        expiration: tomorrow
+    name: test-tmc-2
+`,
+			expected: []string{
+				`5:21: contexts[0].globalOpts.auth.expiration: expected a date-time, got "tomorrow"`,
+				`6:5: contexts[0].name: duplicate key "name"`,
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var problems []string
+			for _, problem := range ValidateClientConfig([]byte(tc.data)) {
+				problems = append(problems, problem.Error())
+			}
+			assert.Equal(t, tc.expected, problems)
+		})
+	}
+}
+
+func TestValidateMetadata(t *testing.T) {
+	problems := ValidateMetadata([]byte(`configMetadata:
+  settings:
+    useUnifiedConfig: true
+  patchStrategy:
+    contexts.group: replace
+  unknown: {}
+`))
+	// Any scalar is decoded as a string
+	assert.Len(t, problems, 1)
+	assert.Equal(t, `6:3: configMetadata.unknown: unknown key "unknown"`, problems[0].Error())
+}
+
+func TestValidate(t *testing.T) {
+	cfg := `clientOptions:
+  features:
+    global:
+      context-target-v2: "true"
+`
+	cfgNextGen := `contexts:
+  - name: test-mc
+currentContext:
+  kubernetes: missing
+`
+	cfgMetadata := `configMetadata:
+  settings:
+    useUnifiedConfig: "false"
+`
+	files, cleanUp := setupTestConfig(t, &CfgTestData{cfg: cfg, cfgNextGen: cfgNextGen, cfgMetadata: cfgMetadata})
+	defer cleanUp()
+	configNextGenFile := files[1]
+
+	err := Validate()
+	assert.Error(t, err)
+	problems, ok := err.(ValidationErrors)
+	assert.True(t, ok)
+	assert.Len(t, problems, 1)
+	assert.Equal(t, configNextGenFile.Name(), problems[0].File)
+	assert.Equal(t, configNextGenFile.Name()+`:4:15: currentContext.kubernetes: current context "missing" does not exist`, problems[0].Error())
+}
@@ -0,0 +1,564 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+
+	"github.com/vmware-tanzu/tanzu-plugin-runtime/config/nodeutils"
+)
+
+// ErrPathNotFound is returned when a path does not exist in the client config
+var ErrPathNotFound = errors.New("config path not found")
+
+type pathSegmentKind int
+
+const (
+	// segmentKey selects the value of a key of a mapping, e.g. clusterOpts or ["a.b"]
+	segmentKey pathSegmentKind = iota
+	// segmentIndex selects an item of a sequence by index, e.g. [0]
+	segmentIndex
+	// segmentSelector selects the item of a sequence with a field value, e.g. [name=prod]
+	segmentSelector
+)
+
+// pathSegment is a segment of a path of the client config
+type pathSegment struct {
+	kind pathSegmentKind
+	// key is the mapping key, or the field of the selector
+	key string
+	// value is the field value of the selector
+	value string
+	// index is the sequence index
+	index int
+}
+
+func (s pathSegment) String() string {
+	switch s.kind {
+	case segmentIndex:
+		return fmt.Sprintf("[%d]", s.index)
+	case segmentSelector:
+		return fmt.Sprintf("[%s=%s]", s.key, s.value)
+	default:
+		return s.key
+	}
+}
+
+// GetValue returns the value of the client config at the path, decoded from its YAML representation.
+// The path is a dotted path of keys with selectors of the sequence items by field value or by index,
+// e.g. contexts[name=prod].clusterOpts.context, clientOptions.features.global.context-target-v2
+// or cli.discoverySources[0].
+// Keys containing '.' or '[' are quoted, e.g. contexts[name=prod].additionalMetadata["tanzu.cloud.vmware.com/org-id"].
+// ErrPathNotFound is returned if the path does not exist. The secret references are returned as is.
+// The features and env variables overridden by the TANZU_CONFIG_FEATURE_<PLUGIN>_<KEY> and
+// TANZU_CONFIG_ENV_<KEY> environment variables are returned with their overridden values, as
+// GetClientConfig does.
+func GetValue(path string) (interface{}, error) {
+	segments, err := parseValuePath(path)
+	if err != nil {
+		return nil, err
+	}
+	// Retrieve client config node
+	node, err := getClientConfigNode()
+	if err != nil {
+		return nil, err
+	}
+	if segments[0].kind == segmentKey && segments[0].key == KeyClientOptions {
+		if err := getConfigOverlay().applyNode(node); err != nil {
+			return nil, err
+		}
+	}
+	valueNode, _, _, err := findValueNode(node.Content[0], segments, false)
+	if err != nil {
+		return nil, err
+	}
+	if valueNode == nil {
+		return nil, errors.Wrapf(ErrPathNotFound, "%s", path)
+	}
+	var value interface{}
+	if err := valueNode.Decode(&value); err != nil {
+		return nil, errors.Wrapf(err, "failed to decode %s", path)
+	}
+	return value, nil
+}
+
+// SetValue sets the value of the client config at the path, see GetValue for the syntax of the path.
+// The value is coerced to the type of the path in the client config schema: booleans and numbers are
+// parsed, and objects and arrays are parsed as YAML or JSON. Objects are merged into the existing
+// value following the patch strategies of the config metadata. The missing keys are created, as well
+// as the sequence item of a selector, e.g. contexts[name=prod] creates the context prod.
+// The contexts are updated as SetContext does: the updated context is validated and its tokens are
+// moved to the secret store, if one is configured.
+func SetValue(path, value string) error {
+	segments, err := parseValuePath(path)
+	if err != nil {
+		return err
+	}
+	newNode, err := coerceValue(segments, value)
+	if err != nil {
+		return errors.Wrapf(err, "invalid value for %s", path)
+	}
+	patchStrategyKey := valuePathPattern(segments)
+	return Update(func(tx *ConfigTx) error {
+		if isContextsPath(segments) {
+			return setContextValue(tx, segments, newNode, patchStrategyKey)
+		}
+		persist, err := setValueNode(tx.node.Content[0], segments, newNode, patchStrategyKey)
+		if err != nil {
+			return err
+		}
+		tx.markPersist(persist)
+		return nil
+	})
+}
+
+// UnsetValue removes the value of the client config at the path, see GetValue for the syntax of the path.
+// The mapping key or the sequence item is removed. It is a no-op if the path does not exist.
+// A context is removed as DeleteContext does, along with its current context, server and secrets.
+func UnsetValue(path string) error {
+	segments, err := parseValuePath(path)
+	if err != nil {
+		return err
+	}
+	return Update(func(tx *ConfigTx) error {
+		if isContextsPath(segments) {
+			return unsetContextValue(tx, segments)
+		}
+		persist, err := unsetValueNode(tx.node.Content[0], segments)
+		if err != nil {
+			return err
+		}
+		tx.markPersist(persist)
+		return nil
+	})
+}
+
+// unsetValueNode removes the node at the path, if any
+func unsetValueNode(root *yaml.Node, segments []pathSegment) (persist bool, err error) {
+	valueNode, parent, index, err := findValueNode(root, segments, false)
+	if err != nil || valueNode == nil {
+		return false, err
+	}
+	if parent.Kind == yaml.MappingNode {
+		parent.Content = append(parent.Content[:index-1], parent.Content[index+1:]...)
+	} else {
+		parent.Content = append(parent.Content[:index], parent.Content[index+1:]...)
+	}
+	return true, nil
+}
+
+// isContextsPath returns true if the path is in the contexts of the client config
+func isContextsPath(segments []pathSegment) bool {
+	return segments[0].kind == segmentKey && segments[0].key == KeyContexts
+}
+
+// contextPathName returns the name of the context selected by the path, e.g. prod for
+// contexts[name=prod].clusterOpts. A context missing from the config can only be selected by name.
+func contextPathName(root *yaml.Node, segments []pathSegment) (string, error) {
+	if len(segments) < 2 {
+		return "", errors.Errorf("%s: the contexts are updated one at a time, e.g. contexts[name=prod]", joinSegments(segments))
+	}
+	contextPath := joinSegments(segments[:2])
+	name := ""
+	if segments[1].kind == segmentSelector && segments[1].key == "name" {
+		name = segments[1].value
+	} else {
+		contextNode, _, _, err := findValueNode(root, segments[:2], false)
+		if err != nil {
+			return "", err
+		}
+		if contextNode == nil {
+			return "", errors.Wrapf(ErrPathNotFound, "%s", contextPath)
+		}
+		if contextNode.Kind == yaml.MappingNode {
+			if nameNode := nodeutils.FindNode(contextNode, nodeutils.WithKeys([]nodeutils.Key{{Name: "name"}})); nameNode != nil {
+				name = nameNode.Value
+			}
+		}
+	}
+	if name == "" {
+		return "", errors.Errorf("%s: context name cannot be empty", contextPath)
+	}
+	if len(segments) == 3 && segments[2].kind == segmentKey && segments[2].key == "name" {
+		return "", errors.Errorf("%s: the name of context %s cannot be changed", joinSegments(segments), name)
+	}
+	return name, nil
+}
+
+// setContextValue sets the value at the path of a context and updates the context with tx.SetContext,
+// which validates it and moves its tokens to the secret store
+func setContextValue(tx *ConfigTx, segments []pathSegment, newNode *yaml.Node, patchStrategyKey string) error {
+	name, err := contextPathName(tx.node.Content[0], segments)
+	if err != nil {
+		return err
+	}
+	previousRefs := contextSecretRefsByName(tx.node, name)
+	persist, err := setValueNode(tx.node.Content[0], segments, newNode, patchStrategyKey)
+	if err != nil || !persist {
+		return err
+	}
+	return updateContextValue(tx, name, previousRefs)
+}
+
+// unsetContextValue removes the value at the path of a context, or the context with tx.DeleteContext
+func unsetContextValue(tx *ConfigTx, segments []pathSegment) error {
+	name, err := contextPathName(tx.node.Content[0], segments)
+	if errors.Is(err, ErrPathNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	// Removing a value of a missing context is a no-op
+	if findContextNode(tx.node, name) == nil {
+		return nil
+	}
+	if len(segments) == 2 {
+		return tx.DeleteContext(name)
+	}
+	previousRefs := contextSecretRefsByName(tx.node, name)
+	persist, err := unsetValueNode(tx.node.Content[0], segments)
+	if err != nil || !persist {
+		return err
+	}
+	return updateContextValue(tx, name, previousRefs)
+}
+
+// updateContextValue updates the context modified in place with tx.SetContext and releases the
+// secrets no longer referenced by the context
+func updateContextValue(tx *ConfigTx, name string, previousRefs []string) error {
+	c, err := getContext(tx.node, name)
+	if err != nil {
+		return err
+	}
+	if err := tx.SetContext(c, false); err != nil {
+		return err
+	}
+	tx.secrets.release(subtractStrings(previousRefs, contextSecretRefsByName(tx.node, name))...)
+	tx.markPersist(true)
+	return nil
+}
+
+// contextSecretRefsByName returns the secret store keys referenced by the context, if it exists
+func contextSecretRefsByName(node *yaml.Node, name string) []string {
+	c, err := getContext(node, name)
+	if err != nil {
+		return nil
+	}
+	return contextSecretRefs(c)
+}
+
+// parseValuePath parses the path into segments
+func parseValuePath(path string) ([]pathSegment, error) {
+	var segments []pathSegment
+	rest := path
+	for rest != "" {
+		switch {
+		case rest[0] == '[':
+			end, err := closingBracket(rest)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid path %q", path)
+			}
+			segment, err := parseBracketSegment(rest[1:end])
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid path %q", path)
+			}
+			segments = append(segments, segment)
+			rest = rest[end+1:]
+		case rest[0] == '.' && len(segments) != 0:
+			rest = rest[1:]
+			if rest == "" || rest[0] == '.' || rest[0] == '[' {
+				return nil, errors.Errorf("invalid path %q: empty key", path)
+			}
+		default:
+			end := strings.IndexAny(rest, ".[")
+			if end == -1 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, errors.Errorf("invalid path %q: empty key", path)
+			}
+			segments = append(segments, pathSegment{kind: segmentKey, key: rest[:end]})
+			rest = rest[end:]
+		}
+	}
+	if len(segments) == 0 {
+		return nil, errors.New("path cannot be empty")
+	}
+	return segments, nil
+}
+
+// closingBracket returns the index of the bracket closing the bracket at the start of s, skipping quoted strings
+func closingBracket(s string) (int, error) {
+	quoted := false
+	for i := 1; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quoted:
+			i++
+		case s[i] == '"':
+			quoted = !quoted
+		case s[i] == ']' && !quoted:
+			return i, nil
+		}
+	}
+	return -1, errors.New("missing ]")
+}
+
+// parseBracketSegment parses the content of the brackets: a quoted key, an index or a selector
+func parseBracketSegment(s string) (pathSegment, error) {
+	if strings.HasPrefix(s, `"`) {
+		key, err := strconv.Unquote(s)
+		if err != nil {
+			return pathSegment{}, errors.Errorf("invalid quoted key %s", s)
+		}
+		return pathSegment{kind: segmentKey, key: key}, nil
+	}
+	if field, value, ok := strings.Cut(s, "="); ok {
+		if field == "" {
+			return pathSegment{}, errors.Errorf("invalid selector [%s]: empty field", s)
+		}
+		if strings.HasPrefix(value, `"`) {
+			unquoted, err := strconv.Unquote(value)
+			if err != nil {
+				return pathSegment{}, errors.Errorf("invalid selector [%s]", s)
+			}
+			value = unquoted
+		}
+		return pathSegment{kind: segmentSelector, key: field, value: value}, nil
+	}
+	index, err := strconv.Atoi(s)
+	if err != nil || index < 0 {
+		return pathSegment{}, errors.Errorf("invalid index [%s], expected a non negative integer, a selector [field=value] or a quoted key", s)
+	}
+	return pathSegment{kind: segmentIndex, index: index}, nil
+}
+
+// valuePathPattern returns the keys of the path joined by '.', the format of the patch strategy keys
+func valuePathPattern(segments []pathSegment) string {
+	var keys []string
+	for _, s := range segments {
+		if s.kind == segmentKey {
+			keys = append(keys, s.key)
+		}
+	}
+	return strings.Join(keys, ".")
+}
+
+// findValueNode returns the node at the path, its parent and its index in the content of the parent.
+// The missing nodes are created if create is set, else the node is nil if missing.
+func findValueNode(root *yaml.Node, segments []pathSegment, create bool) (node, parent *yaml.Node, index int, err error) {
+	node = root
+	for i, segment := range segments {
+		if create && node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+			node.Kind, node.Tag, node.Value = segmentParentKind(segment), "", ""
+		}
+		path := joinSegments(segments[:i+1])
+		child, childIndex, err := childValueNode(node, segment, path)
+		if err != nil {
+			return nil, nil, -1, err
+		}
+		if child == nil {
+			if !create {
+				return nil, nil, -1, nil
+			}
+			child, childIndex, err = createChildValueNode(node, segment, segments[i+1:], path)
+			if err != nil {
+				return nil, nil, -1, err
+			}
+		}
+		node, parent, index = child, node, childIndex
+	}
+	return node, parent, index, nil
+}
+
+// segmentParentKind returns the kind of the node selected by the segment
+func segmentParentKind(segment pathSegment) yaml.Kind {
+	if segment.kind == segmentKey {
+		return yaml.MappingNode
+	}
+	return yaml.SequenceNode
+}
+
+// childValueNode returns the child of the node selected by the segment and its index in the content of the node
+func childValueNode(node *yaml.Node, segment pathSegment, path string) (*yaml.Node, int, error) {
+	if node.Kind != segmentParentKind(segment) {
+		return nil, -1, errors.Errorf("%s: expected %s, got %s", path, describeKind(segmentParentKind(segment)), describeNode(node))
+	}
+	switch segment.kind {
+	case segmentKey:
+		if child := nodeutils.FindNode(node, nodeutils.WithKeys([]nodeutils.Key{{Name: segment.key}})); child != nil {
+			return child, nodeutils.GetNodeIndex(node.Content, segment.key), nil
+		}
+	case segmentIndex:
+		if segment.index < len(node.Content) {
+			return node.Content[segment.index], segment.index, nil
+		}
+	case segmentSelector:
+		for i, item := range node.Content {
+			if item.Kind != yaml.MappingNode {
+				continue
+			}
+			if field := nodeutils.FindNode(item, nodeutils.WithKeys([]nodeutils.Key{{Name: segment.key}})); field != nil && field.Kind == yaml.ScalarNode && field.Value == segment.value {
+				return item, i, nil
+			}
+		}
+	}
+	return nil, -1, nil
+}
+
+// createChildValueNode creates the child of the node selected by the segment, of the kind expected by the next segments
+func createChildValueNode(node *yaml.Node, segment pathSegment, next []pathSegment, path string) (*yaml.Node, int, error) {
+	switch segment.kind {
+	case segmentKey:
+		kind := yaml.ScalarNode
+		if len(next) != 0 {
+			kind = segmentParentKind(next[0])
+		}
+		keys := []nodeutils.Key{{Name: segment.key, Type: kind}}
+		child := nodeutils.FindNode(node, nodeutils.WithForceCreate(), nodeutils.WithKeys(keys))
+		return child, nodeutils.GetNodeIndex(node.Content, segment.key), nil
+	case segmentIndex:
+		return nil, -1, errors.Wrapf(ErrPathNotFound, "%s: index out of range", path)
+	case segmentSelector:
+		// The item of a selector is created with the field of the selector
+		child := &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+			{Kind: yaml.ScalarNode, Tag: nodeutils.NodeTagStr, Value: segment.key},
+			{Kind: yaml.ScalarNode, Tag: nodeutils.NodeTagStr, Value: segment.value},
+		}}
+		node.Content = append(node.Content, child)
+		return child, len(node.Content) - 1, nil
+	}
+	return nil, -1, errors.Errorf("%s: unknown path segment", path)
+}
+
+// setValueNode sets the node at the path to the new node following the patch strategies
+func setValueNode(root *yaml.Node, segments []pathSegment, newNode *yaml.Node, patchStrategyKey string) (persist bool, err error) {
+	node, _, _, err := findValueNode(root, segments, true)
+	if err != nil {
+		return false, err
+	}
+	if equalValueNodes(node, newNode) {
+		return false, nil
+	}
+
+	patchStrategies := constructPatchStrategies()
+	if node.Kind == yaml.MappingNode && newNode.Kind == yaml.MappingNode &&
+		!strings.EqualFold(patchStrategies[patchStrategyKey], nodeutils.PatchStrategyReplace) {
+		if _, err := nodeutils.DeleteNodes(newNode, node, nodeutils.WithPatchStrategyKey(patchStrategyKey), nodeutils.WithPatchStrategies(patchStrategies)); err != nil {
+			return false, err
+		}
+		return nodeutils.MergeNodes(newNode, node)
+	}
+	*node = *newNode
+	return true, nil
+}
+
+// coerceValue converts the value to a node of the type of the path in the client config schema
+func coerceValue(segments []pathSegment, value string) (*yaml.Node, error) {
+	schema, err := valuePathSchema(segments)
+	if err != nil {
+		return nil, err
+	}
+	switch schema.Type {
+	case schemaTypeString:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: nodeutils.NodeTagStr, Value: value}, nil
+	case schemaTypeBoolean:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, errors.Errorf("expected a boolean, got %q", value)
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(b)}, nil
+	case schemaTypeInteger:
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return nil, errors.Errorf("expected an integer, got %q", value)
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: value}, nil
+	case schemaTypeNumber:
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return nil, errors.Errorf("expected a number, got %q", value)
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!float", Value: value}, nil
+	}
+
+	// Objects, arrays and values of any type are parsed as YAML
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(value), &doc); err != nil {
+		return nil, errors.Wrap(err, "failed to parse the value")
+	}
+	node := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null"}
+	if len(doc.Content) != 0 {
+		node = doc.Content[0]
+	}
+	if problems := validateNode(node, schema, joinSegments(segments)); len(problems) != 0 {
+		return nil, problems
+	}
+	clearNodeStyle(node)
+	return node, nil
+}
+
+// valuePathSchema returns the schema of the value at the path in the client config schema
+func valuePathSchema(segments []pathSegment) (*JSONSchema, error) {
+	schema := ClientConfigJSONSchema()
+	for i, segment := range segments {
+		var next *JSONSchema
+		switch {
+		case segment.kind == segmentKey && schema.Type == schemaTypeObject:
+			if next = schema.Properties[segment.key]; next == nil {
+				next = schema.AdditionalProperties
+			}
+		case segment.kind != segmentKey && schema.Type == schemaTypeArray:
+			next = schema.Items
+		case schema.Type == "":
+			// Any value
+			return schema, nil
+		}
+		if next == nil {
+			return nil, errors.Errorf("unknown path %s", joinSegments(segments[:i+1]))
+		}
+		schema = next
+	}
+	return schema, nil
+}
+
+// joinSegments returns the path of the segments
+func joinSegments(segments []pathSegment) string {
+	var b strings.Builder
+	for i, segment := range segments {
+		switch {
+		case segment.kind != segmentKey:
+			b.WriteString(segment.String())
+		case strings.ContainsAny(segment.key, ".[]\""):
+			b.WriteString("[" + strconv.Quote(segment.key) + "]")
+		default:
+			if i != 0 {
+				b.WriteString(".")
+			}
+			b.WriteString(segment.key)
+		}
+	}
+	return b.String()
+}
+
+// describeKind describes the node kind for the path errors
+func describeKind(kind yaml.Kind) string {
+	if kind == yaml.MappingNode {
+		return "an object"
+	}
+	return "an array"
+}
+
+// clearNodeStyle writes the parsed value in the block style of the config files
+func clearNodeStyle(node *yaml.Node) {
+	if node.Kind != yaml.ScalarNode {
+		node.Style = 0
+	}
+	for _, child := range node.Content {
+		clearNodeStyle(child)
+	}
+}
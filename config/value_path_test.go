@@ -0,0 +1,213 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	configtypes "github.com/vmware-tanzu/tanzu-plugin-runtime/config/types"
+)
+
+func TestGetValue(t *testing.T) {
+	err := setupForGetContext()
+	assert.NoError(t, err)
+	defer cleanupTestingDir(t)
+
+	value, err := GetValue("contexts[name=test-mc].clusterOpts.path")
+	assert.NoError(t, err)
+	assert.Equal(t, "test-path", value)
+
+	value, err = GetValue("contexts[name=test-mc].clusterOpts.isManagementCluster")
+	assert.NoError(t, err)
+	assert.Equal(t, true, value)
+
+	value, err = GetValue("contexts[3].additionalMetadata[\"tanzuOrgID\"]")
+	assert.NoError(t, err)
+	assert.Equal(t, "fake-org-id", value)
+
+	value, err = GetValue("currentContext")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"kubernetes": "test-mc-2", "mission-control": "test-tmc"}, value)
+
+	_, err = GetValue("contexts[name=missing].clusterOpts")
+	assert.ErrorIs(t, err, ErrPathNotFound)
+	_, err = GetValue("contexts.name")
+	assert.EqualError(t, err, `contexts.name: expected an object, got an array`)
+	_, err = GetValue("contexts[name=test-mc")
+	assert.ErrorContains(t, err, "missing ]")
+	_, err = GetValue("contexts[-1]")
+	assert.ErrorContains(t, err, "invalid index [-1]")
+	_, err = GetValue("")
+	assert.EqualError(t, err, "path cannot be empty")
+}
+
+func TestGetValueWithOverlay(t *testing.T) {
+	err := setupForGetContext()
+	assert.NoError(t, err)
+	defer cleanupTestingDir(t)
+	assert.NoError(t, SetFeature("global", "context-target-v2", "false"))
+	assert.NoError(t, SetEnv("FOO", "bar"))
+	t.Setenv("TANZU_CONFIG_FEATURE_GLOBAL_CONTEXT_TARGET_V2", "true")
+	t.Setenv("TANZU_CONFIG_ENV_FOO", "overridden")
+	t.Setenv("TANZU_CONFIG_ENV_NEW", "new")
+
+	value, err := GetValue("clientOptions.features.global.context-target-v2")
+	assert.NoError(t, err)
+	assert.Equal(t, "true", value)
+	value, err = GetValue("clientOptions.env.FOO")
+	assert.NoError(t, err)
+	assert.Equal(t, "overridden", value)
+	value, err = GetValue("clientOptions.env")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"FOO": "overridden", "NEW": "new"}, value)
+
+	// The overridden values are not persisted
+	envs, err := GetAllEnvs()
+	assert.NoError(t, err)
+	assert.Equal(t, "overridden", envs["FOO"])
+	assert.NoError(t, SetValue("clientOptions.env.BAR", "baz"))
+	cfg, err := GetClientConfigNoLock()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"FOO": "bar", "BAR": "baz"}, cfg.ClientOptions.Env)
+}
+
+func TestSetValue(t *testing.T) {
+	err := setupForGetContext()
+	assert.NoError(t, err)
+	defer cleanupTestingDir(t)
+
+	// Scalars are coerced to the type of the schema
+	assert.NoError(t, SetValue("contexts[name=test-mc].clusterOpts.context", "new-context"))
+	assert.NoError(t, SetValue("contexts[name=test-mc].clusterOpts.isManagementCluster", "false"))
+	assert.NoError(t, SetValue("clientOptions.features.global.context-target-v2", "true"))
+	c, err := GetContext("test-mc")
+	assert.NoError(t, err)
+	assert.Equal(t, "new-context", c.ClusterOpts.Context)
+	assert.False(t, c.ClusterOpts.IsManagementCluster)
+	enabled, err := IsFeatureEnabled("global", "context-target-v2")
+	assert.NoError(t, err)
+	assert.True(t, enabled)
+	value, err := GetValue("clientOptions.features.global.context-target-v2")
+	assert.NoError(t, err)
+	assert.Equal(t, "true", value)
+
+	err = SetValue("contexts[name=test-mc].clusterOpts.isManagementCluster", "maybe")
+	assert.ErrorContains(t, err, `expected a boolean, got "maybe"`)
+	err = SetValue("contexts[name=test-mc].unknown", "value")
+	assert.ErrorContains(t, err, "unknown path contexts[name=test-mc].unknown")
+	err = SetValue("contexts[name=test-mc].clusterOpts", "[1, 2]")
+	assert.ErrorContains(t, err, "expected an object, got an array")
+
+	// Objects are merged following the patch strategies
+	assert.NoError(t, SetValue("contexts[name=test-mc].clusterOpts", `{"endpoint": "new-endpoint"}`))
+	c, err = GetContext("test-mc")
+	assert.NoError(t, err)
+	assert.Equal(t, &configtypes.ClusterServer{Endpoint: "new-endpoint", Path: "test-path", Context: "new-context"}, c.ClusterOpts)
+
+	assert.NoError(t, SetValue("contexts[name=test-tanzu].additionalMetadata", "foo: bar"))
+	c, err = GetContext("test-tanzu")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"foo": "bar"}, c.AdditionalMetadata)
+
+	// A selector creates the missing item
+	assert.NoError(t, SetValue("contexts[name=prod]", `{"contextType": "kubernetes", "clusterOpts": {"path": "prod-path", "context": "prod"}}`))
+	c, err = GetContext("prod")
+	assert.NoError(t, err)
+	assert.Equal(t, configtypes.ContextTypeK8s, c.ContextType)
+	assert.Equal(t, "prod-path", c.ClusterOpts.Path)
+
+	err = SetValue("contexts[10].name", "foo")
+	assert.ErrorIs(t, err, ErrPathNotFound)
+
+	// The contexts are validated as SetContext does
+	err = SetValue("contexts[name=test-mc].contextType", "mission-control")
+	assert.ErrorContains(t, err, "does not match")
+	err = SetValue("contexts[name=test-mc].labels", `{"-invalid": "x"}`)
+	assert.ErrorContains(t, err, `invalid label key "-invalid"`)
+	err = SetValue("contexts[name=test-mc].name", "foo")
+	assert.ErrorContains(t, err, "the name of context test-mc cannot be changed")
+	err = SetValue("contexts", "[]")
+	assert.ErrorContains(t, err, "the contexts are updated one at a time")
+	c, err = GetContext("test-mc")
+	assert.NoError(t, err)
+	assert.Equal(t, configtypes.ContextTypeK8s, c.ContextType)
+}
+
+func TestSetValueWithSecretStore(t *testing.T) {
+	err := setupForGetContext()
+	assert.NoError(t, err)
+	defer cleanupTestingDir(t)
+	dir := t.TempDir()
+	store := NewFileSecretStoreAt(filepath.Join(dir, SecretsFileName), filepath.Join(dir, SecretsKeyFileName))
+	SetSecretStore(store)
+	defer SetSecretStore(nil)
+
+	// The tokens are moved to the secret store
+	assert.NoError(t, SetValue("contexts[name=test-tmc].globalOpts.auth.refresh_token", "refresh-token"))
+	cfg, err := GetClientConfig()
+	assert.NoError(t, err)
+	raw, err := cfg.GetContext("test-tmc")
+	assert.NoError(t, err)
+	ref := raw.GlobalOpts.Auth.RefreshToken
+	assert.True(t, IsSecretRef(ref))
+	c, err := GetContext("test-tmc")
+	assert.NoError(t, err)
+	assert.Equal(t, "refresh-token", c.GlobalOpts.Auth.RefreshToken)
+
+	// The secrets no longer referenced are deleted
+	assert.NoError(t, UnsetValue("contexts[name=test-tmc].globalOpts.auth.refresh_token"))
+	_, err = store.Get(strings.TrimPrefix(ref, SecretRefPrefix))
+	assert.ErrorIs(t, err, ErrSecretNotFound)
+	c, err = GetContext("test-tmc")
+	assert.NoError(t, err)
+	assert.Empty(t, c.GlobalOpts.Auth.RefreshToken)
+
+	assert.NoError(t, SetValue("contexts[name=test-tmc].globalOpts.auth.refresh_token", "refresh-token"))
+	cfg, err = GetClientConfig()
+	assert.NoError(t, err)
+	raw, err = cfg.GetContext("test-tmc")
+	assert.NoError(t, err)
+	ref = raw.GlobalOpts.Auth.RefreshToken
+	assert.NoError(t, UnsetValue("contexts[name=test-tmc]"))
+	_, err = store.Get(strings.TrimPrefix(ref, SecretRefPrefix))
+	assert.ErrorIs(t, err, ErrSecretNotFound)
+}
+
+func TestUnsetValue(t *testing.T) {
+	err := setupForGetContext()
+	assert.NoError(t, err)
+	defer cleanupTestingDir(t)
+
+	assert.NoError(t, SetEnv("FOO", "bar"))
+	assert.NoError(t, UnsetValue("clientOptions.env.FOO"))
+	_, err = GetEnv("FOO")
+	assert.Error(t, err)
+
+	assert.NoError(t, UnsetValue("contexts[name=test-mc].clusterOpts.context"))
+	c, err := GetContext("test-mc")
+	assert.NoError(t, err)
+	assert.Empty(t, c.ClusterOpts.Context)
+	assert.Equal(t, "test-path", c.ClusterOpts.Path)
+
+	// The current context of a removed context is removed as well
+	assert.NoError(t, SetActiveContext("test-mc"))
+	assert.NoError(t, UnsetValue("contexts[name=test-mc]"))
+	_, err = GetContext("test-mc")
+	assert.Error(t, err)
+	_, err = GetActiveContext(configtypes.ContextTypeK8s)
+	assert.Error(t, err)
+	_, err = GetServer("test-mc")
+	assert.Error(t, err)
+
+	assert.NoError(t, UnsetValue("contexts[name=test-tanzu]"))
+	_, err = GetContext("test-tanzu")
+	assert.Error(t, err)
+
+	// Missing paths are a no-op
+	assert.NoError(t, UnsetValue("contexts[name=missing].clusterOpts"))
+}
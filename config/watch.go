@@ -0,0 +1,249 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"time"
+
+	configtypes "github.com/vmware-tanzu/tanzu-plugin-runtime/config/types"
+)
+
+// DefaultWatchPollInterval is the default interval at which the config files are checked for changes
+const DefaultWatchPollInterval = time.Second
+
+// ConfigEventType is the type of change observed in the client config
+type ConfigEventType string
+
+const (
+	// ActiveContextChanged is emitted when the active context of a context type is set, changed or removed
+	ActiveContextChanged ConfigEventType = "ActiveContextChanged"
+	// ContextAdded is emitted when a context is added
+	ContextAdded ConfigEventType = "ContextAdded"
+	// ContextRemoved is emitted when a context is removed
+	ContextRemoved ConfigEventType = "ContextRemoved"
+	// ContextUpdated is emitted when an existing context is modified
+	ContextUpdated ConfigEventType = "ContextUpdated"
+	// FeatureChanged is emitted when a feature flag is added, changed or removed
+	FeatureChanged ConfigEventType = "FeatureChanged"
+	// EnvChanged is emitted when an env is added, changed or removed
+	EnvChanged ConfigEventType = "EnvChanged"
+	// CertChanged is emitted when a cert configuration is added, changed or removed
+	CertChanged ConfigEventType = "CertChanged"
+)
+
+// ConfigEvent describes a change in the client config
+type ConfigEvent struct {
+	// Type of the change
+	Type ConfigEventType
+	// Key identifies the changed item. It is the context type for ActiveContextChanged,
+	// the context name for context events, <plugin>.<key> for FeatureChanged,
+	// the env key for EnvChanged and the host for CertChanged.
+	Key string
+	// OldValue is the value before the change, empty if the item was added.
+	// It is the context name for ActiveContextChanged, the value for FeatureChanged and EnvChanged.
+	OldValue string
+	// NewValue is the value after the change, empty if the item was removed.
+	// It is the context name for ActiveContextChanged, the value for FeatureChanged and EnvChanged.
+	NewValue string
+}
+
+type watchOptions struct {
+	pollInterval time.Duration
+}
+
+type WatchOptions func(o *watchOptions)
+
+// WithPollInterval sets the interval at which the config files are checked for changes
+func WithPollInterval(interval time.Duration) WatchOptions {
+	return func(o *watchOptions) {
+		o.pollInterval = interval
+	}
+}
+
+// Watch monitors the config files and emits an event for each change made to the client
+// config, including the changes made by other processes. The returned channel is closed
+// once ctx is done.
+func Watch(ctx context.Context, opts ...WatchOptions) (<-chan ConfigEvent, error) {
+	options := &watchOptions{pollInterval: DefaultWatchPollInterval}
+	for _, opt := range opts {
+		opt(options)
+	}
+	if options.pollInterval <= 0 {
+		return nil, fmt.Errorf("invalid poll interval %v", options.pollInterval)
+	}
+
+	fingerprint, err := configFilesFingerprint()
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := GetClientConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan ConfigEvent)
+	go func() {
+		defer close(events)
+		ticker := time.NewTicker(options.pollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			newFingerprint, err := configFilesFingerprint()
+			if err != nil || newFingerprint == fingerprint {
+				continue
+			}
+			newCfg, err := GetClientConfig()
+			if err != nil {
+				// The config is being edited, retry on the next tick
+				continue
+			}
+			fingerprint = newFingerprint
+			for _, event := range diffClientConfigEvents(cfg, newCfg) {
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+			cfg = newCfg
+		}
+	}()
+	return events, nil
+}
+
+// configFilesFingerprint returns a digest of the content of the config files
+func configFilesFingerprint() (string, error) {
+	h := sha256.New()
+	for _, pathGetter := range []func() (string, error){ClientConfigPath, ClientConfigNextGenPath, CfgMetadataFilePath} {
+		path, err := pathGetter()
+		if err != nil {
+			return "", err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return "", err
+		}
+		// Length prefix each file so that content cannot shift between files
+		fmt.Fprintf(h, "%d:", len(data))
+		h.Write(data)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// diffClientConfigEvents computes the events describing the changes from the old to the new client config
+func diffClientConfigEvents(oldCfg, newCfg *configtypes.ClientConfig) []ConfigEvent {
+	var events []ConfigEvent
+
+	// Active contexts
+	for _, contextType := range sortedKeys(oldCfg.CurrentContext, newCfg.CurrentContext) {
+		oldName, newName := oldCfg.CurrentContext[configtypes.ContextType(contextType)], newCfg.CurrentContext[configtypes.ContextType(contextType)]
+		if oldName != newName {
+			events = append(events, ConfigEvent{Type: ActiveContextChanged, Key: contextType, OldValue: oldName, NewValue: newName})
+		}
+	}
+
+	// Contexts
+	oldContexts := make(map[string]*configtypes.Context)
+	for _, c := range oldCfg.KnownContexts {
+		oldContexts[c.Name] = c
+	}
+	newContexts := make(map[string]*configtypes.Context)
+	for _, c := range newCfg.KnownContexts {
+		newContexts[c.Name] = c
+	}
+	for _, name := range sortedKeys(oldContexts, newContexts) {
+		oldCtx, existed := oldContexts[name]
+		newCtx, exists := newContexts[name]
+		switch {
+		case !existed:
+			events = append(events, ConfigEvent{Type: ContextAdded, Key: name})
+		case !exists:
+			events = append(events, ConfigEvent{Type: ContextRemoved, Key: name})
+		case !reflect.DeepEqual(oldCtx, newCtx):
+			events = append(events, ConfigEvent{Type: ContextUpdated, Key: name})
+		}
+	}
+
+	// Features and envs
+	oldFeatures, newFeatures := flattenFeatures(oldCfg), flattenFeatures(newCfg)
+	events = append(events, diffStringMapEvents(FeatureChanged, oldFeatures, newFeatures)...)
+	var oldEnvs, newEnvs map[string]string
+	if oldCfg.ClientOptions != nil {
+		oldEnvs = oldCfg.ClientOptions.Env
+	}
+	if newCfg.ClientOptions != nil {
+		newEnvs = newCfg.ClientOptions.Env
+	}
+	events = append(events, diffStringMapEvents(EnvChanged, oldEnvs, newEnvs)...)
+
+	// Certs
+	oldCerts := make(map[string]*configtypes.Cert)
+	for _, c := range oldCfg.Certs {
+		oldCerts[c.Host] = c
+	}
+	newCerts := make(map[string]*configtypes.Cert)
+	for _, c := range newCfg.Certs {
+		newCerts[c.Host] = c
+	}
+	for _, host := range sortedKeys(oldCerts, newCerts) {
+		if !reflect.DeepEqual(oldCerts[host], newCerts[host]) {
+			events = append(events, ConfigEvent{Type: CertChanged, Key: host})
+		}
+	}
+	return events
+}
+
+// flattenFeatures returns the feature flags of the config keyed by <plugin>.<key>
+func flattenFeatures(cfg *configtypes.ClientConfig) map[string]string {
+	features := make(map[string]string)
+	if cfg.ClientOptions == nil {
+		return features
+	}
+	for plugin, featureMap := range cfg.ClientOptions.Features {
+		for key, value := range featureMap {
+			features[plugin+"."+key] = value
+		}
+	}
+	return features
+}
+
+func diffStringMapEvents(eventType ConfigEventType, oldMap, newMap map[string]string) []ConfigEvent {
+	var events []ConfigEvent
+	for _, key := range sortedKeys(oldMap, newMap) {
+		oldValue, existed := oldMap[key]
+		newValue, exists := newMap[key]
+		if existed != exists || oldValue != newValue {
+			events = append(events, ConfigEvent{Type: eventType, Key: key, OldValue: oldValue, NewValue: newValue})
+		}
+	}
+	return events
+}
+
+// sortedKeys returns the sorted union of the keys of the maps
+func sortedKeys[K ~string, V any](maps ...map[K]V) []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, m := range maps {
+		for k := range m {
+			if !seen[string(k)] {
+				seen[string(k)] = true
+				keys = append(keys, string(k))
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
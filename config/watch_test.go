@@ -0,0 +1,90 @@
+// Copyright 2023 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	configtypes "github.com/vmware-tanzu/tanzu-plugin-runtime/config/types"
+)
+
+func receiveEvents(t *testing.T, events <-chan ConfigEvent, count int) []ConfigEvent {
+	var received []ConfigEvent
+	for len(received) < count {
+		select {
+		case event := <-events:
+			received = append(received, event)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for config events, received %v", received)
+		}
+	}
+	return received
+}
+
+func TestWatch(t *testing.T) {
+	// Setup config data
+	_, cleanUp := setupTestConfig(t, &CfgTestData{})
+
+	defer func() {
+		cleanUp()
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events, err := Watch(ctx, WithPollInterval(10*time.Millisecond))
+	assert.NoError(t, err)
+
+	err = SetContext(&configtypes.Context{
+		Name:        "test-mc",
+		ContextType: configtypes.ContextTypeK8s,
+		ClusterOpts: &configtypes.ClusterServer{
+			Endpoint: "test-endpoint",
+			Path:     "test-path",
+			Context:  "test-context",
+		},
+	}, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []ConfigEvent{
+		{Type: ActiveContextChanged, Key: string(configtypes.ContextTypeK8s), NewValue: "test-mc"},
+		{Type: ContextAdded, Key: "test-mc"},
+	}, receiveEvents(t, events, 2))
+
+	err = Update(func(tx *ConfigTx) error {
+		if err := tx.SetEnv("env1", "val1"); err != nil {
+			return err
+		}
+		if err := tx.SetFeature("global", "feature1", "true"); err != nil {
+			return err
+		}
+		return tx.SetCert(&configtypes.Cert{Host: "test-endpoint", SkipCertVerify: "true"})
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []ConfigEvent{
+		{Type: FeatureChanged, Key: "global.feature1", NewValue: "true"},
+		{Type: EnvChanged, Key: "env1", NewValue: "val1"},
+		{Type: CertChanged, Key: "test-endpoint"},
+	}, receiveEvents(t, events, 3))
+
+	err = DeleteContext("test-mc")
+	assert.NoError(t, err)
+	assert.Equal(t, []ConfigEvent{
+		{Type: ActiveContextChanged, Key: string(configtypes.ContextTypeK8s), OldValue: "test-mc"},
+		{Type: ContextRemoved, Key: "test-mc"},
+	}, receiveEvents(t, events, 2))
+
+	// The channel is closed once the context is done
+	cancel()
+	select {
+	case _, ok := <-events:
+		assert.False(t, ok)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the events channel to be closed")
+	}
+
+	_, err = Watch(context.Background(), WithPollInterval(0))
+	assert.Equal(t, "invalid poll interval 0s", err.Error())
+}